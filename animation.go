@@ -0,0 +1,110 @@
+package chessboard
+
+import (
+	"time"
+
+	"gioui.org/f32"
+	"github.com/failosof/chessboard/rules"
+	"github.com/failosof/chessboard/util"
+)
+
+// maxAnimatedPieces keeps the board from animating jumps between unrelated
+// positions, like setting a new game.
+const maxAnimatedPieces = 3
+
+type pieceMove struct {
+	piece rules.Piece
+	from  rules.Square
+	to    rules.Square
+}
+
+type animation struct {
+	moves    []pieceMove
+	start    time.Time
+	duration time.Duration
+}
+
+func (a *animation) running(now time.Time) bool {
+	return len(a.moves) > 0 && now.Sub(a.start) < a.duration
+}
+
+func (a *animation) moving(square rules.Square) bool {
+	for _, move := range a.moves {
+		if move.to == square {
+			return true
+		}
+	}
+	return false
+}
+
+// progress eases in and out, so pieces don't start and stop abruptly.
+func (a *animation) progress(now time.Time) float32 {
+	t := float32(now.Sub(a.start)) / float32(a.duration)
+	t = min(max(t, 0), 1)
+	if t < 0.5 {
+		return 4 * t * t * t
+	}
+	t = 2*t - 2
+	return 1 + t*t*t/2
+}
+
+func lerp(from, to f32.Point, t float32) f32.Point {
+	return from.Add(to.Sub(from).Mul(t))
+}
+
+// diffBoards finds the pieces that moved from one board to the other. Pieces
+// are matched by what left and what appeared rather than by the move itself,
+// so castling works even when the king and rook swap or share squares.
+func diffBoards(prev, cur []rules.Piece, squares []rules.Square) []pieceMove {
+	var vacated, appeared []rules.Square
+	for _, square := range squares {
+		if prev[square] == cur[square] {
+			continue
+		}
+		if prev[square] != rules.NoPiece {
+			vacated = append(vacated, square)
+		}
+		if cur[square] != rules.NoPiece {
+			appeared = append(appeared, square)
+		}
+	}
+
+	var moves []pieceMove
+	for _, to := range appeared {
+		piece := cur[to]
+		i := closestSquare(vacated, to, func(square rules.Square) bool {
+			return prev[square] == piece
+		})
+		if i < 0 {
+			// promotion
+			i = closestSquare(vacated, to, func(square rules.Square) bool {
+				return prev[square] == rules.NewPiece(rules.Pawn, piece.Color())
+			})
+		}
+		if i < 0 {
+			continue
+		}
+
+		moves = append(moves, pieceMove{piece: piece, from: vacated[i], to: to})
+		vacated = append(vacated[:i], vacated[i+1:]...)
+	}
+
+	if len(moves) > maxAnimatedPieces {
+		return nil
+	}
+	return moves
+}
+
+func closestSquare(squares []rules.Square, to rules.Square, match func(rules.Square) bool) int {
+	found, best := -1, 0
+	for i, square := range squares {
+		if !match(square) {
+			continue
+		}
+		distance := max(util.Abs(square.File()-to.File()), util.Abs(square.Rank()-to.Rank()))
+		if found < 0 || distance < best {
+			found, best = i, distance
+		}
+	}
+	return found
+}
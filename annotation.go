@@ -1,14 +1,21 @@
 package chessboard
 
 import (
+	"image"
 	"image/color"
 	"log/slog"
+	"regexp"
+	"strings"
+	"unicode"
 
+	"gioui.org/font"
 	"gioui.org/layout"
 	"gioui.org/op"
+	"gioui.org/text"
+	"gioui.org/widget/material"
+	"github.com/failosof/chessboard/rules"
 	"github.com/failosof/chessboard/union"
 	"github.com/failosof/chessboard/util"
-	"github.com/notnil/chess"
 )
 
 type AnnoType int8
@@ -19,30 +26,46 @@ const (
 	CircleAnno
 	CrossAnno
 	ArrowAnno
+	BadgeAnno
 )
 
 type Annotation struct {
 	Type  AnnoType
-	Start chess.Square
-	End   chess.Square // only for arrows
+	Start rules.Square
+	End   rules.Square // only for arrows
+	NAG   NAG          // only for badges
 	Color color.NRGBA
 	Width union.Size
 
+	th     *material.Theme // labels badges, the widget's theme when nil
 	drawOp *op.CallOp
 }
 
+func NewBadge(th *material.Theme, square rules.Square, nag NAG) Annotation {
+	return Annotation{
+		Type:  BadgeAnno,
+		Start: square,
+		NAG:   nag,
+		Color: nag.Color(),
+		th:    th,
+	}
+}
+
 func (a *Annotation) Copy() Annotation {
 	return Annotation{
 		Type:  a.Type,
 		Start: a.Start,
 		End:   a.End,
+		NAG:   a.NAG,
 		Color: a.Color,
 		Width: a.Width,
+		th:    a.th,
 	}
 }
 
 func (a *Annotation) Equal(b *Annotation) bool {
-	return a.Type == b.Type && a.Start == b.Start && a.Color == b.Color && (a.Type != ArrowAnno || a.End == b.End)
+	return a.Type == b.Type && a.Start == b.Start && a.Color == b.Color &&
+		(a.Type != ArrowAnno || a.End == b.End) && (a.Type != BadgeAnno || a.NAG == b.NAG)
 }
 
 func (a *Annotation) Scale(factor float32) {
@@ -69,6 +92,8 @@ func (a *Annotation) Draw(gtx layout.Context, squareOrigins []union.Point, squar
 				start := squareOrigins[a.Start].Pt
 				end := squareOrigins[a.End].Pt
 				util.DrawArrow(cache, start, end, squareSize.F32, a.Width.Float, a.Color)
+			case BadgeAnno:
+				a.drawBadge(gtx, cache, annoRect, squareSize)
 			default:
 				slog.Error("unknown annotation type", "type", a.Type)
 			}
@@ -82,3 +107,112 @@ func (a *Annotation) Draw(gtx layout.Context, squareOrigins []union.Point, squar
 		}
 	}
 }
+
+func (a *Annotation) drawBadge(gtx layout.Context, ops *op.Ops, square image.Rectangle, squareSize union.Size) {
+	badge := badgeRect(square, squareSize)
+	diameter := badge.Dx()
+	util.DrawEllipse(ops, badge, a.Color)
+
+	gtx.Ops = ops
+	gtx.Constraints = layout.Exact(badge.Size())
+	defer op.Offset(badge.Min).Push(ops).Pop()
+	layout.Center.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		gtx.Constraints.Min = image.Point{}
+		label := material.Label(a.th, gtx.Metric.PxToSp(diameter*3/5), a.NAG.Symbol())
+		label.Color = util.WhiteColor
+		label.Font.Weight = font.Bold
+		label.Alignment = text.Middle
+		label.MaxLines = 1
+		return label.Layout(gtx)
+	})
+}
+
+// badgeRect puts the badge in the square's top right corner.
+func badgeRect(square image.Rectangle, squareSize union.Size) image.Rectangle {
+	diameter := util.Round(squareSize.Float * 0.4)
+	return util.Rect(image.Pt(square.Max.X-diameter, square.Min.Y), image.Pt(diameter, diameter))
+}
+
+var commentCommandRe = regexp.MustCompile(`\[%(cal|csl)\s+([^\]]*)\]`)
+
+// AnnotationsFromComment reads the arrows and circles that Lichess and
+// ChessBase embed into PGN comments as [%cal Ge2e4] and [%csl Rd5].
+func AnnotationsFromComment(comment string, colors Color) []Annotation {
+	var annotations []Annotation
+	for _, match := range commentCommandRe.FindAllStringSubmatch(comment, -1) {
+		for _, item := range strings.Split(match[2], ",") {
+			item = strings.TrimSpace(item)
+			if len(item) < 3 {
+				continue
+			}
+
+			anno := Annotation{Color: colorFromLetter(item[0], colors)}
+			squares := item[1:]
+			if match[1] == "cal" {
+				// squares run up to 16 files and ranks, like a10b12
+				split := strings.IndexFunc(squares[1:], unicode.IsLetter) + 1
+				if split > 0 {
+					start, err1 := rules.ParseSquare(squares[:split])
+					end, err2 := rules.ParseSquare(squares[split:])
+					if err1 == nil && err2 == nil {
+						anno.Type, anno.Start, anno.End = ArrowAnno, start, end
+					}
+				}
+			} else if start, err := rules.ParseSquare(squares); err == nil {
+				anno.Type, anno.Start = CircleAnno, start
+			}
+			if anno.Type != NoAnno {
+				annotations = append(annotations, anno)
+			}
+		}
+	}
+	return annotations
+}
+
+func CommentFromAnnotations(annotations []Annotation, colors Color) string {
+	var arrows, circles []string
+	for _, anno := range annotations {
+		letter := letterFromColor(anno.Color, colors)
+		switch anno.Type {
+		case ArrowAnno:
+			arrows = append(arrows, letter+anno.Start.String()+anno.End.String())
+		case CircleAnno, RectAnno:
+			circles = append(circles, letter+anno.Start.String())
+		}
+	}
+
+	var commands []string
+	if len(circles) > 0 {
+		commands = append(commands, "[%csl "+strings.Join(circles, ",")+"]")
+	}
+	if len(arrows) > 0 {
+		commands = append(commands, "[%cal "+strings.Join(arrows, ",")+"]")
+	}
+	return strings.Join(commands, " ")
+}
+
+func colorFromLetter(letter byte, colors Color) color.NRGBA {
+	switch letter {
+	case 'R':
+		return colors.Danger
+	case 'Y':
+		return colors.Warning
+	case 'B':
+		return colors.Info
+	default:
+		return colors.Primary
+	}
+}
+
+func letterFromColor(c color.NRGBA, colors Color) string {
+	switch c {
+	case colors.Danger:
+		return "R"
+	case colors.Warning:
+		return "Y"
+	case colors.Info:
+		return "B"
+	default:
+		return "G"
+	}
+}
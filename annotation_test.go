@@ -0,0 +1,47 @@
+package chessboard
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/widget/material"
+	"github.com/failosof/chessboard/rules"
+	"github.com/failosof/chessboard/union"
+)
+
+func TestBadgeRect(t *testing.T) {
+	tests := []struct {
+		square image.Rectangle
+		size   float32
+		want   image.Rectangle
+	}{
+		{image.Rect(0, 0, 100, 100), 100, image.Rect(60, 0, 100, 40)},
+		{image.Rect(100, 200, 150, 250), 50, image.Rect(130, 200, 150, 220)},
+		{image.Rect(7, 7, 19, 19), 12.5, image.Rect(14, 7, 19, 12)},
+	}
+
+	for _, test := range tests {
+		if got := badgeRect(test.square, union.SizeFromFloat(test.size)); got != test.want {
+			t.Errorf("badge of %v = %v, want %v", test.square, got, test.want)
+		}
+	}
+}
+
+func TestBadgeTheme(t *testing.T) {
+	w, gtx := newTestWidget(t)
+	th := material.NewTheme()
+	e4, _ := rules.ParseSquare("e4")
+
+	badge := NewBadge(th, e4, GoodMove)
+	if copied := badge.Copy(); copied.th != th {
+		t.Error("a copied badge lost its theme")
+	}
+
+	// a badge without a theme, like one from another board, takes the widget's
+	w.AddAnnotation(badge)
+	w.AddAnnotation(Annotation{Type: BadgeAnno, Start: e4, NAG: Blunder, Color: Blunder.Color()})
+	w.layout(gtx)
+	if w.annotations[0].th != th || w.annotations[1].th != w.th {
+		t.Error("badges aren't drawn with their own theme or the widget's")
+	}
+}
@@ -0,0 +1,161 @@
+package chessboard
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"github.com/failosof/chessboard/rules"
+	"github.com/failosof/chessboard/util"
+)
+
+// Board draws the squares instead of scaling a board image, so it stays
+// crisp at any size and fits any number of files and ranks. Textures are
+// tiled over the squares of their color at their own resolution, and may be
+// translucent to let the color through.
+type Board struct {
+	Light        color.NRGBA
+	Dark         color.NRGBA
+	LightTexture image.Image
+	DarkTexture  image.Image
+	Border       unit.Dp
+	BorderColor  color.NRGBA
+	Radius       unit.Dp
+}
+
+var (
+	LichessBrown = Board{Light: rgb(0xf0d9b5), Dark: rgb(0xb58863)}
+	LichessBlue  = Board{Light: rgb(0xdee3e6), Dark: rgb(0x8ca2ad)}
+	LichessGreen = Board{Light: rgb(0xffffdd), Dark: rgb(0x86a666)}
+
+	ChessComGreen = Board{Light: rgb(0xeeeed2), Dark: rgb(0x769656)}
+	ChessComBrown = Board{Light: rgb(0xedd6b0), Dark: rgb(0xb88762)}
+)
+
+func rgb(c uint32) color.NRGBA {
+	return color.NRGBA{R: uint8(c >> 16), G: uint8(c >> 8), B: uint8(c), A: 0xff}
+}
+
+func (b Board) textured() bool {
+	return b.LightTexture != nil || b.DarkTexture != nil
+}
+
+// layoutFrame draws the border of the board around its squares.
+func (w *Widget) layoutFrame(gtx layout.Context) layout.Dimensions {
+	w.mu.Lock()
+	board := w.config.Board
+	w.mu.Unlock()
+
+	border := gtx.Dp(board.Border)
+	if border == 0 {
+		return w.layout(gtx)
+	}
+
+	inset := image.Pt(2*border, 2*border)
+	gtx.Constraints.Min = image.Point{}
+	gtx.Constraints.Max = gtx.Constraints.Max.Sub(inset)
+	if gtx.Constraints.Max.X < 0 || gtx.Constraints.Max.Y < 0 {
+		return layout.Dimensions{}
+	}
+
+	macro := op.Record(gtx.Ops)
+	stack := op.Offset(image.Pt(border, border)).Push(gtx.Ops)
+	dims := w.layout(gtx)
+	stack.Pop()
+	squares := macro.Stop()
+
+	size := dims.Size.Add(inset)
+	frame := clip.UniformRRect(image.Rectangle{Max: size}, gtx.Dp(board.Radius))
+	paint.FillShape(gtx.Ops, board.BorderColor, frame.Op(gtx.Ops))
+	squares.Add(gtx.Ops)
+
+	return layout.Dimensions{Size: size}
+}
+
+// boardClip rounds the outer corners of the squares, less so when the border
+// takes up part of the radius.
+func (w *Widget) boardClip(gtx layout.Context) clip.RRect {
+	radius := max(gtx.Dp(w.config.Board.Radius)-gtx.Dp(w.config.Board.Border), 0)
+	return clip.UniformRRect(image.Rectangle{Max: w.curBoardSize}, radius)
+}
+
+// drawBackground paints the board image when the board is 8x8, the squares
+// of the config's Board otherwise. Textured squares are rasterized once per
+// size: squaresImage is reused when it still fits, and the one drawn is
+// returned to be passed in next time.
+func drawBackground(ops *op.Ops, config Config, files, ranks int, size image.Point, rect func(rules.Square) image.Rectangle, squaresImage *image.RGBA) *image.RGBA {
+	if files == 8 && ranks == 8 && config.BoardImage != nil {
+		factor := util.ToF32(size).Div(config.BoardImageSize.Float)
+		util.DrawImage(ops, config.BoardImage, image.Point{}, factor)
+		return squaresImage
+	}
+
+	board := config.Board
+	if board.textured() {
+		if squaresImage == nil || squaresImage.Bounds().Max != size {
+			squaresImage = rasterizeSquares(board, files, ranks, size, rect)
+		}
+		util.DrawImage(ops, squaresImage, image.Point{}, f32.Pt(1, 1))
+		return squaresImage
+	}
+
+	util.DrawPane(ops, image.Rectangle{Max: size}, board.Light)
+	for rank := 0; rank < ranks; rank++ {
+		for file := 0; file < files; file++ {
+			if square := rules.NewSquare(file, rank); util.SquareColor(square) == rules.Black {
+				util.DrawPane(ops, rect(square), board.Dark)
+			}
+		}
+	}
+	return squaresImage
+}
+
+func rasterizeSquares(board Board, files, ranks int, size image.Point, rect func(rules.Square) image.Rectangle) *image.RGBA {
+	img := image.NewRGBA(image.Rectangle{Max: size})
+	for rank := 0; rank < ranks; rank++ {
+		for file := 0; file < files; file++ {
+			square := rules.NewSquare(file, rank)
+			fill, texture := board.Light, board.LightTexture
+			if util.SquareColor(square) == rules.Black {
+				fill, texture = board.Dark, board.DarkTexture
+			}
+
+			r := rect(square)
+			draw.Draw(img, r, image.NewUniform(fill), image.Point{}, draw.Src)
+			if texture != nil {
+				tile(img, r, texture)
+			}
+		}
+	}
+	return img
+}
+
+// tile repeats a texture over a rectangle, aligned to the origin so that
+// neighbouring squares continue the same pattern.
+func tile(dst draw.Image, rect image.Rectangle, texture image.Image) {
+	bounds := texture.Bounds()
+	size := bounds.Size()
+	if size.X == 0 || size.Y == 0 {
+		return
+	}
+
+	start := image.Pt(rect.Min.X-rect.Min.X%size.X, rect.Min.Y-rect.Min.Y%size.Y)
+	for y := start.Y; y < rect.Max.Y; y += size.Y {
+		for x := start.X; x < rect.Max.X; x += size.X {
+			tileRect := image.Rectangle{Min: image.Pt(x, y), Max: image.Pt(x, y).Add(size)}.Intersect(rect)
+			src := bounds.Min.Add(tileRect.Min.Sub(image.Pt(x, y)))
+			draw.Draw(dst, tileRect, texture, src, draw.Over)
+		}
+	}
+}
+
+func (w *Widget) squareRect(square rules.Square) image.Rectangle {
+	origin := w.squareOrigins[square]
+	return image.Rectangle{Min: origin.Pt, Max: origin.F32.Add(w.squareSize.F32).Round()}
+}
@@ -0,0 +1,281 @@
+package chessboard
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+	"github.com/failosof/chessboard/rules"
+	"github.com/failosof/chessboard/util"
+)
+
+type BughouseEvent struct {
+	Board int
+	Event
+}
+
+// Bughouse shows a bughouse match on two widgets side by side. The second
+// board is flipped, so partners sit next to each other, and pieces captured on
+// one board show up in the partner's pocket. Partners share the time of their
+// team, which loses as soon as it runs out.
+type Bughouse struct {
+	Boards [2]*Widget
+	Clock  *TeamClock
+
+	th     *material.Theme
+	match  *rules.Bughouse
+	events []BughouseEvent
+}
+
+func NewBughouse(th *material.Theme, config Config, initial, increment time.Duration) *Bughouse {
+	b := Bughouse{
+		Clock: NewTeamClock(initial, increment),
+		th:    th,
+		match: rules.NewBughouse(),
+	}
+
+	for i := range b.Boards {
+		b.Boards[i] = NewWidget(th, config)
+		b.Boards[i].SetRules(b.match.Board(i))
+	}
+	b.Boards[1].SetFlipped(true)
+
+	return &b
+}
+
+func (b *Bughouse) Match() *rules.Bughouse {
+	return b.match
+}
+
+// Move plays a move that didn't come from the boards, like one received from
+// a server, and presses the team clock for it.
+func (b *Bughouse) Move(board int, move rules.Move, now time.Time) error {
+	if board < 0 || board >= len(b.Boards) {
+		return fmt.Errorf("no board %d", board)
+	}
+
+	r := b.match.Board(board)
+	color := r.Turn()
+	if err := r.Move(move); err != nil {
+		return err
+	}
+
+	b.Clock.Press(board, color, now)
+	b.events = append(b.events, BughouseEvent{
+		Board: board,
+		Event: Event{Type: MoveEvent, Move: move, FEN: r.FEN()},
+	})
+	b.checkOutcome(now)
+	return nil
+}
+
+// Update returns the events of both boards in the order they happened.
+func (b *Bughouse) Update(gtx layout.Context) (BughouseEvent, bool) {
+	b.collect(gtx)
+
+	if len(b.events) == 0 {
+		return BughouseEvent{}, false
+	}
+
+	e := b.events[0]
+	b.events = b.events[1:]
+	return e, true
+}
+
+func (b *Bughouse) Layout(gtx layout.Context) layout.Dimensions {
+	b.collect(gtx)
+
+	if team := b.Clock.Flagged(gtx.Now); team >= 0 {
+		// the first team plays white on the first board
+		flagged := rules.White
+		if team == 1 {
+			flagged = rules.Black
+		}
+		b.match.Timeout(0, flagged)
+		b.checkOutcome(gtx.Now)
+	}
+
+	return layout.Flex{
+		Axis:    layout.Horizontal,
+		Spacing: layout.SpaceEvenly,
+	}.Layout(
+		gtx,
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			return b.layoutBoard(gtx, 0)
+		}),
+		layout.Rigid(layout.Spacer{Width: unit.Dp(16)}.Layout),
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			return b.layoutBoard(gtx, 1)
+		}),
+	)
+}
+
+func (b *Bughouse) layoutBoard(gtx layout.Context, board int) layout.Dimensions {
+	top, bottom := rules.Black, rules.White
+	if b.Boards[board].Flipped() {
+		top, bottom = bottom, top
+	}
+
+	return layout.Flex{
+		Axis:      layout.Vertical,
+		Alignment: layout.End,
+	}.Layout(
+		gtx,
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return b.layoutClock(gtx, board, top)
+		}),
+		layout.Flexed(1, b.Boards[board].Layout),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return b.layoutClock(gtx, board, bottom)
+		}),
+	)
+}
+
+func (b *Bughouse) layoutClock(gtx layout.Context, board int, color rules.Color) layout.Dimensions {
+	return ClockStyle{
+		Theme:      b.th,
+		Clock:      b.Clock.Board(board),
+		Color:      color,
+		TextSize:   unit.Sp(20),
+		Background: util.Transparentize(util.GrayColor, 0.5),
+		Foreground: util.BlackColor,
+		Active:     util.Transparentize(util.GreenColor, 0.7),
+	}.Layout(gtx)
+}
+
+// collect moves the events of the boards into one queue and presses the
+// clocks for the moves made on them.
+func (b *Bughouse) collect(gtx layout.Context) {
+	for i, board := range b.Boards {
+		for {
+			e, ok := board.Update(gtx)
+			if !ok {
+				break
+			}
+
+			if e.Type == MoveEvent {
+				color := b.match.Board(i).Turn().Other()
+				b.Clock.Press(i, color, gtx.Now)
+			}
+			b.events = append(b.events, BughouseEvent{Board: i, Event: e})
+		}
+	}
+	b.checkOutcome(gtx.Now)
+}
+
+func (b *Bughouse) checkOutcome(now time.Time) {
+	if outcome, _ := b.match.Outcome(0); outcome != rules.NoOutcome {
+		b.Clock.Stop(now)
+	}
+}
+
+// TeamClock counts down the time of the two bughouse teams, numbered like
+// rules.Team. A team's time runs while it's to move on either board and it
+// gets the increment for every move of its players. A board counts from its
+// first move on.
+type TeamClock struct {
+	remaining [2]time.Duration
+	increment time.Duration
+	turns     [2]rules.Color // by board, NoColor until its first move
+	since     time.Time
+
+	mu sync.Mutex
+}
+
+func NewTeamClock(initial, increment time.Duration) *TeamClock {
+	return &TeamClock{
+		remaining: [2]time.Duration{initial, initial},
+		increment: increment,
+	}
+}
+
+// Press is what a player does after moving on a board: their team gets the
+// increment and the opponents are to move there.
+func (c *TeamClock) Press(board int, moved rules.Color, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.settle(now)
+	if c.turns[board] == moved {
+		c.remaining[rules.Team(board, moved)] += c.increment
+	}
+	c.turns[board] = moved.Other()
+}
+
+func (c *TeamClock) Stop(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.settle(now)
+	c.turns = [2]rules.Color{}
+}
+
+func (c *TeamClock) Remaining(team int, now time.Time) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.remainingAt(team, now)
+}
+
+// Flagged returns the team whose time ran out, or -1.
+func (c *TeamClock) Flagged(now time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for team := range c.remaining {
+		if c.remainingAt(team, now) <= 0 {
+			return team
+		}
+	}
+	return -1
+}
+
+// Board shows the clock from a board, where each color has its team's time.
+func (c *TeamClock) Board(board int) Timer {
+	return boardClock{clock: c, board: board}
+}
+
+// settle takes the time since the last change off the teams that were to
+// move.
+func (c *TeamClock) settle(now time.Time) {
+	for team := range c.remaining {
+		if c.running(team) {
+			c.remaining[team] -= now.Sub(c.since)
+		}
+	}
+	c.since = now
+}
+
+func (c *TeamClock) running(team int) bool {
+	for board, turn := range c.turns {
+		if turn != rules.NoColor && rules.Team(board, turn) == team {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *TeamClock) remainingAt(team int, now time.Time) time.Duration {
+	remaining := c.remaining[team]
+	if c.running(team) {
+		remaining -= now.Sub(c.since)
+	}
+	return max(remaining, 0)
+}
+
+type boardClock struct {
+	clock *TeamClock
+	board int
+}
+
+func (b boardClock) Running() rules.Color {
+	b.clock.mu.Lock()
+	defer b.clock.mu.Unlock()
+	return b.clock.turns[b.board]
+}
+
+func (b boardClock) Remaining(color rules.Color, now time.Time) time.Duration {
+	return b.clock.Remaining(rules.Team(b.board, color), now)
+}
@@ -0,0 +1,58 @@
+package chessboard
+
+import (
+	"testing"
+	"time"
+
+	"github.com/failosof/chessboard/rules"
+)
+
+func TestTeamClock(t *testing.T) {
+	start := time.Now()
+	at := func(seconds int) time.Time {
+		return start.Add(time.Duration(seconds) * time.Second)
+	}
+
+	clock := NewTeamClock(time.Minute, 2*time.Second)
+	clock.Press(0, rules.White, at(0)) // team 1 to move on board 0
+	clock.Press(1, rules.White, at(5)) // team 0 to move on board 1
+	clock.Press(0, rules.Black, at(8)) // team 0 to move on both boards
+
+	tests := []struct {
+		team int
+		now  time.Time
+		want time.Duration
+	}{
+		// team 0 ran from 5s on and doesn't run twice as fast for being to
+		// move on both boards from 8s
+		{0, at(18), time.Minute - 13*time.Second},
+		// team 1 ran from 0s to 8s, with the increment for the move on board 0
+		// but none for the first move on board 1
+		{1, at(18), time.Minute - 8*time.Second + 2*time.Second},
+	}
+	for _, test := range tests {
+		if got := clock.Remaining(test.team, test.now); got != test.want {
+			t.Errorf("team %d has %s, want %s", test.team, got, test.want)
+		}
+	}
+
+	board := clock.Board(1)
+	if got := board.Running(); got != rules.Black {
+		t.Errorf("board 2 runs for %s, want black", got)
+	}
+	if got, want := board.Remaining(rules.Black, at(18)), clock.Remaining(0, at(18)); got != want {
+		t.Errorf("black on board 2 has %s, want the first team's %s", got, want)
+	}
+
+	if got := clock.Flagged(at(60)); got != -1 {
+		t.Errorf("team %d flagged early", got)
+	}
+	if got := clock.Flagged(at(69)); got != 0 {
+		t.Errorf("flagged team = %d, want 0", got)
+	}
+
+	clock.Stop(at(20))
+	if got, want := clock.Remaining(0, at(100)), time.Minute-15*time.Second; got != want {
+		t.Errorf("stopped clock has %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,157 @@
+package chessboard
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"sync"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+	"github.com/failosof/chessboard/rules"
+	"github.com/failosof/chessboard/util"
+)
+
+// Clock counts down the time of both players with a Fischer increment. It
+// doesn't know about moves, the owner presses it after every one.
+type Clock struct {
+	remaining [3]time.Duration
+	increment time.Duration
+	running   rules.Color
+	since     time.Time
+
+	mu sync.Mutex
+}
+
+func NewClock(initial, increment time.Duration) *Clock {
+	c := Clock{increment: increment}
+	c.remaining[rules.White] = initial
+	c.remaining[rules.Black] = initial
+	return &c
+}
+
+// Press is what a player does after moving: their time stops, they get the
+// increment and the opponent's time starts.
+func (c *Clock) Press(moved rules.Color, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.running == moved {
+		c.remaining[moved] -= now.Sub(c.since)
+		c.remaining[moved] += c.increment
+	}
+	c.running = moved.Other()
+	c.since = now
+}
+
+func (c *Clock) Stop(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.running != rules.NoColor {
+		c.remaining[c.running] -= now.Sub(c.since)
+		c.running = rules.NoColor
+	}
+}
+
+func (c *Clock) Running() rules.Color {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.running
+}
+
+func (c *Clock) Remaining(color rules.Color, now time.Time) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.remainingAt(color, now)
+}
+
+// Set overrides the remaining time of a player, as servers send it.
+func (c *Clock) Set(color rules.Color, remaining time.Duration, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.remaining[color] = remaining
+	if c.running == color {
+		c.since = now
+	}
+}
+
+// Flagged returns the player whose time ran out, if any.
+func (c *Clock) Flagged(now time.Time) rules.Color {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, color := range []rules.Color{rules.White, rules.Black} {
+		if c.remainingAt(color, now) <= 0 {
+			return color
+		}
+	}
+	return rules.NoColor
+}
+
+func (c *Clock) remainingAt(color rules.Color, now time.Time) time.Duration {
+	remaining := c.remaining[color]
+	if c.running == color {
+		remaining -= now.Sub(c.since)
+	}
+	return max(remaining, 0)
+}
+
+// FormatClock shows minutes and seconds, and tenths in the last ten seconds.
+func FormatClock(d time.Duration) string {
+	if d < 10*time.Second {
+		return fmt.Sprintf("%d.%d", d/time.Second, d%time.Second/(100*time.Millisecond))
+	}
+	d = d.Round(time.Second)
+	if d >= time.Hour {
+		return fmt.Sprintf("%d:%02d:%02d", d/time.Hour, d%time.Hour/time.Minute, d%time.Minute/time.Second)
+	}
+	return fmt.Sprintf("%d:%02d", d/time.Minute, d%time.Minute/time.Second)
+}
+
+// Timer is what a clock face shows, a Clock or a bughouse board's view of
+// the team clock.
+type Timer interface {
+	Running() rules.Color
+	Remaining(color rules.Color, now time.Time) time.Duration
+}
+
+type ClockStyle struct {
+	Theme      *material.Theme
+	Clock      Timer
+	Color      rules.Color
+	TextSize   unit.Sp
+	Background color.NRGBA
+	Foreground color.NRGBA
+	Active     color.NRGBA
+}
+
+func (s ClockStyle) Layout(gtx layout.Context) layout.Dimensions {
+	running := s.Clock.Running() == s.Color
+	if running {
+		gtx.Execute(op.InvalidateCmd{At: gtx.Now.Add(100 * time.Millisecond)})
+	}
+
+	macro := op.Record(gtx.Ops)
+	dims := layout.UniformInset(unit.Dp(4)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		label := material.Label(s.Theme, s.TextSize, FormatClock(s.Clock.Remaining(s.Color, gtx.Now)))
+		label.Color = s.Foreground
+		label.Alignment = text.End
+		label.MaxLines = 1
+		return label.Layout(gtx)
+	})
+	call := macro.Stop()
+
+	background := s.Background
+	if running {
+		background = s.Active
+	}
+	util.DrawPane(gtx.Ops, image.Rectangle{Max: dims.Size}, background)
+	call.Add(gtx.Ops)
+	return dims
+}
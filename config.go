@@ -4,12 +4,15 @@ import (
 	"fmt"
 	"image"
 	"image/color"
-	"path/filepath"
+	"log/slog"
+	"os"
 	"time"
 
+	"gioui.org/op"
+
+	"github.com/failosof/chessboard/rules"
 	"github.com/failosof/chessboard/union"
 	"github.com/failosof/chessboard/util"
-	"github.com/notnil/chess"
 )
 
 type Coordinates int8
@@ -28,6 +31,10 @@ type Color struct {
 	Info     color.NRGBA
 	Warning  color.NRGBA
 	Danger   color.NRGBA
+	Check    color.NRGBA
+	GameOver color.NRGBA
+	Pocket   color.NRGBA
+	Goal     color.NRGBA
 }
 
 var (
@@ -38,59 +45,140 @@ var (
 		Info:     util.Transparentize(util.BlueColor, 0.7),
 		Warning:  util.Transparentize(util.YellowColor, 0.7),
 		Danger:   util.Transparentize(util.RedColor, 0.7),
+		Check:    util.RedColor,
+		GameOver: util.Transparentize(util.BlackColor, 0.6),
+		Pocket:   util.Transparentize(util.BlackColor, 0.7),
+		Goal:     util.Transparentize(util.GreenColor, 0.8),
 	}
 )
 
+func DefaultColors() Color {
+	return defaultColors
+}
+
+// Piece holds an image for every rules.Piece, fairy pieces included. Pieces
+// without an image are not drawn. Images are scaled to the square size once
+// and shared by every board, pieces with an SVG are rasterized at the square
+// size instead, so they stay sharp. Font, when set, draws every piece as a
+// glyph instead.
 type Piece struct {
 	Images []image.Image
 	Sizes  []union.Size
+	SVGs   []*util.SVG
+	Font   *FontPieces
+}
+
+func (p *Piece) Set(piece rules.Piece, img image.Image) {
+	p.grow()
+	p.Images[piece] = img
+	p.Sizes[piece] = union.SizeFromMinPt(img.Bounds().Size())
+}
+
+func (p *Piece) SetSVG(piece rules.Piece, svg *util.SVG) {
+	p.grow()
+	p.SVGs[piece] = svg
+	p.Sizes[piece] = union.SizeFromMinPt(svg.Size())
+}
+
+func (p *Piece) grow() {
+	if len(p.Images) < rules.PieceCount {
+		p.Images = append(p.Images, make([]image.Image, rules.PieceCount-len(p.Images))...)
+	}
+	if len(p.Sizes) < rules.PieceCount {
+		p.Sizes = append(p.Sizes, make([]union.Size, rules.PieceCount-len(p.Sizes))...)
+	}
+	if len(p.SVGs) < rules.PieceCount {
+		p.SVGs = append(p.SVGs, make([]*util.SVG, rules.PieceCount-len(p.SVGs))...)
+	}
+}
+
+func (p Piece) Draw(ops *op.Ops, piece rules.Piece, at image.Point, size union.Size) {
+	if piece <= rules.NoPiece {
+		return
+	}
+
+	if p.Font != nil {
+		p.Font.Draw(ops, piece, at, size)
+		return
+	}
+
+	if size.Int <= 0 {
+		return
+	}
+	var source any
+	switch {
+	case int(piece) < len(p.SVGs) && p.SVGs[piece] != nil:
+		source = p.SVGs[piece]
+	case int(piece) < len(p.Images) && p.Images[piece] != nil:
+		source = p.Images[piece]
+	default:
+		return
+	}
+
+	imageOp, err := rasters.get(source, size.Int)
+	if err != nil {
+		slog.Error("can't draw piece", "piece", piece, "err", err)
+		return
+	}
+	util.DrawImageOp(ops, imageOp, at)
+}
+
+// sources lists the images and SVGs of the set.
+func (p Piece) sources() []any {
+	var sources []any
+	for _, svg := range p.SVGs {
+		if svg != nil {
+			sources = append(sources, svg)
+		}
+	}
+	for _, img := range p.Images {
+		if img != nil {
+			sources = append(sources, img)
+		}
+	}
+	return sources
 }
 
 type Config struct {
+	// Files and Ranks set the board geometry, zero takes it from rules
+	// implementing rules.Geometry or falls back to 8x8.
+	Files          int
+	Ranks          int
 	ShowHints      bool
 	ShowLastMove   bool
+	ShowCheck      bool
+	ShowGameOver   bool
 	Color          Color
 	AnimationSpeed time.Duration
 	Coordinates    Coordinates
+	// Board draws the squares when there is no BoardImage, or the board
+	// isn't 8x8.
+	Board          Board
 	BoardImage     image.Image
 	BoardImageSize union.Size
 	Piece          Piece
 }
 
+// NewConfig leaves the board to be drawn from Config.Board when
+// boardFilename is empty.
 func NewConfig(boardFilename string, piecesFolderName string) (c Config, err error) {
-	c.BoardImage, err = util.OpenImage(boardFilename)
-	if err != nil {
-		return c, fmt.Errorf("can't load Board image: %Board", err)
-	}
+	c.Board = LichessBrown
+	if boardFilename != "" {
+		c.BoardImage, err = util.OpenImage(boardFilename)
+		if err != nil {
+			return c, fmt.Errorf("can't load Board image: %Board", err)
+		}
 
-	c.BoardImageSize = union.SizeFromMinPt(c.BoardImage.Bounds().Max)
+		c.BoardImageSize = union.SizeFromMinPt(c.BoardImage.Bounds().Max)
+	}
 
-	c.Piece.Images, c.Piece.Sizes, err = loadPieceImages(piecesFolderName)
+	c.Piece, err = LoadPiecesFS(os.DirFS(piecesFolderName), ShortNaming)
 	if err != nil {
-		return c, fmt.Errorf("can't load piece images: %Board", err)
+		return c, fmt.Errorf("can't load piece images: %w", err)
 	}
 
-	c.Color = defaultColors
-
-	return
-}
-
-func loadPieceImages(dir string) (images []image.Image, sizes []union.Size, err error) {
-	images = make([]image.Image, 13)
-	sizes = make([]union.Size, 13)
-
-	for piece := chess.WhiteKing; piece <= chess.BlackPawn; piece++ {
-		fileName := fmt.Sprintf("%s%s.png", piece.Color(), piece.Type())
-		filePath := filepath.Join(dir, fileName)
-
-		images[piece], err = util.OpenImage(filePath)
-		if err != nil {
-			err = fmt.Errorf("failed to open piece file %q: %Board", filePath, err)
-			return
-		}
-
-		sizes[piece] = union.SizeFromMinPt(images[piece].Bounds().Max)
-	}
+	c.Color = DefaultColors()
+	c.AnimationSpeed = 200 * time.Millisecond
 
 	return
 }
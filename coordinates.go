@@ -1,22 +1,29 @@
 package chessboard
 
 import (
+	"cmp"
 	"image"
+	"strconv"
 
+	"gioui.org/f32"
 	"gioui.org/layout"
 	"gioui.org/op"
 	"gioui.org/unit"
 	"gioui.org/widget/material"
-	"github.com/failosof/chessboard/union"
 	"github.com/failosof/chessboard/util"
-	"github.com/notnil/chess"
 )
 
+// CoordinatesStyle labels the files and ranks of a board, Files and Ranks
+// default to 8. Border is the width of the frame the board draws around its
+// squares.
 type CoordinatesStyle struct {
 	Type     Coordinates
 	Theme    *material.Theme
 	FontSize float32
 	Flipped  bool
+	Files    int
+	Ranks    int
+	Border   int
 	Board    layout.Widget
 }
 
@@ -33,31 +40,40 @@ func (s CoordinatesStyle) Layout(gtx layout.Context) layout.Dimensions {
 	}
 }
 
+// Inset is the room the coordinates take around the board.
+func (s CoordinatesStyle) Inset(gtx layout.Context) int {
+	if s.Type == OutsideCoordinates {
+		return gtx.Dp(unit.Dp(s.FontSize))
+	}
+	return 0
+}
+
 func (s CoordinatesStyle) outside(gtx layout.Context) layout.Dimensions {
-	size := union.SizeFromMinPt(gtx.Constraints.Max)
-	boardSize := size.Float - s.FontSize*2
-	squareSize := float32(boardSize) / 8
+	files, ranks := cmp.Or(s.Files, 8), cmp.Or(s.Ranks, 8)
+	inset := s.FontSize + float32(s.Border)
+	size := util.ToF32(gtx.Constraints.Max).Sub(f32.Pt(inset*2, inset*2))
+	squareSize := min(size.X/float32(files), size.Y/float32(ranks))
 	coordPadding := squareSize/2 - s.FontSize/4
 
-	for file := chess.FileA; file <= chess.FileH; file++ {
+	for file := 0; file < files; file++ {
 		i := file
 		if s.Flipped {
-			i = 7 - file
+			i = files - 1 - file
 		}
-		centerX := util.Round(s.FontSize + float32(i)*squareSize + coordPadding)
+		centerX := util.Round(inset + float32(i)*squareSize + coordPadding)
 		stack := op.Offset(image.Pt(centerX, 0)).Push(gtx.Ops)
-		material.Label(s.Theme, unit.Sp(s.FontSize), file.String()).Layout(gtx)
+		material.Label(s.Theme, unit.Sp(s.FontSize), string(rune('a'+file))).Layout(gtx)
 		stack.Pop()
 	}
 
-	for rank := chess.Rank1; rank <= chess.Rank8; rank++ {
+	for rank := 0; rank < ranks; rank++ {
 		i := rank
 		if !s.Flipped {
-			i = 7 - rank
+			i = ranks - 1 - rank
 		}
-		centerY := util.Round(s.FontSize + float32(i)*squareSize + coordPadding)
+		centerY := util.Round(inset + float32(i)*squareSize + coordPadding)
 		stack := op.Offset(image.Pt(0, centerY)).Push(gtx.Ops)
-		material.Label(s.Theme, unit.Sp(s.FontSize), rank.String()).Layout(gtx)
+		material.Label(s.Theme, unit.Sp(s.FontSize), strconv.Itoa(rank+1)).Layout(gtx)
 		stack.Pop()
 	}
 
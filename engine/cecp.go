@@ -0,0 +1,501 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// featureTimeout is how long engines that don't know protover 2 are given
+// to send features, as the protocol asks.
+const featureTimeout = 2 * time.Second
+
+// CECP mate scores are 100000 plus the moves to mate, a bare 100000 of
+// either sign means the side to move is mated.
+const cecpMate = 100_000
+
+var ErrResigned = errors.New("engine resigned")
+
+// CECP talks the XBoard protocol. The engine keeps its own game, so
+// positions that continue the last one only send the new moves, and the
+// engine is kept in force mode between searches so it never moves by itself.
+type CECP struct {
+	name     string
+	features map[string]string
+	w        io.Writer
+	lines    chan string
+	done     chan struct{}
+	proc     *process
+	pings    int
+
+	fen   string // the position the engine has
+	moves []string
+
+	mu sync.Mutex
+}
+
+// NewCECP talks CECP over the given streams, which makes it possible to
+// drive an engine that isn't a local process, or a scripted fake one.
+func NewCECP(r io.Reader, w io.Writer) (*CECP, error) {
+	done := make(chan struct{})
+	e := &CECP{
+		features: make(map[string]string),
+		w:        w,
+		lines:    readLines(r, done),
+		done:     done,
+	}
+
+	if err := e.handshake(); err != nil {
+		close(done)
+		return nil, fmt.Errorf("can't initialize CECP engine: %w", err)
+	}
+	return e, nil
+}
+
+func (e *CECP) handshake() error {
+	for _, cmd := range []string{"xboard", "protover 2"} {
+		if err := e.send(cmd); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+	if err := e.readFeatures(ctx); err != nil {
+		return err
+	}
+	e.name = e.features["myname"]
+	return e.NewGame()
+}
+
+func StartCECP(path string, args ...string) (*CECP, error) {
+	proc, err := startProcess(path, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := NewCECP(proc.stdout, proc.stdin)
+	if err != nil {
+		proc.kill()
+		return nil, err
+	}
+
+	e.proc = proc
+	return e, nil
+}
+
+func (e *CECP) Name() string {
+	return e.name
+}
+
+// Feature returns a feature the engine sent, like variants or setboard.
+func (e *CECP) Feature(name string) (string, bool) {
+	value, ok := e.features[name]
+	return value, ok
+}
+
+// SetOption sets an option the engine announced, buttons take an empty
+// value.
+func (e *CECP) SetOption(name, value string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	cmd := "option " + name
+	if value != "" {
+		cmd += "=" + value
+	}
+	if err := e.send(cmd); err != nil {
+		return err
+	}
+	return e.syncWithTimeout()
+}
+
+func (e *CECP) NewGame() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, cmd := range []string{"new", "force", "post", "easy"} {
+		if err := e.send(cmd); err != nil {
+			return err
+		}
+	}
+	e.fen = chess.StartingPosition().String()
+	e.moves = nil
+	return e.syncWithTimeout()
+}
+
+func (e *CECP) SetPosition(fen string, moves []string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	played := len(e.moves)
+	if fen != e.fen || played > len(moves) || !slices.Equal(moves[:played], e.moves) {
+		if err := e.setBoard(fen); err != nil {
+			return err
+		}
+		played = 0
+	}
+
+	for _, move := range moves[played:] {
+		if err := e.sendMove(move); err != nil {
+			return err
+		}
+		e.moves = append(e.moves, move)
+	}
+	return nil
+}
+
+// Go searches the position, where a time control becomes level, time and
+// otim, a move time becomes st and a depth sd. As st only takes whole
+// seconds the engine is also told to move once the move time is up. Infinite
+// searches use analyze mode and end with the context, their best move is the
+// first of the principal variation.
+func (e *CECP) Go(ctx context.Context, limits Limits, infos chan<- Info) (result Result, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if limits.MoveTime > 0 && !limits.Infinite {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limits.MoveTime)
+		defer cancel()
+	}
+
+	commands := e.limitCommands(limits)
+	if limits.Infinite {
+		commands = append(commands, "analyze")
+	} else {
+		commands = append(commands, "go")
+	}
+	for _, cmd := range commands {
+		if err := e.send(cmd); err != nil {
+			return result, err
+		}
+	}
+
+	done := ctx.Done()
+	var deadline <-chan time.Time
+	for {
+		select {
+		case <-done:
+			if limits.Infinite {
+				if err := e.send("exit"); err != nil {
+					return result, err
+				}
+				if len(result.Info.PV) > 0 {
+					result.BestMove = result.Info.PV[0]
+				}
+				return result, nil
+			}
+			if err := e.send("?"); err != nil {
+				return result, err
+			}
+			done = nil
+			deadline = time.After(stopTimeout)
+		case <-deadline:
+			return result, fmt.Errorf("engine didn't stop in %s", stopTimeout)
+		case line, ok := <-e.lines:
+			if !ok {
+				return result, ErrClosed
+			}
+
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+
+			switch {
+			case fields[0] == "move" && len(fields) > 1:
+				result.BestMove = fields[1]
+				e.moves = append(e.moves, fields[1])
+				return result, e.send("force")
+			case fields[0] == "Hint:" && len(fields) > 1:
+				result.Ponder = fields[1]
+			case fields[0] == "resign":
+				e.send("force")
+				return result, ErrResigned
+			case strings.HasPrefix(fields[0], "Illegal") || strings.HasPrefix(fields[0], "Error"):
+				e.fen = "" // set the board up again next time
+				return result, fmt.Errorf("engine refused: %s", line)
+			default:
+				info, ok := e.parseThinking(fields)
+				if !ok {
+					continue
+				}
+				result.Info = info
+				if infos != nil {
+					select {
+					case infos <- info:
+					default:
+					}
+				}
+			}
+		}
+	}
+}
+
+func (e *CECP) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	select {
+	case <-e.done:
+		return ErrClosed
+	default:
+		close(e.done)
+	}
+
+	err := e.send("quit")
+	if e.proc != nil {
+		return e.proc.wait()
+	}
+	return err
+}
+
+func (e *CECP) send(cmd string) error {
+	slog.Debug("cecp send", "cmd", cmd)
+	if _, err := fmt.Fprintln(e.w, cmd); err != nil {
+		return fmt.Errorf("can't send %q to engine: %w", cmd, err)
+	}
+	return nil
+}
+
+// readFeatures reads feature lines until done=1, or for two seconds when
+// the engine doesn't say it needs more time with done=0. Every feature is
+// accepted except SAN moves.
+func (e *CECP) readFeatures(ctx context.Context) error {
+	timeout := time.After(featureTimeout)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout:
+			return nil
+		case line, ok := <-e.lines:
+			if !ok {
+				return ErrClosed
+			}
+			rest, ok := strings.CutPrefix(strings.TrimSpace(line), "feature ")
+			if !ok {
+				continue
+			}
+
+			for _, feature := range parseFeatures(rest) {
+				name, value := feature[0], feature[1]
+				switch {
+				case name == "done" && value == "0":
+					timeout = nil
+					continue
+				case name == "done":
+					return nil
+				}
+
+				answer := "accepted "
+				if name == "san" && value == "1" {
+					answer = "rejected "
+				} else {
+					e.features[name] = value
+				}
+				if err := e.send(answer + name); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// parseFeatures splits name=value pairs, where values may be quoted.
+func parseFeatures(s string) [][2]string {
+	var features [][2]string
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		name, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			break
+		}
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, " ")
+		}
+		features = append(features, [2]string{name, value})
+		s = rest
+	}
+	return features
+}
+
+func (e *CECP) syncWithTimeout() error {
+	if e.features["ping"] != "1" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+
+	e.pings++
+	pong := strconv.Itoa(e.pings)
+	if err := e.send("ping " + pong); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case line, ok := <-e.lines:
+			if !ok {
+				return ErrClosed
+			}
+			if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "pong" && fields[1] == pong {
+				return nil
+			}
+		}
+	}
+}
+
+func (e *CECP) setBoard(fen string) error {
+	if err := e.send("new"); err != nil {
+		return err
+	}
+	if err := e.send("force"); err != nil {
+		return err
+	}
+	if !isStartingPosition(fen) {
+		if e.features["setboard"] != "1" {
+			return fmt.Errorf("can't set up %s: engine has no setboard", fen)
+		}
+		if err := e.send("setboard " + fen); err != nil {
+			return err
+		}
+	}
+	e.fen, e.moves = fen, nil
+	return nil
+}
+
+// isStartingPosition ignores the move counters, which new sets anyway.
+func isStartingPosition(fen string) bool {
+	start := strings.Fields(chess.StartingPosition().String())
+	fields := strings.Fields(fen)
+	return len(fields) >= 4 && slices.Equal(fields[:4], start[:4])
+}
+
+func (e *CECP) sendMove(move string) error {
+	if e.features["usermove"] == "1" {
+		return e.send("usermove " + move)
+	}
+	return e.send(move)
+}
+
+// limitCommands sets the engine's clock from the point of view of the side
+// to move, which is the side the engine plays once it's told to go.
+func (e *CECP) limitCommands(limits Limits) []string {
+	var commands []string
+	if limits.WhiteTime > 0 || limits.BlackTime > 0 {
+		own, other, inc := limits.WhiteTime, limits.BlackTime, limits.WhiteInc
+		if e.turn() == chess.Black {
+			own, other, inc = limits.BlackTime, limits.WhiteTime, limits.BlackInc
+		}
+		base := int(own.Round(time.Second).Seconds())
+		commands = append(commands,
+			fmt.Sprintf("level %d %d:%02d %d", limits.MovesToGo, base/60, base%60, int(inc.Seconds())),
+			fmt.Sprintf("time %d", own.Milliseconds()/10),
+			fmt.Sprintf("otim %d", other.Milliseconds()/10))
+	}
+	if limits.MoveTime > 0 {
+		commands = append(commands, fmt.Sprintf("st %d", int(math.Ceil(limits.MoveTime.Seconds()))))
+	}
+	if limits.Depth > 0 {
+		commands = append(commands, fmt.Sprintf("sd %d", limits.Depth))
+	}
+	return commands
+}
+
+func (e *CECP) turn() chess.Color {
+	turn := chess.White
+	if fields := strings.Fields(e.fen); len(fields) > 1 && fields[1] == "b" {
+		turn = chess.Black
+	}
+	if len(e.moves)%2 == 1 {
+		turn = turn.Other()
+	}
+	return turn
+}
+
+// parseThinking reads a line of post output: depth, score, time in
+// centiseconds, nodes and the principal variation, which is turned into UCI
+// moves as far as they can be read.
+func (e *CECP) parseThinking(fields []string) (info Info, ok bool) {
+	if len(fields) < 5 {
+		return info, false
+	}
+
+	var numbers [4]int
+	for i := range numbers {
+		n, err := strconv.Atoi(strings.TrimRight(fields[i], ".&*+"))
+		if err != nil {
+			return info, false
+		}
+		numbers[i] = n
+	}
+
+	info.Depth = numbers[0]
+	switch score := numbers[1]; {
+	case score == cecpMate || score == -cecpMate:
+		info.Score = Mated
+	case score > cecpMate:
+		info.Score.Mate = score - cecpMate
+	case score < -cecpMate:
+		info.Score.Mate = score + cecpMate
+	default:
+		info.Score.CP = score
+	}
+	info.Time = time.Duration(numbers[2]) * 10 * time.Millisecond
+	info.Nodes = numbers[3]
+	info.PV = e.pvMoves(fields[4:])
+	return info, true
+}
+
+func (e *CECP) pvMoves(fields []string) []string {
+	fen, err := chess.FEN(e.fen)
+	if err != nil {
+		return nil
+	}
+	game := chess.NewGame(fen, chess.UseNotation(chess.UCINotation{}))
+	for _, move := range e.moves {
+		if err := game.MoveStr(move); err != nil {
+			return nil
+		}
+	}
+
+	uci := chess.UCINotation{}
+	san := chess.AlgebraicNotation{}
+	var pv []string
+	for _, field := range fields {
+		field = strings.TrimRight(field, "!?+#")
+		if field == "" || strings.HasSuffix(field, ".") || strings.HasPrefix(field, "<") || strings.HasPrefix(field, "(") {
+			continue // move numbers and annotations
+		}
+
+		position := game.Position()
+		move, err := uci.Decode(position, field)
+		if err != nil {
+			if move, err = san.Decode(position, field); err != nil {
+				break
+			}
+		}
+		if err := game.Move(move); err != nil {
+			break
+		}
+		pv = append(pv, uci.Encode(position, move))
+	}
+	return pv
+}
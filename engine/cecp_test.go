@@ -0,0 +1,298 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/failosof/chessboard/engine/enginetest"
+)
+
+const startFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+func newFakeCECP(t *testing.T, replies ...[]string) (*CECP, *enginetest.Engine) {
+	t.Helper()
+
+	replies = append(replies, []string{"protover 2",
+		`feature done=0`,
+		`feature myname="Fake 1.0" ping=1 setboard=1 usermove=1 san=1`,
+		`feature variants="normal" done=1`,
+	})
+	script := enginetest.Replies(replies...)
+	fake := enginetest.New(func(cmd string) []string {
+		if n, ok := strings.CutPrefix(cmd, "ping "); ok {
+			return []string{"pong " + n}
+		}
+		return script(cmd)
+	})
+	t.Cleanup(func() { fake.Close() })
+
+	e, err := NewCECP(fake.Stdout, fake.Stdin)
+	if err != nil {
+		t.Fatalf("NewCECP: %v", err)
+	}
+	return e, fake
+}
+
+// sent waits for the fake to have read n commands, the last ones aren't
+// answered so nothing else tells when they arrived.
+func sent(t *testing.T, fake *enginetest.Engine, n int) []string {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		commands := fake.Commands()
+		if len(commands) >= n {
+			return commands
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the engine got %q, want %d commands", commands, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCECPHandshake(t *testing.T) {
+	e, fake := newFakeCECP(t)
+
+	if got, want := e.Name(), "Fake 1.0"; got != want {
+		t.Errorf("Name() = %q, want %q", got, want)
+	}
+	if value, ok := e.Feature("variants"); !ok || value != "normal" {
+		t.Errorf("Feature(variants) = %q, %t, want normal", value, ok)
+	}
+	if _, ok := e.Feature("san"); ok {
+		t.Error("the rejected san feature was kept")
+	}
+
+	want := []string{
+		"xboard", "protover 2",
+		"accepted myname", "accepted ping", "accepted setboard", "accepted usermove", "rejected san", "accepted variants",
+		"new", "force", "post", "easy", "ping 1",
+	}
+	if got := fake.Commands(); !slices.Equal(got, want) {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+}
+
+func TestNewCECPFails(t *testing.T) {
+	fake := enginetest.New(enginetest.Replies([]string{"protover 2", "feature done=0"}))
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		fake.Close()
+	}()
+
+	e, err := NewCECP(fake.Stdout, fake.Stdin)
+	if err == nil {
+		t.Fatal("NewCECP didn't fail when the engine quit during the handshake")
+	}
+	if e != nil {
+		t.Errorf("NewCECP returned an engine with the error %v", err)
+	}
+}
+
+func TestCECPGo(t *testing.T) {
+	e, fake := newFakeCECP(t, []string{"go",
+		"1 15 0 20 e7e5",
+		"thinking about it",
+		"2. -20 3 150 Nf6 Nc3",
+		"Hint: b1c3",
+		"move g8f6",
+	})
+
+	if err := e.SetPosition(startFEN, []string{"e2e4"}); err != nil {
+		t.Fatalf("SetPosition: %v", err)
+	}
+	infos := make(chan Info, 8)
+	limits := Limits{WhiteTime: 5 * time.Minute, BlackTime: 4 * time.Minute, BlackInc: 2 * time.Second, Depth: 10}
+	result, err := e.Go(context.Background(), limits, infos)
+	if err != nil {
+		t.Fatalf("Go: %v", err)
+	}
+
+	if result.BestMove != "g8f6" || result.Ponder != "b1c3" {
+		t.Errorf("best move %s ponder %s, want g8f6 ponder b1c3", result.BestMove, result.Ponder)
+	}
+	want := Info{Depth: 2, Score: Score{CP: -20}, Nodes: 150, Time: 30 * time.Millisecond, PV: []string{"g8f6", "b1c3"}}
+	if got := result.Info; got.Depth != want.Depth || got.Score != want.Score || got.Nodes != want.Nodes ||
+		got.Time != want.Time || !slices.Equal(got.PV, want.PV) {
+		t.Errorf("info = %+v, want %+v", got, want)
+	}
+	if len(infos) != 2 {
+		t.Errorf("got %d infos, want 2", len(infos))
+	}
+
+	// the engine already has the moves up to its own
+	if err := e.SetPosition(startFEN, []string{"e2e4", "g8f6", "b1c3"}); err != nil {
+		t.Fatalf("SetPosition: %v", err)
+	}
+
+	commands := sent(t, fake, 21)
+	if got, want := commands[13:], []string{
+		"usermove e2e4",
+		"level 0 4:00 2", "time 24000", "otim 30000", "sd 10", "go",
+		"force",
+		"usermove b1c3",
+	}; !slices.Equal(got, want) {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+}
+
+func TestCECPSetBoard(t *testing.T) {
+	e, fake := newFakeCECP(t)
+
+	if err := e.SetPosition(startFEN, []string{"e2e4"}); err != nil {
+		t.Fatalf("SetPosition: %v", err)
+	}
+	// a different line sets the board up again
+	fen := "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1"
+	if err := e.SetPosition(fen, []string{"e2e4"}); err != nil {
+		t.Fatalf("SetPosition: %v", err)
+	}
+
+	commands := sent(t, fake, 18)
+	if got, want := commands[13:], []string{
+		"usermove e2e4",
+		"new", "force", "setboard " + fen, "usermove e2e4",
+	}; !slices.Equal(got, want) {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+}
+
+func TestCECPRefusals(t *testing.T) {
+	tests := []struct {
+		reply string
+		err   error
+		want  []string
+	}{
+		{"resign", ErrResigned, []string{"go", "force"}},
+		// the engine's game can't be trusted after a refusal
+		{"Illegal move: e2e4", nil, []string{"go", "new", "force", "usermove e2e4"}},
+		{"Error (unknown command): go", nil, []string{"go", "new", "force", "usermove e2e4"}},
+	}
+
+	for _, test := range tests {
+		e, fake := newFakeCECP(t, []string{"go", test.reply})
+		if err := e.SetPosition(startFEN, []string{"e2e4"}); err != nil {
+			t.Fatalf("SetPosition: %v", err)
+		}
+
+		_, err := e.Go(context.Background(), Limits{Depth: 1}, nil)
+		if err == nil || test.err != nil && !errors.Is(err, test.err) {
+			t.Errorf("%s: Go error = %v, want %v", test.reply, err, test.err)
+		}
+		if err := e.SetPosition(startFEN, []string{"e2e4"}); err != nil {
+			t.Fatalf("SetPosition: %v", err)
+		}
+
+		commands := sent(t, fake, 15+len(test.want))
+		if got := commands[15:]; !slices.Equal(got, test.want) {
+			t.Errorf("%s: commands after sd 1 %q, want %q", test.reply, got, test.want)
+		}
+	}
+}
+
+func TestCECPMoveNow(t *testing.T) {
+	e, fake := newFakeCECP(t,
+		[]string{"go", "5 100012 250 9000 d1h5"},
+		[]string{"?", "move d1h5"},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	result, err := e.Go(ctx, Limits{MoveTime: time.Minute}, nil)
+	if err != nil {
+		t.Fatalf("Go: %v", err)
+	}
+	if result.BestMove != "d1h5" || result.Info.Score.Mate != 12 {
+		t.Errorf("result = %+v, want d1h5 with mate 12", result)
+	}
+	if commands := fake.Commands(); !slices.Contains(commands, "st 60") || !slices.Contains(commands, "?") {
+		t.Errorf("commands = %q, want st 60 and ?", commands)
+	}
+}
+
+func TestCECPMoveTime(t *testing.T) {
+	e, fake := newFakeCECP(t, []string{"?", "move e2e4"})
+
+	start := time.Now()
+	result, err := e.Go(context.Background(), Limits{MoveTime: 50 * time.Millisecond}, nil)
+	if err != nil {
+		t.Fatalf("Go: %v", err)
+	}
+	if result.BestMove != "e2e4" {
+		t.Errorf("best move %s, want e2e4", result.BestMove)
+	}
+	// st rounds up to a second, the engine is made to move before that
+	if took := time.Since(start); took > 500*time.Millisecond {
+		t.Errorf("a 50ms search took %s", took)
+	}
+	commands := sent(t, fake, 17)
+	if got, want := commands[13:], []string{"st 1", "go", "?", "force"}; !slices.Equal(got, want) {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+}
+
+func TestCECPAnalyze(t *testing.T) {
+	e, fake := newFakeCECP(t, []string{"analyze", "8 -100003 120 40000 f2f3 e7e5"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	result, err := e.Go(ctx, Limits{Infinite: true}, nil)
+	if err != nil {
+		t.Fatalf("Go: %v", err)
+	}
+	if result.BestMove != "f2f3" || result.Info.Score.Mate != -3 {
+		t.Errorf("result = %+v, want f2f3 with mate -3", result)
+	}
+	commands := sent(t, fake, 15)
+	if got, want := commands[13:], []string{"analyze", "exit"}; !slices.Equal(got, want) {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+}
+
+func TestCECPEngineQuits(t *testing.T) {
+	e, fake := newFakeCECP(t)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		fake.Close()
+	}()
+
+	if _, err := e.Go(context.Background(), Limits{Depth: 1}, nil); !errors.Is(err, ErrClosed) {
+		t.Errorf("Go error = %v, want %v", err, ErrClosed)
+	}
+}
+
+func TestParseThinking(t *testing.T) {
+	tests := []struct {
+		line  string
+		score Score
+		pv    []string
+		ok    bool
+	}{
+		{"10 35 120 5000 e4 e5 Nf3", Score{CP: 35}, []string{"e2e4", "e7e5", "g1f3"}, true},
+		{"10 -35 120 5000 1. e2e4 e7e5 2. Nf3", Score{CP: -35}, []string{"e2e4", "e7e5", "g1f3"}, true},
+		{"12+ 100003 300 90000 e2e4", Score{Mate: 3}, []string{"e2e4"}, true},
+		{"12 -100002 300 90000 <HT> e4", Score{Mate: -2}, []string{"e2e4"}, true},
+		{"1 -100000 0 1", Score{}, nil, false},
+		{"1 -100000 0 1 e4", Mated, []string{"e2e4"}, true},
+		{"1 100000 0 1 e4", Mated, []string{"e2e4"}, true},
+		{"4 20 10 300 e4 Ke2 Qxe2", Score{CP: 20}, []string{"e2e4"}, true},
+		{"Hint: e4", Score{}, nil, false},
+	}
+
+	e := &CECP{fen: startFEN}
+	for _, test := range tests {
+		info, ok := e.parseThinking(strings.Fields(test.line))
+		if ok != test.ok {
+			t.Errorf("parseThinking(%q) ok = %t, want %t", test.line, ok, test.ok)
+			continue
+		}
+		if ok && (info.Score != test.score || !slices.Equal(info.PV, test.pv)) {
+			t.Errorf("parseThinking(%q) = %+v %q, want %+v %q", test.line, info.Score, info.PV, test.score, test.pv)
+		}
+	}
+}
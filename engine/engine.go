@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+var ErrClosed = errors.New("engine is closed")
+
+// Score is always from the side to move's point of view. Mate is the number
+// of moves to mate, negative when the side to move is getting mated.
+type Score struct {
+	CP   int
+	Mate int
+}
+
+// Mated is the score of a side to move that is checkmated already, which
+// UCI engines send as mate 0. It's worth less than being mated in any number
+// of moves.
+var Mated = Score{CP: -MateScore}
+
+// IsMate reports a mate score, including Mated and its negation.
+func (s Score) IsMate() bool {
+	return s.Mate != 0 || s.CP <= -MateScore || s.CP >= MateScore
+}
+
+// Centipawns folds mate scores into the centipawn range, so a shorter mate is
+// always worth more than a longer one.
+func (s Score) Centipawns() int {
+	switch {
+	case s.Mate > 0:
+		return MateScore - s.Mate
+	case s.Mate < 0:
+		return -MateScore - s.Mate
+	default:
+		return s.CP
+	}
+}
+
+func (s Score) Negate() Score {
+	return Score{CP: -s.CP, Mate: -s.Mate}
+}
+
+func (s Score) String() string {
+	if s.IsMate() {
+		return fmt.Sprintf("#%d", s.Mate)
+	}
+	return fmt.Sprintf("%+.2f", float64(s.CP)/100)
+}
+
+const MateScore = 100_000
+
+type Info struct {
+	Depth   int
+	MultiPV int
+	Score   Score
+	Nodes   int
+	Time    time.Duration
+	PV      []string
+}
+
+type Limits struct {
+	Depth     int
+	Nodes     int
+	MoveTime  time.Duration
+	WhiteTime time.Duration
+	BlackTime time.Duration
+	WhiteInc  time.Duration
+	BlackInc  time.Duration
+	MovesToGo int
+	Infinite  bool
+}
+
+type Result struct {
+	BestMove string
+	Ponder   string
+	Info     Info
+}
+
+// Engine is what the rest of the module needs from a chess engine, whatever
+// protocol it speaks. Moves are in UCI notation and positions are given as a
+// starting FEN plus the moves played from it.
+type Engine interface {
+	Name() string
+	SetOption(name, value string) error
+	NewGame() error
+	SetPosition(fen string, moves []string) error
+	Go(ctx context.Context, limits Limits, infos chan<- Info) (Result, error)
+	Close() error
+}
+
+func SetGamePosition(e Engine, game *chess.Game) error {
+	return SetPositionAt(e, game, len(game.Moves()))
+}
+
+func SetPositionAt(e Engine, game *chess.Game, ply int) error {
+	moves := game.Moves()
+	if ply < 0 || ply > len(moves) {
+		return fmt.Errorf("ply %d out of range", ply)
+	}
+
+	notation := chess.UCINotation{}
+	uciMoves := make([]string, ply)
+	for i, move := range moves[:ply] {
+		uciMoves[i] = notation.Encode(nil, move)
+	}
+	return e.SetPosition(game.Positions()[0].String(), uciMoves)
+}
+
+func formatMoves(moves []string) string {
+	if len(moves) == 0 {
+		return ""
+	}
+	return " moves " + strings.Join(moves, " ")
+}
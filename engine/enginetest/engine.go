@@ -0,0 +1,96 @@
+// Package enginetest fakes chess engines from a script, to try out the
+// drivers of the engine package without running a real engine.
+package enginetest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Script answers a command the driver sent with the lines the engine says
+// back, which may be none.
+type Script func(cmd string) []string
+
+// Engine is the engine's end of the streams a driver talks over: the driver
+// reads from Stdout and writes to Stdin.
+type Engine struct {
+	Stdin  io.Writer
+	Stdout io.Reader
+
+	script   Script
+	in       *io.PipeReader
+	out      *io.PipeWriter
+	commands []string
+
+	mu sync.Mutex
+}
+
+// New starts answering commands with the script.
+func New(script Script) *Engine {
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+
+	e := &Engine{
+		Stdin:  inWriter,
+		Stdout: outReader,
+		script: script,
+		in:     inReader,
+		out:    outWriter,
+	}
+	go e.serve()
+	return e
+}
+
+// Replies answers the commands starting with a prefix with lines, other
+// commands aren't answered. The first matching prefix wins.
+func Replies(replies ...[]string) Script {
+	return func(cmd string) []string {
+		for _, reply := range replies {
+			if strings.HasPrefix(cmd, reply[0]) {
+				return reply[1:]
+			}
+		}
+		return nil
+	}
+}
+
+// Say writes lines without being asked, like thinking output.
+func (e *Engine) Say(lines ...string) error {
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(e.out, line); err != nil {
+			return fmt.Errorf("can't say %q: %w", line, err)
+		}
+	}
+	return nil
+}
+
+// Commands returns what the driver sent so far.
+func (e *Engine) Commands() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return slices.Clone(e.commands)
+}
+
+// Close ends both streams, as if the engine exited.
+func (e *Engine) Close() error {
+	e.in.Close()
+	return e.out.Close()
+}
+
+func (e *Engine) serve() {
+	scanner := bufio.NewScanner(e.in)
+	for scanner.Scan() {
+		cmd := scanner.Text()
+		e.mu.Lock()
+		e.commands = append(e.commands, cmd)
+		e.mu.Unlock()
+
+		if err := e.Say(e.script(cmd)...); err != nil {
+			return
+		}
+	}
+}
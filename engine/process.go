@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"time"
+)
+
+const quitTimeout = 2 * time.Second
+
+type process struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	done   chan error
+}
+
+func startProcess(path string, args ...string) (*process, error) {
+	p := &process{
+		cmd:  exec.Command(path, args...),
+		done: make(chan error, 1),
+	}
+
+	var err error
+	p.stdin, err = p.cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("can't open engine stdin: %w", err)
+	}
+	p.stdout, err = p.cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("can't open engine stdout: %w", err)
+	}
+
+	if err := p.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("can't start engine %q: %w", path, err)
+	}
+	go func() {
+		p.done <- p.cmd.Wait()
+	}()
+
+	return p, nil
+}
+
+func (p *process) wait() error {
+	p.stdin.Close()
+	select {
+	case err := <-p.done:
+		return err
+	case <-time.After(quitTimeout):
+		p.kill()
+		return fmt.Errorf("engine didn't quit in %s", quitTimeout)
+	}
+}
+
+func (p *process) kill() {
+	if p.cmd.Process != nil {
+		p.cmd.Process.Kill()
+	}
+}
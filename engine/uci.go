@@ -0,0 +1,315 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	handshakeTimeout = 10 * time.Second
+	stopTimeout      = 5 * time.Second
+)
+
+type UCI struct {
+	name  string
+	w     io.Writer
+	lines chan string
+	done  chan struct{}
+	proc  *process
+
+	mu sync.Mutex
+}
+
+// NewUCI talks UCI over the given streams, which makes it possible to drive
+// an engine that isn't a local process, or a scripted fake one.
+func NewUCI(r io.Reader, w io.Writer) (*UCI, error) {
+	done := make(chan struct{})
+	e := &UCI{
+		w:     w,
+		lines: readLines(r, done),
+		done:  done,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+
+	if err := e.handshake(ctx); err != nil {
+		close(done)
+		return nil, fmt.Errorf("can't initialize UCI engine: %w", err)
+	}
+	return e, nil
+}
+
+func (e *UCI) handshake(ctx context.Context) error {
+	if err := e.send("uci"); err != nil {
+		return err
+	}
+	err := e.readUntil(ctx, func(fields []string) bool {
+		if len(fields) > 2 && fields[0] == "id" && fields[1] == "name" {
+			e.name = strings.Join(fields[2:], " ")
+		}
+		return fields[0] == "uciok"
+	})
+	if err != nil {
+		return err
+	}
+	return e.sync(ctx)
+}
+
+func StartUCI(path string, args ...string) (*UCI, error) {
+	proc, err := startProcess(path, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := NewUCI(proc.stdout, proc.stdin)
+	if err != nil {
+		proc.kill()
+		return nil, err
+	}
+
+	e.proc = proc
+	return e, nil
+}
+
+func (e *UCI) Name() string {
+	return e.name
+}
+
+func (e *UCI) SetOption(name, value string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.send(fmt.Sprintf("setoption name %s value %s", name, value)); err != nil {
+		return err
+	}
+	return e.syncWithTimeout()
+}
+
+func (e *UCI) NewGame() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.send("ucinewgame"); err != nil {
+		return err
+	}
+	return e.syncWithTimeout()
+}
+
+func (e *UCI) SetPosition(fen string, moves []string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.send("position fen " + fen + formatMoves(moves))
+}
+
+func (e *UCI) Go(ctx context.Context, limits Limits, infos chan<- Info) (result Result, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.send(goCommand(limits)); err != nil {
+		return result, err
+	}
+
+	done := ctx.Done()
+	var deadline <-chan time.Time
+	for {
+		select {
+		case <-done:
+			if err := e.send("stop"); err != nil {
+				return result, err
+			}
+			done = nil
+			deadline = time.After(stopTimeout)
+		case <-deadline:
+			return result, fmt.Errorf("engine didn't stop in %s", stopTimeout)
+		case line, ok := <-e.lines:
+			if !ok {
+				return result, ErrClosed
+			}
+
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+
+			switch fields[0] {
+			case "info":
+				info, ok := parseInfo(fields[1:])
+				if !ok {
+					continue
+				}
+				if info.MultiPV <= 1 {
+					result.Info = info
+				}
+				if infos != nil {
+					select {
+					case infos <- info:
+					default:
+					}
+				}
+			case "bestmove":
+				if len(fields) < 2 {
+					return result, fmt.Errorf("invalid best move line %q", line)
+				}
+				result.BestMove = fields[1]
+				if len(fields) > 3 && fields[2] == "ponder" {
+					result.Ponder = fields[3]
+				}
+				return result, nil
+			}
+		}
+	}
+}
+
+func (e *UCI) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	select {
+	case <-e.done:
+		return ErrClosed
+	default:
+		close(e.done)
+	}
+
+	err := e.send("quit")
+	if e.proc != nil {
+		return e.proc.wait()
+	}
+	return err
+}
+
+func (e *UCI) send(cmd string) error {
+	slog.Debug("uci send", "cmd", cmd)
+	if _, err := fmt.Fprintln(e.w, cmd); err != nil {
+		return fmt.Errorf("can't send %q to engine: %w", cmd, err)
+	}
+	return nil
+}
+
+func (e *UCI) syncWithTimeout() error {
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+	return e.sync(ctx)
+}
+
+func (e *UCI) sync(ctx context.Context) error {
+	if err := e.send("isready"); err != nil {
+		return err
+	}
+	return e.readUntil(ctx, func(fields []string) bool {
+		return fields[0] == "readyok"
+	})
+}
+
+func (e *UCI) readUntil(ctx context.Context, done func(fields []string) bool) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case line, ok := <-e.lines:
+			if !ok {
+				return ErrClosed
+			}
+			if fields := strings.Fields(line); len(fields) > 0 && done(fields) {
+				return nil
+			}
+		}
+	}
+}
+
+// readLines reads until the engine's output ends, or stops handing over
+// lines once done is closed.
+func readLines(r io.Reader, done <-chan struct{}) chan string {
+	lines := make(chan string, 64)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			slog.Debug("engine says", "line", scanner.Text())
+			select {
+			case lines <- scanner.Text():
+			case <-done:
+				return
+			}
+		}
+	}()
+	return lines
+}
+
+func goCommand(limits Limits) string {
+	cmd := []string{"go"}
+	if limits.WhiteTime > 0 {
+		cmd = append(cmd, "wtime", strconv.FormatInt(limits.WhiteTime.Milliseconds(), 10))
+	}
+	if limits.BlackTime > 0 {
+		cmd = append(cmd, "btime", strconv.FormatInt(limits.BlackTime.Milliseconds(), 10))
+	}
+	if limits.WhiteInc > 0 {
+		cmd = append(cmd, "winc", strconv.FormatInt(limits.WhiteInc.Milliseconds(), 10))
+	}
+	if limits.BlackInc > 0 {
+		cmd = append(cmd, "binc", strconv.FormatInt(limits.BlackInc.Milliseconds(), 10))
+	}
+	if limits.MovesToGo > 0 {
+		cmd = append(cmd, "movestogo", strconv.Itoa(limits.MovesToGo))
+	}
+	if limits.Depth > 0 {
+		cmd = append(cmd, "depth", strconv.Itoa(limits.Depth))
+	}
+	if limits.Nodes > 0 {
+		cmd = append(cmd, "nodes", strconv.Itoa(limits.Nodes))
+	}
+	if limits.MoveTime > 0 {
+		cmd = append(cmd, "movetime", strconv.FormatInt(limits.MoveTime.Milliseconds(), 10))
+	}
+	if limits.Infinite {
+		cmd = append(cmd, "infinite")
+	}
+	return strings.Join(cmd, " ")
+}
+
+func parseInfo(fields []string) (info Info, ok bool) {
+	for i := 0; i < len(fields); i++ {
+		next := func() int {
+			if i+1 >= len(fields) {
+				return 0
+			}
+			i++
+			n, _ := strconv.Atoi(fields[i])
+			return n
+		}
+
+		switch fields[i] {
+		case "depth":
+			info.Depth = next()
+		case "multipv":
+			info.MultiPV = next()
+		case "nodes":
+			info.Nodes = next()
+		case "time":
+			info.Time = time.Duration(next()) * time.Millisecond
+		case "cp":
+			info.Score.CP = next()
+			ok = true
+		case "mate":
+			if info.Score.Mate = next(); info.Score.Mate == 0 {
+				info.Score = Mated
+			}
+			ok = true
+		case "pv":
+			info.PV = append([]string(nil), fields[i+1:]...)
+			return info, true
+		case "string":
+			return info, ok
+		}
+	}
+	return info, ok
+}
@@ -0,0 +1,195 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/failosof/chessboard/engine/enginetest"
+)
+
+func newFakeUCI(t *testing.T, replies ...[]string) (*UCI, *enginetest.Engine) {
+	t.Helper()
+
+	replies = append(replies,
+		[]string{"uci", "id name Fake 1.0", "id author Test", "uciok"},
+		[]string{"isready", "readyok"},
+	)
+	fake := enginetest.New(enginetest.Replies(replies...))
+	t.Cleanup(func() { fake.Close() })
+
+	e, err := NewUCI(fake.Stdout, fake.Stdin)
+	if err != nil {
+		t.Fatalf("NewUCI: %v", err)
+	}
+	return e, fake
+}
+
+func TestUCIHandshake(t *testing.T) {
+	e, fake := newFakeUCI(t)
+
+	if got, want := e.Name(), "Fake 1.0"; got != want {
+		t.Errorf("Name() = %q, want %q", got, want)
+	}
+	if err := e.SetOption("Hash", "32"); err != nil {
+		t.Fatalf("SetOption: %v", err)
+	}
+	if err := e.NewGame(); err != nil {
+		t.Fatalf("NewGame: %v", err)
+	}
+
+	want := []string{"uci", "isready", "setoption name Hash value 32", "isready", "ucinewgame", "isready"}
+	if got := fake.Commands(); !slices.Equal(got, want) {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+}
+
+func TestNewUCIFails(t *testing.T) {
+	fake := enginetest.New(enginetest.Replies([]string{"uci", "id name Broken"}))
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		fake.Close()
+	}()
+
+	e, err := NewUCI(fake.Stdout, fake.Stdin)
+	if err == nil {
+		t.Fatal("NewUCI didn't fail when the engine quit during the handshake")
+	}
+	if e != nil {
+		t.Errorf("NewUCI returned an engine with the error %v", err)
+	}
+}
+
+func TestUCIGo(t *testing.T) {
+	e, fake := newFakeUCI(t, []string{"go",
+		"info depth 1 score cp 13 nodes 20 time 1 pv e2e4",
+		"info string thinking hard",
+		"info depth 2 multipv 2 score cp -40 pv d2d4",
+		"info depth 2 multipv 1 score cp 21 nodes 400 time 12 pv e2e4 e7e5",
+		"bestmove e2e4 ponder e7e5",
+	})
+
+	if err := e.SetPosition("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", []string{"g1f3"}); err != nil {
+		t.Fatalf("SetPosition: %v", err)
+	}
+	infos := make(chan Info, 8)
+	result, err := e.Go(context.Background(), Limits{Depth: 2, WhiteTime: time.Minute, BlackInc: time.Second}, infos)
+	if err != nil {
+		t.Fatalf("Go: %v", err)
+	}
+
+	if result.BestMove != "e2e4" || result.Ponder != "e7e5" {
+		t.Errorf("best move %s ponder %s, want e2e4 ponder e7e5", result.BestMove, result.Ponder)
+	}
+	want := Info{Depth: 2, MultiPV: 1, Score: Score{CP: 21}, Nodes: 400, Time: 12 * time.Millisecond, PV: []string{"e2e4", "e7e5"}}
+	if got := result.Info; got.Depth != want.Depth || got.Score != want.Score || got.Nodes != want.Nodes ||
+		got.Time != want.Time || !slices.Equal(got.PV, want.PV) {
+		t.Errorf("info = %+v, want %+v", got, want)
+	}
+	if len(infos) != 3 {
+		t.Errorf("got %d infos, want 3", len(infos))
+	}
+
+	commands := fake.Commands()
+	if got, want := commands[len(commands)-2:], []string{
+		"position fen rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 moves g1f3",
+		"go wtime 60000 binc 1000 depth 2",
+	}; !slices.Equal(got, want) {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+}
+
+func TestUCIStop(t *testing.T) {
+	e, fake := newFakeUCI(t,
+		[]string{"go", "info depth 30 score mate 3 pv h5f7"},
+		[]string{"stop", "bestmove h5f7"},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	result, err := e.Go(ctx, Limits{Infinite: true}, nil)
+	if err != nil {
+		t.Fatalf("Go: %v", err)
+	}
+	if result.BestMove != "h5f7" || result.Info.Score.Mate != 3 {
+		t.Errorf("result = %+v, want h5f7 with mate 3", result)
+	}
+	if commands := fake.Commands(); !slices.Contains(commands, "go infinite") || !slices.Contains(commands, "stop") {
+		t.Errorf("commands = %q, want go infinite and stop", commands)
+	}
+}
+
+func TestUCIEngineQuits(t *testing.T) {
+	e, fake := newFakeUCI(t)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		fake.Close()
+	}()
+
+	if _, err := e.Go(context.Background(), Limits{Depth: 1}, nil); !errors.Is(err, ErrClosed) {
+		t.Errorf("Go error = %v, want %v", err, ErrClosed)
+	}
+}
+
+func TestParseInfo(t *testing.T) {
+	tests := []struct {
+		line  string
+		score Score
+		ok    bool
+	}{
+		{"depth 10 score cp 35 pv e2e4", Score{CP: 35}, true},
+		{"depth 10 score cp -120 lowerbound nodes 5", Score{CP: -120}, true},
+		{"depth 12 score mate 4 pv d1h5", Score{Mate: 4}, true},
+		{"depth 12 score mate -2", Score{Mate: -2}, true},
+		{"depth 0 score mate 0", Mated, true},
+		{"depth 5 nodes 100 currmove e2e4", Score{}, false},
+		{"string score cp 10", Score{}, false},
+	}
+
+	for _, test := range tests {
+		info, ok := parseInfo(strings.Fields(test.line))
+		if ok != test.ok || info.Score != test.score {
+			t.Errorf("parseInfo(%q) = %+v, %t, want score %+v, %t", test.line, info.Score, ok, test.score, test.ok)
+		}
+	}
+}
+
+func TestMatedScore(t *testing.T) {
+	if !Mated.IsMate() || !Mated.Negate().IsMate() {
+		t.Error("mated isn't a mate score")
+	}
+	if got := Mated.String(); got != "#0" {
+		t.Errorf("mated prints as %s, want #0", got)
+	}
+	if Mated.Centipawns() >= (Score{Mate: -1}).Centipawns() {
+		t.Errorf("mated %d isn't worse than mated in one %d", Mated.Centipawns(), (Score{Mate: -1}).Centipawns())
+	}
+	if Mated.Negate().Centipawns() <= (Score{Mate: 1}).Centipawns() {
+		t.Errorf("having mated %d isn't better than mate in one %d", Mated.Negate().Centipawns(), (Score{Mate: 1}).Centipawns())
+	}
+}
+
+func TestReadLinesStops(t *testing.T) {
+	done := make(chan struct{})
+	close(done)
+
+	lines := readLines(strings.NewReader(strings.Repeat("info depth 1\n", 1000)), done)
+	read := 0
+	timeout := time.After(time.Second)
+	for open := true; open; {
+		select {
+		case _, open = <-lines:
+			if open {
+				read++
+			}
+		case <-timeout:
+			t.Fatal("readLines kept going after done")
+		}
+	}
+	if read == 1000 {
+		t.Error("readLines handed over every line after done")
+	}
+}
@@ -0,0 +1,40 @@
+package chessboard
+
+import (
+	"github.com/failosof/chessboard/rules"
+)
+
+type EventType int8
+
+const (
+	NoEvent EventType = iota
+	MoveEvent
+	TakebackEvent
+	RedoEvent
+	// AnnotateEvent is emitted after annotations were drawn or cleared on
+	// the board, Widget.Annotations has them.
+	AnnotateEvent
+)
+
+func (t EventType) String() string {
+	switch t {
+	case MoveEvent:
+		return "move"
+	case TakebackEvent:
+		return "takeback"
+	case RedoEvent:
+		return "redo"
+	case AnnotateEvent:
+		return "annotate"
+	default:
+		return "none"
+	}
+}
+
+// Event tells what happened on the board. Move is the move made, taken back
+// or redone, whatever rules drive the board, and FEN the position after it.
+type Event struct {
+	Type EventType
+	Move rules.Move
+	FEN  string
+}
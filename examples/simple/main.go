@@ -1,8 +1,10 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log/slog"
+	"math/rand/v2"
 	"os"
 	"time"
 
@@ -15,11 +17,17 @@ import (
 	"gioui.org/widget"
 	"gioui.org/widget/material"
 	"github.com/failosof/chessboard"
+	"github.com/failosof/chessboard/engine"
+	"github.com/failosof/chessboard/play"
+	"github.com/failosof/chessboard/rules"
 	"github.com/failosof/chessboard/util"
 	"github.com/notnil/chess"
 )
 
+var enginePath = flag.String("engine", "", "UCI engine to play against, like stockfish")
+
 func main() {
+	flag.Parse()
 	slog.SetLogLoggerLevel(slog.LevelDebug)
 	go func() {
 		if err := draw(new(app.Window)); err != nil {
@@ -41,6 +49,8 @@ func draw(window *app.Window) error {
 
 	config.ShowHints = true
 	config.ShowLastMove = true
+	config.ShowCheck = true
+	config.ShowGameOver = true
 	config.Coordinates = chessboard.OutsideCoordinates
 
 	pos, err := chess.FEN("8/PPPPPPPP/8/8/8/8/8/3K2k1 w - - 0 1")
@@ -66,6 +76,48 @@ func draw(window *app.Window) error {
 	}()
 
 	flipBtn := new(widget.Clickable)
+	takebackBtn := new(widget.Clickable)
+	redoBtn := new(widget.Clickable)
+	chess960Btn := new(widget.Clickable)
+	crazyhouseBtn := new(widget.Clickable)
+	minichessBtn := new(widget.Clickable)
+	variantBtn := new(widget.Clickable)
+	themeBtn := new(widget.Clickable)
+	themes := chessboard.NewThemes()
+	boardThemes := themes.Boards()
+	boardTheme := 0
+	variant := rules.Standard
+
+	// playing the engine, when one was given
+	var eng engine.Engine
+	var versus *play.Game
+	var clock *chessboard.Clock
+	versusBtns := new(versusButtons)
+	level := 3
+	if *enginePath != "" {
+		uci, err := engine.StartUCI(*enginePath)
+		if err != nil {
+			return err
+		}
+		defer uci.Close()
+		eng = uci
+	}
+	defer func() {
+		if versus != nil {
+			versus.Close()
+		}
+	}()
+	startGame := func(human rules.Color) {
+		if versus != nil {
+			versus.Close()
+		}
+		clock = chessboard.NewClock(5*time.Minute, 3*time.Second)
+		versus = play.NewGame(eng, board, clock)
+		versus.OnUpdate = window.Invalidate
+		if err := versus.Start("", human, play.Levels[level]); err != nil {
+			slog.Warn("can't start game", "err", err)
+		}
+	}
 
 	var ops op.Ops
 	for {
@@ -78,6 +130,82 @@ func draw(window *app.Window) error {
 			if flipBtn.Clicked(gtx) {
 				board.Flip(gtx)
 			}
+			if takebackBtn.Clicked(gtx) {
+				if versus != nil {
+					if err := versus.Takeback(); err != nil {
+						slog.Warn("can't take back", "err", err)
+					}
+				} else if err := board.Takeback(gtx); err != nil {
+					slog.Warn("can't take back", "err", err)
+				}
+			}
+			if versusBtns.white.Clicked(gtx) {
+				startGame(rules.White)
+			}
+			if versusBtns.black.Clicked(gtx) {
+				startGame(rules.Black)
+			}
+			if versusBtns.level.Clicked(gtx) {
+				level = (level + 1) % len(play.Levels)
+			}
+			if versusBtns.resign.Clicked(gtx) && versus != nil {
+				if err := versus.Resign(); err != nil {
+					slog.Warn("can't resign", "err", err)
+				}
+			}
+			if versusBtns.draw.Clicked(gtx) && versus != nil {
+				accepted, err := versus.OfferDraw()
+				if err != nil {
+					slog.Warn("can't offer draw", "err", err)
+				}
+				slog.Debug("draw offer", "accepted", accepted)
+			}
+			if versus != nil {
+				versus.CheckClock(gtx.Now)
+			}
+			if redoBtn.Clicked(gtx) {
+				if err := board.Redo(gtx); err != nil {
+					slog.Warn("can't redo", "err", err)
+				}
+			}
+			if chess960Btn.Clicked(gtx) {
+				if err := board.SetChess960(rand.IntN(rules.Chess960Positions)); err != nil {
+					slog.Warn("can't set up chess960", "err", err)
+				}
+				slog.Debug("chess960 position", "fen", board.FEN())
+			}
+			if crazyhouseBtn.Clicked(gtx) {
+				board.SetRules(rules.NewCrazyhouseGame())
+			}
+			if themeBtn.Clicked(gtx) {
+				boardTheme = (boardTheme + 1) % len(boardThemes)
+				theme, err := themes.Theme(boardThemes[boardTheme], "aquarium")
+				if err != nil {
+					slog.Warn("can't load theme", "err", err)
+				} else {
+					board.SetTheme(theme)
+				}
+			}
+			if variantBtn.Clicked(gtx) {
+				variant = rules.Variants[(int(variant)+1)%len(rules.Variants)]
+				board.SetRules(rules.NewVariantGame(variant))
+				slog.Debug("variant", "name", variant)
+			}
+			if minichessBtn.Clicked(gtx) {
+				if err := board.SetFEN(rules.GardnerFEN); err != nil {
+					slog.Warn("can't set up minichess", "err", err)
+				}
+			}
+			for {
+				ev, ok := board.Update(gtx)
+				if !ok {
+					break
+				}
+				slog.Debug("board event", "type", ev.Type, "move", ev.Move)
+				if versus != nil {
+					versus.HandleEvent(ev)
+				}
+			}
 			layout.Background{}.Layout(
 				gtx,
 				func(gtx layout.Context) layout.Dimensions {
@@ -113,8 +241,70 @@ func draw(window *app.Window) error {
 										},
 									)
 								}),
+								layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+									return layout.UniformInset(unit.Dp(20)).Layout(
+										gtx,
+										func(gtx layout.Context) layout.Dimensions {
+											return material.Button(th, takebackBtn, "Takeback").Layout(gtx)
+										},
+									)
+								}),
+								layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+									return layout.UniformInset(unit.Dp(20)).Layout(
+										gtx,
+										func(gtx layout.Context) layout.Dimensions {
+											return material.Button(th, redoBtn, "Redo").Layout(gtx)
+										},
+									)
+								}),
+								layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+									return layout.UniformInset(unit.Dp(20)).Layout(
+										gtx,
+										func(gtx layout.Context) layout.Dimensions {
+											return material.Button(th, chess960Btn, "Chess960").Layout(gtx)
+										},
+									)
+								}),
+								layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+									return layout.UniformInset(unit.Dp(20)).Layout(
+										gtx,
+										func(gtx layout.Context) layout.Dimensions {
+											return material.Button(th, crazyhouseBtn, "Crazyhouse").Layout(gtx)
+										},
+									)
+								}),
+								layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+									return layout.UniformInset(unit.Dp(20)).Layout(
+										gtx,
+										func(gtx layout.Context) layout.Dimensions {
+											return material.Button(th, minichessBtn, "Minichess").Layout(gtx)
+										},
+									)
+								}),
+								layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+									return layout.UniformInset(unit.Dp(20)).Layout(
+										gtx,
+										func(gtx layout.Context) layout.Dimensions {
+											return material.Button(th, variantBtn, variant.String()).Layout(gtx)
+										},
+									)
+								}),
+								layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+									return layout.UniformInset(unit.Dp(20)).Layout(
+										gtx,
+										func(gtx layout.Context) layout.Dimensions {
+											return material.Button(th, themeBtn, boardThemes[boardTheme]).Layout(gtx)
+										},
+									)
+								}),
 							)
 						}),
+						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+							if eng == nil {
+								return layout.Dimensions{}
+							}
+							return layoutVersus(gtx, th, versus, clock, level, versusBtns)
+						}),
 						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
 							return layout.UniformInset(unit.Dp(20)).Layout(gtx, board.Layout)
 						}),
@@ -126,3 +316,43 @@ func draw(window *app.Window) error {
 		}
 	}
 }
+
+type versusButtons struct {
+	white, black, level, resign, draw widget.Clickable
+}
+
+// layoutVersus shows the clocks of a game against the engine, with the
+// engine's on top, and the buttons to play it.
+func layoutVersus(gtx layout.Context, th *material.Theme, versus *play.Game, clock *chessboard.Clock, level int, btns *versusButtons) layout.Dimensions {
+	button := func(btn *widget.Clickable, label string) layout.FlexChild {
+		return layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.UniformInset(unit.Dp(20)).Layout(gtx, material.Button(th, btn, label).Layout)
+		})
+	}
+	clockFor := func(color func() rules.Color) layout.FlexChild {
+		return layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			if versus == nil {
+				return layout.Dimensions{}
+			}
+			return layout.UniformInset(unit.Dp(20)).Layout(gtx, chessboard.ClockStyle{
+				Theme:      th,
+				Clock:      clock,
+				Color:      color(),
+				TextSize:   unit.Sp(24),
+				Background: util.GrayColor,
+				Foreground: util.WhiteColor,
+				Active:     util.BlackColor,
+			}.Layout)
+		})
+	}
+
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		clockFor(func() rules.Color { return versus.Human().Other() }),
+		button(&btns.white, "Play White"),
+		button(&btns.black, "Play Black"),
+		button(&btns.level, fmt.Sprintf("Level %d", level+1)),
+		button(&btns.resign, "Resign"),
+		button(&btns.draw, "Offer draw"),
+		clockFor(func() rules.Color { return versus.Human() }),
+	)
+}
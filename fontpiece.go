@@ -0,0 +1,157 @@
+package chessboard
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"sync"
+
+	"gioui.org/f32"
+	"gioui.org/font"
+	"gioui.org/font/opentype"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/failosof/chessboard/rules"
+	"github.com/failosof/chessboard/union"
+	"github.com/failosof/chessboard/util"
+)
+
+// UnicodeGlyphs maps both colors to the filled Unicode chess symbols, the
+// FontPieces colors tell them apart.
+var UnicodeGlyphs = map[rules.Piece]rune{
+	rules.WhiteKing: '♚', rules.WhiteQueen: '♛', rules.WhiteRook: '♜',
+	rules.WhiteBishop: '♝', rules.WhiteKnight: '♞', rules.WhitePawn: '♟',
+	rules.BlackKing: '♚', rules.BlackQueen: '♛', rules.BlackRook: '♜',
+	rules.BlackBishop: '♝', rules.BlackKnight: '♞', rules.BlackPawn: '♟',
+}
+
+// FontPieces draws pieces as glyphs of a chess font, or of any font with
+// the Unicode chess symbols. Pieces are vector shapes, so they are sharp at
+// every size. Sizes and offsets are fractions of the square size.
+type FontPieces struct {
+	Shaper *text.Shaper
+	Font   font.Font
+	Glyphs map[rules.Piece]rune
+	// Scale is the font size, where 1 is an em as high as the square.
+	Scale        float32
+	White        color.NRGBA
+	Black        color.NRGBA
+	Outline      color.NRGBA
+	OutlineWidth float32
+	Shadow       color.NRGBA
+	ShadowOffset f32.Point
+
+	// the shaper isn't safe for concurrent use, and boards in different
+	// windows draw from different goroutines
+	mu sync.Mutex
+}
+
+// NewFontPieces draws the Unicode chess symbols with the shaper fonts, the
+// system fonts usually have them.
+func NewFontPieces(shaper *text.Shaper) *FontPieces {
+	return &FontPieces{
+		Shaper:       shaper,
+		Glyphs:       UnicodeGlyphs,
+		Scale:        0.9,
+		White:        util.WhiteColor,
+		Black:        util.BlackColor,
+		Outline:      util.BlackColor,
+		OutlineWidth: 0.04,
+		Shadow:       util.Transparentize(util.BlackColor, 0.7),
+		ShadowOffset: f32.Pt(0.02, 0.03),
+	}
+}
+
+// OpenFontPieces loads a TTF or OTF chess font, glyphs maps its characters
+// to the pieces.
+func OpenFontPieces(filename string, glyphs map[rules.Piece]rune) (*FontPieces, error) {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("can't read font: %w", err)
+	}
+	faces, err := opentype.ParseCollection(src)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse font: %w", err)
+	}
+
+	f := NewFontPieces(text.NewShaper(text.NoSystemFonts(), text.WithCollection(faces)))
+	f.Font = faces[0].Font
+	f.Glyphs = glyphs
+	return f, nil
+}
+
+func (f *FontPieces) Draw(ops *op.Ops, piece rules.Piece, at image.Point, size union.Size) {
+	glyph, ok := f.Glyphs[piece]
+	if !ok || f.Shaper == nil || size.Int <= 0 {
+		return
+	}
+
+	path, bounds := f.shape(glyph, size.F32.Y)
+	if bounds.Empty() {
+		return
+	}
+
+	// center the ink of the glyph on the square
+	center := util.ToF32(at).Add(size.F32.Div(2))
+	inkCenter := f32.Pt(fixedToF32(bounds.Min.X+bounds.Max.X), fixedToF32(bounds.Min.Y+bounds.Max.Y)).Div(2)
+	origin := center.Sub(inkCenter)
+
+	if f.Shadow.A > 0 {
+		shadow := origin.Add(f32.Pt(f.ShadowOffset.X*size.F32.X, f.ShadowOffset.Y*size.F32.Y))
+		f.fill(ops, path, shadow, f.Shadow, f.OutlineWidth*size.F32.Y)
+	}
+
+	fill := f.White
+	if piece.Color() == rules.Black {
+		fill = f.Black
+	}
+	if f.Outline.A > 0 {
+		f.fill(ops, path, origin, f.Outline, f.OutlineWidth*size.F32.Y)
+	}
+	f.fill(ops, path, origin, fill, 0)
+}
+
+// fill paints the glyph, grown by half the stroke width when it's set.
+func (f *FontPieces) fill(ops *op.Ops, path clip.PathSpec, at f32.Point, fill color.NRGBA, stroke float32) {
+	defer op.Affine(f32.Affine2D{}.Offset(at)).Push(ops).Pop()
+	if stroke > 0 {
+		paint.FillShape(ops, fill, clip.Stroke{Path: path, Width: stroke}.Op())
+	}
+	paint.FillShape(ops, fill, clip.Outline{Path: path}.Op())
+}
+
+// shape returns the glyph path and its ink box, both relative to the dot.
+func (f *FontPieces) shape(glyph rune, size float32) (clip.PathSpec, fixed.Rectangle26_6) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	scale := f.Scale
+	if scale <= 0 {
+		scale = 1
+	}
+	// without a MaxWidth the glyph would be truncated away
+	f.Shaper.LayoutString(text.Parameters{
+		Font:     f.Font,
+		PxPerEm:  fixed.Int26_6(size * scale * 64),
+		MaxWidth: int(4 * size * scale),
+		MaxLines: 1,
+	}, string(glyph))
+
+	var glyphs []text.Glyph
+	for g, ok := f.Shaper.NextGlyph(); ok; g, ok = f.Shaper.NextGlyph() {
+		glyphs = append(glyphs, g)
+	}
+	if len(glyphs) == 0 {
+		return clip.PathSpec{}, fixed.Rectangle26_6{}
+	}
+	return f.Shaper.Shape(glyphs[:1]), glyphs[0].Bounds
+}
+
+func fixedToF32(v fixed.Int26_6) float32 {
+	return float32(v) / 64
+}
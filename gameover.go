@@ -0,0 +1,63 @@
+package chessboard
+
+import (
+	"image"
+	"image/color"
+
+	"gioui.org/layout"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+	"github.com/failosof/chessboard/rules"
+	"github.com/failosof/chessboard/util"
+)
+
+func FormatOutcome(outcome rules.Outcome) string {
+	if outcome == rules.Draw {
+		return "½-½"
+	}
+	return outcome.String()
+}
+
+type GameOver struct {
+	Theme      *material.Theme
+	Outcome    rules.Outcome
+	Method     rules.Method
+	BoardSize  image.Point
+	Background color.NRGBA
+	Foreground color.NRGBA
+}
+
+func (g GameOver) Layout(gtx layout.Context) layout.Dimensions {
+	util.DrawPane(gtx.Ops, image.Rectangle{Max: g.BoardSize}, g.Background)
+
+	side := min(g.BoardSize.X, g.BoardSize.Y)
+	resultSize := gtx.Metric.PxToSp(side / 8)
+	reasonSize := gtx.Metric.PxToSp(side / 20)
+
+	gtx.Constraints = layout.Exact(g.BoardSize)
+	return layout.Center.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		gtx.Constraints.Min = image.Point{}
+		return layout.Flex{
+			Axis:      layout.Vertical,
+			Alignment: layout.Middle,
+		}.Layout(
+			gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				label := material.Label(g.Theme, resultSize, FormatOutcome(g.Outcome))
+				label.Color = g.Foreground
+				label.Alignment = text.Middle
+				return label.Layout(gtx)
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if g.Method == rules.NoMethod {
+					return layout.Dimensions{}
+				}
+				label := material.Label(g.Theme, reasonSize, g.Method.String())
+				label.Color = g.Foreground
+				label.Alignment = text.Middle
+				return layout.Inset{Top: unit.Dp(reasonSize / 2)}.Layout(gtx, label.Layout)
+			}),
+		)
+	})
+}
@@ -5,5 +5,15 @@ go 1.23
 require (
 	gioui.org v0.7.1
 	github.com/notnil/chess v1.10.0
+	github.com/srwiley/oksvg v0.0.0-20221011165216-be6e8873101c
+	github.com/srwiley/rasterx v0.0.0-20210519020934-456a8d69b780
 	golang.org/x/exp v0.0.0-20240707233637-46b078467d37
+	golang.org/x/image v0.18.0
+	golang.org/x/net v0.26.0
+)
+
+require (
+	github.com/go-text/typesetting v0.1.1 // indirect
+	golang.org/x/exp/shiny v0.0.0-20240707233637-46b078467d37 // indirect
+	golang.org/x/text v0.16.0 // indirect
 )
@@ -0,0 +1,387 @@
+package chessboard
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"image"
+	"slices"
+	"sync"
+
+	"gioui.org/font"
+	"gioui.org/io/event"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+	"github.com/failosof/chessboard/rules"
+	"github.com/failosof/chessboard/union"
+	"github.com/failosof/chessboard/util"
+)
+
+// Grid shows many games at once as small read-only boards, like the boards
+// of a simul or a tournament round. Every board is drawn once per position
+// and kept as a macro, so a frame where one game moved redraws only that
+// board. Pieces come from the same scaled images as full boards do.
+type Grid struct {
+	th     *material.Theme
+	config Config
+
+	// MinSize is the narrowest a board gets before the grid drops a column.
+	MinSize unit.Dp
+	Spacing unit.Dp
+
+	boards  []*MiniBoard
+	clicked []*MiniBoard
+	list    layout.List
+
+	mu sync.Mutex
+}
+
+// ErrNoGame is returned for moves on a board that was added without a game.
+var ErrNoGame = errors.New("board has no game")
+
+// MiniBoard is a board of a Grid, its game may be moved on from anywhere as
+// long as it isn't moved while the grid is laid out.
+type MiniBoard struct {
+	white   string
+	black   string
+	game    rules.Rules
+	flipped bool
+	dirty   bool
+
+	hash    uint64
+	outcome rules.Outcome
+	width   int
+	drawing op.CallOp
+	// squaresImage keeps textured squares between positions
+	squaresImage *image.RGBA
+
+	mu sync.Mutex
+}
+
+func NewGrid(th *material.Theme, config Config) *Grid {
+	return &Grid{
+		th:      th,
+		config:  config,
+		MinSize: 200,
+		Spacing: 8,
+		list:    layout.List{Axis: layout.Vertical},
+	}
+}
+
+func (g *Grid) Add(white, black string, game rules.Rules) *MiniBoard {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	board := &MiniBoard{white: white, black: black, game: game, dirty: true}
+	g.boards = append(g.boards, board)
+	return board
+}
+
+func (g *Grid) Remove(board *MiniBoard) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.boards = slices.DeleteFunc(g.boards, func(b *MiniBoard) bool {
+		return b == board
+	})
+}
+
+func (g *Grid) Boards() []*MiniBoard {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return slices.Clone(g.boards)
+}
+
+func (g *Grid) SetConfig(config Config) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.config = config
+	for _, board := range g.boards {
+		board.invalidate()
+	}
+}
+
+// Update returns the next board that was clicked, to be opened with Open.
+func (g *Grid) Update(gtx layout.Context) (*MiniBoard, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(g.clicked) == 0 {
+		return nil, false
+	}
+
+	board := g.clicked[0]
+	g.clicked = g.clicked[1:]
+	return board, true
+}
+
+// Open makes a full interactive widget for a board of the grid. Both share
+// the game, so moves made on the widget show up in the grid.
+func (g *Grid) Open(board *MiniBoard) *Widget {
+	g.mu.Lock()
+	w := NewWidget(g.th, g.config)
+	g.mu.Unlock()
+
+	w.SetRules(board.Rules())
+	w.SetFlipped(board.Flipped())
+	return w
+}
+
+func (g *Grid) Layout(gtx layout.Context) layout.Dimensions {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	spacing := gtx.Dp(g.Spacing)
+	minSize := max(gtx.Dp(cmp.Or(g.MinSize, 200)), 1)
+	columns := max((gtx.Constraints.Max.X+spacing)/(minSize+spacing), 1)
+	width := (gtx.Constraints.Max.X - (columns-1)*spacing) / columns
+	rows := (len(g.boards) + columns - 1) / columns
+
+	return g.list.Layout(gtx, rows, func(gtx layout.Context, row int) layout.Dimensions {
+		height := 0
+		for column := 0; column < columns; column++ {
+			i := row*columns + column
+			if i >= len(g.boards) {
+				break
+			}
+
+			stack := op.Offset(image.Pt(column*(width+spacing), 0)).Push(gtx.Ops)
+			dims := g.layoutBoard(gtx, g.boards[i], width)
+			stack.Pop()
+			height = max(height, dims.Size.Y)
+		}
+		return layout.Dimensions{Size: image.Pt(gtx.Constraints.Max.X, height+spacing)}
+	})
+}
+
+func (g *Grid) layoutBoard(gtx layout.Context, board *MiniBoard, width int) layout.Dimensions {
+	board.mu.Lock()
+	defer board.mu.Unlock()
+
+	var hash uint64
+	outcome := rules.NoOutcome
+	if board.game != nil {
+		hash = board.game.Hash()
+		if outcomer, ok := board.game.(rules.Outcomer); ok {
+			outcome, _ = outcomer.Outcome()
+		}
+	}
+
+	if board.dirty || board.hash != hash || board.outcome != outcome || board.width != width {
+		board.hash, board.outcome, board.width = hash, outcome, width
+		board.dirty = false
+
+		cache := new(op.Ops)
+		macro := op.Record(cache)
+		rgtx := gtx
+		rgtx.Ops = cache
+		g.drawBoard(rgtx, board, width)
+		board.drawing = macro.Stop()
+	}
+
+	board.drawing.Add(gtx.Ops)
+	size := image.Pt(width, board.height(gtx, width))
+
+	area := clip.Rect(image.Rectangle{Max: size}).Push(gtx.Ops)
+	event.Op(gtx.Ops, board)
+	pointer.CursorPointer.Add(gtx.Ops)
+	area.Pop()
+
+	for {
+		ev, ok := gtx.Event(pointer.Filter{
+			Target: board,
+			Kinds:  pointer.Press,
+		})
+		if !ok {
+			break
+		}
+
+		if e, ok := ev.(pointer.Event); ok && e.Buttons == pointer.ButtonPrimary {
+			g.clicked = append(g.clicked, board)
+		}
+	}
+
+	return layout.Dimensions{Size: size}
+}
+
+func (g *Grid) drawBoard(gtx layout.Context, board *MiniBoard, width int) {
+	header := gtx.Sp(miniHeaderSize)
+	headerGtx := gtx
+	headerGtx.Constraints = layout.Exact(image.Pt(width, header))
+	g.layoutHeader(headerGtx, board)
+
+	if board.game == nil {
+		return
+	}
+
+	defer op.Offset(image.Pt(0, header)).Push(gtx.Ops).Pop()
+
+	files, ranks := board.geometry()
+	squareSize := union.SizeFromFloat(float32(width) / float32(files))
+	boardSize := image.Pt(width, util.Round(squareSize.Float*float32(ranks)))
+
+	origin := func(square rules.Square) image.Point {
+		return util.SquareToPoint(square, squareSize.Float, files, ranks, board.flipped).Round()
+	}
+	rect := func(square rules.Square) image.Rectangle {
+		at := util.SquareToPoint(square, squareSize.Float, files, ranks, board.flipped)
+		return image.Rectangle{Min: at.Round(), Max: at.Add(squareSize.F32).Round()}
+	}
+	board.squaresImage = drawBackground(gtx.Ops, g.config, files, ranks, boardSize, rect, board.squaresImage)
+
+	if lastMove, ok := board.game.LastMove(); ok {
+		if lastMove.From != rules.NoSquare {
+			util.DrawPane(gtx.Ops, rect(lastMove.From), g.config.Color.LastMove)
+		}
+		util.DrawPane(gtx.Ops, rect(lastMove.To), g.config.Color.LastMove)
+	}
+
+	for rank := 0; rank < ranks; rank++ {
+		for file := 0; file < files; file++ {
+			square := rules.NewSquare(file, rank)
+			g.config.Piece.Draw(gtx.Ops, board.game.Piece(square), origin(square), squareSize)
+		}
+	}
+}
+
+// miniHeaderSize is the height of the line with the players above a board.
+const miniHeaderSize = unit.Sp(20)
+
+func (g *Grid) layoutHeader(gtx layout.Context, board *MiniBoard) layout.Dimensions {
+	textSize := miniHeaderSize * 0.65
+	return layout.Flex{Alignment: layout.Middle}.Layout(
+		gtx,
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			gtx.Constraints.Min = image.Point{}
+			label := material.Label(g.th, textSize, board.white+" – "+board.black)
+			label.MaxLines = 1
+			return label.Layout(gtx)
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			if board.outcome == rules.NoOutcome {
+				return layout.Dimensions{}
+			}
+
+			gtx.Constraints.Min = image.Point{}
+			macro := op.Record(gtx.Ops)
+			dims := layout.UniformInset(unit.Dp(2)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				label := material.Label(g.th, textSize, FormatOutcome(board.outcome))
+				label.Color = util.WhiteColor
+				label.Font.Weight = font.Bold
+				label.MaxLines = 1
+				return label.Layout(gtx)
+			})
+			call := macro.Stop()
+
+			badge := clip.UniformRRect(image.Rectangle{Max: dims.Size}, dims.Size.Y/4)
+			paint.FillShape(gtx.Ops, g.config.Color.GameOver, badge.Op(gtx.Ops))
+			call.Add(gtx.Ops)
+			return dims
+		}),
+	)
+}
+
+func (b *MiniBoard) geometry() (files, ranks int) {
+	files, ranks = 8, 8
+	if geometry, ok := b.game.(rules.Geometry); ok {
+		files, ranks = geometry.Files(), geometry.Ranks()
+	}
+	return files, ranks
+}
+
+func (b *MiniBoard) height(gtx layout.Context, width int) int {
+	if b.game == nil {
+		return gtx.Sp(miniHeaderSize)
+	}
+	files, ranks := b.geometry()
+	return gtx.Sp(miniHeaderSize) + util.Round(float32(width)/float32(files)*float32(ranks))
+}
+
+func (b *MiniBoard) invalidate() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.dirty = true
+	b.squaresImage = nil
+}
+
+func (b *MiniBoard) Players() (white, black string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.white, b.black
+}
+
+func (b *MiniBoard) SetPlayers(white, black string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.white, b.black = white, black
+	b.dirty = true
+}
+
+func (b *MiniBoard) Rules() rules.Rules {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.game
+}
+
+func (b *MiniBoard) SetRules(r rules.Rules) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.game = r
+	b.dirty = true
+}
+
+func (b *MiniBoard) Play(move rules.Move) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.game == nil {
+		return fmt.Errorf("can't play %s: %w", move, ErrNoGame)
+	}
+	if err := b.game.Move(move); err != nil {
+		return fmt.Errorf("can't play %s: %w", move, err)
+	}
+	return nil
+}
+
+func (b *MiniBoard) Resign(color rules.Color) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.game == nil {
+		return fmt.Errorf("can't resign: %w", ErrNoGame)
+	}
+	ender, ok := b.game.(rules.Ender)
+	if !ok {
+		return fmt.Errorf("can't resign: %w", errors.ErrUnsupported)
+	}
+	ender.Resign(color)
+	return nil
+}
+
+func (b *MiniBoard) AgreeDraw() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.game == nil {
+		return fmt.Errorf("can't agree to a draw: %w", ErrNoGame)
+	}
+	ender, ok := b.game.(rules.Ender)
+	if !ok {
+		return fmt.Errorf("can't agree to a draw: %w", errors.ErrUnsupported)
+	}
+	ender.AgreeDraw()
+	return nil
+}
+
+func (b *MiniBoard) Flipped() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.flipped
+}
+
+func (b *MiniBoard) SetFlipped(flipped bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.flipped = flipped
+	b.dirty = true
+}
@@ -0,0 +1,92 @@
+package chessboard
+
+import (
+	"errors"
+	"image"
+	"os"
+	"testing"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/widget/material"
+	"github.com/failosof/chessboard/rules"
+)
+
+func TestGridRecordsChangedBoards(t *testing.T) {
+	config := Config{Board: LichessBrown}
+	var err error
+	config.Piece, err = LoadPiecesFS(os.DirFS("assets/pieces/aquarium"), ShortNaming)
+	if err != nil {
+		t.Fatalf("can't load pieces: %v", err)
+	}
+
+	grid := NewGrid(material.NewTheme(), config)
+	boards := []*MiniBoard{
+		grid.Add("A", "B", rules.NewGame(rules.StartingPosition())),
+		grid.Add("C", "D", rules.NewGame(rules.StartingPosition())),
+		grid.Add("E", "F", rules.NewGame(rules.StartingPosition())),
+	}
+
+	layoutAt := func(width int) []op.CallOp {
+		gtx := layout.Context{
+			Ops:         new(op.Ops),
+			Constraints: layout.Exact(image.Pt(width, 2000)),
+			Now:         time.Now(),
+		}
+		grid.Layout(gtx)
+		drawings := make([]op.CallOp, len(boards))
+		for i, board := range boards {
+			drawings[i] = board.drawing
+		}
+		return drawings
+	}
+	recorded := func(step string, before, after []op.CallOp, want ...int) {
+		t.Helper()
+		for i := range boards {
+			changed := before[i] != after[i]
+			if changed != (len(want) == 0 || i == want[0]) {
+				t.Errorf("%s: board %d re-recorded %t", step, i, changed)
+			}
+		}
+	}
+
+	drawings := layoutAt(900)
+	if again := layoutAt(900); again[0] != drawings[0] || again[1] != drawings[1] || again[2] != drawings[2] {
+		t.Error("unchanged boards were re-recorded")
+	}
+
+	e2e4, _ := rules.ParseMove("e2e4")
+	if err := boards[1].Play(e2e4); err != nil {
+		t.Fatalf("Play: %v", err)
+	}
+	moved := layoutAt(900)
+	recorded("move", drawings, moved, 1)
+
+	if err := boards[2].Resign(rules.White); err != nil {
+		t.Fatalf("Resign: %v", err)
+	}
+	resigned := layoutAt(900)
+	recorded("resignation", moved, resigned, 2)
+	if boards[2].outcome != rules.BlackWon {
+		t.Errorf("resigned board shows %s", boards[2].outcome)
+	}
+
+	// a narrower grid makes every board smaller
+	recorded("width", resigned, layoutAt(600))
+}
+
+func TestMiniBoardWithoutGame(t *testing.T) {
+	board := NewGrid(material.NewTheme(), Config{}).Add("A", "B", nil)
+
+	e2e4, _ := rules.ParseMove("e2e4")
+	for name, err := range map[string]error{
+		"Play":      board.Play(e2e4),
+		"Resign":    board.Resign(rules.White),
+		"AgreeDraw": board.AgreeDraw(),
+	} {
+		if !errors.Is(err, ErrNoGame) {
+			t.Errorf("%s = %v, want %v", name, err, ErrNoGame)
+		}
+	}
+}
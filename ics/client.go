@@ -0,0 +1,199 @@
+// Package ics plays and observes games of an Internet Chess Server like FICS
+// or ICC over its telnet protocol, with board updates in style 12.
+package ics
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultAddr = "freechess.org:5000"
+
+const loginTimeout = 30 * time.Second
+
+var ErrLogin = errors.New("login failed")
+
+// Client is a session on the server. Dial logs in, after that Run reads
+// what the server sends while commands go out through Send.
+type Client struct {
+	// Username is the handle the server gave us, guests get a random one.
+	Username string
+
+	conn net.Conn
+	r    *bufio.Reader
+
+	mu sync.Mutex
+}
+
+// Dial connects and logs in, as a guest when the password is empty.
+func Dial(ctx context.Context, addr, username, password string) (*Client, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("can't connect to %s: %w", addr, err)
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(loginTimeout)
+	}
+	conn.SetDeadline(deadline)
+
+	c := NewClient(conn)
+	if err := c.Login(username, password); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return c, nil
+}
+
+// NewClient wraps a connection that hasn't logged in yet.
+func NewClient(conn net.Conn) *Client {
+	return &Client{conn: conn, r: bufio.NewReader(conn)}
+}
+
+// Login answers the login prompts and asks for style 12 board updates.
+func (c *Client) Login(username, password string) error {
+	if _, _, err := c.readUntil("login:"); err != nil {
+		return fmt.Errorf("can't log in: %w", err)
+	}
+	if err := c.Send(username); err != nil {
+		return err
+	}
+
+	text, prompt, err := c.readUntil("password:", "Press return", "login:", "% ")
+	if err != nil {
+		return fmt.Errorf("can't log in: %w", err)
+	}
+	switch prompt {
+	case "password:":
+		if password == "" {
+			return fmt.Errorf("can't log in as %s without a password: %w", username, ErrLogin)
+		}
+		if err := c.Send(password); err != nil {
+			return err
+		}
+	case "Press return":
+		// Press return to enter the server as "GuestXXXX":
+		rest, _, err := c.readUntil(`":`)
+		if err != nil {
+			return fmt.Errorf("can't log in: %w", err)
+		}
+		if _, name, ok := strings.Cut(rest, `"`); ok {
+			c.Username, _, _ = strings.Cut(name, `"`)
+		}
+		if err := c.Send(""); err != nil {
+			return err
+		}
+	case "login:":
+		return fmt.Errorf("can't log in as %s: %w", username, ErrLogin)
+	}
+
+	if prompt != "% " {
+		text, prompt, err = c.readUntil("login:", "% ")
+		if err != nil {
+			return fmt.Errorf("can't log in: %w", err)
+		}
+		if prompt == "login:" {
+			return fmt.Errorf("can't log in as %s: %w", username, ErrLogin)
+		}
+	}
+
+	// **** Starting FICS session as GuestXXXX(U) ****
+	if _, session, ok := strings.Cut(text, "session as "); ok {
+		if fields := strings.Fields(session); len(fields) > 0 {
+			name, _, _ := strings.Cut(fields[0], "(")
+			c.Username = name
+		}
+	}
+	if c.Username == "" {
+		c.Username = username
+	}
+
+	for _, command := range []string{"set style 12", "set bell 0", "iset nowrap 1"} {
+		if err := c.Send(command); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Send writes a command, like a move in coordinate notation or resign.
+func (c *Client) Send(command string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := io.WriteString(c.conn, command+"\n"); err != nil {
+		return fmt.Errorf("can't send %q: %w", command, err)
+	}
+	return nil
+}
+
+// Run reads lines until the connection is closed or the context is done,
+// sending what they parse into to the channel. Lines that don't parse are
+// logged and skipped.
+func (c *Client) Run(ctx context.Context, messages chan<- Message) error {
+	stop := context.AfterFunc(ctx, func() {
+		c.conn.SetReadDeadline(time.Now())
+	})
+	defer stop()
+
+	for {
+		line, err := c.r.ReadString('\n')
+		if line = stripPrompt(strings.TrimRight(line, "\r\n")); line != "" {
+			m, perr := ParseLine(line)
+			if perr != nil {
+				slog.Warn("can't parse ics line", "line", line, "err", perr)
+			} else {
+				select {
+				case messages <- m:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		}
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("can't read from server: %w", err)
+		}
+	}
+}
+
+func (c *Client) Close() error {
+	c.Send("quit")
+	return c.conn.Close()
+}
+
+// readUntil reads until the text ends with one of the prompts, which
+// don't end their lines.
+func (c *Client) readUntil(prompts ...string) (string, string, error) {
+	var text strings.Builder
+	for {
+		b, err := c.r.ReadByte()
+		if err != nil {
+			return text.String(), "", err
+		}
+		text.WriteByte(b)
+
+		s := text.String()
+		for _, prompt := range prompts {
+			if strings.HasSuffix(s, prompt) {
+				return s, prompt, nil
+			}
+		}
+	}
+}
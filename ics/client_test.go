@@ -0,0 +1,208 @@
+package ics
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gioui.org/widget/material"
+	"github.com/failosof/chessboard"
+	"github.com/failosof/chessboard/ics/icstest"
+	"github.com/failosof/chessboard/rules"
+)
+
+func newTestServer(t *testing.T) *icstest.Server {
+	t.Helper()
+
+	server, err := icstest.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	server.Passwords = map[string]string{"alice": "secret"}
+	t.Cleanup(func() { server.Close() })
+	return server
+}
+
+func dial(t *testing.T, server *icstest.Server, username, password string) *Client {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, err := Dial(ctx, server.Addr, username, password)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// nextCommand waits for the next command the server got.
+func nextCommand(t *testing.T, server *icstest.Server) string {
+	t.Helper()
+
+	select {
+	case command := <-server.Commands():
+		return command
+	case <-time.After(5 * time.Second):
+		t.Fatal("the server got no command")
+		return ""
+	}
+}
+
+func TestDial(t *testing.T) {
+	server := newTestServer(t)
+
+	tests := []struct {
+		username string
+		password string
+		want     string
+		err      error
+	}{
+		{"guest", "", icstest.GuestName, nil},
+		{"alice", "secret", "alice", nil},
+		{"alice", "wrong", "", ErrLogin},
+		{"alice", "", "", ErrLogin},
+	}
+
+	for _, test := range tests {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		client, err := Dial(ctx, server.Addr, test.username, test.password)
+		cancel()
+		if !errors.Is(err, test.err) {
+			t.Errorf("Dial as %s/%s: %v, want %v", test.username, test.password, err, test.err)
+		}
+		if err != nil {
+			continue
+		}
+
+		if client.Username != test.want {
+			t.Errorf("Dial as %s: username %s, want %s", test.username, client.Username, test.want)
+		}
+		var commands []string
+		for range 3 {
+			commands = append(commands, nextCommand(t, server))
+		}
+		if want := []string{"set style 12", "set bell 0", "iset nowrap 1"}; !slices.Equal(commands, want) {
+			t.Errorf("commands after login %q, want %q", commands, want)
+		}
+		client.Close()
+		if got := nextCommand(t, server); got != "quit" {
+			t.Errorf("Close sent %q, want quit", got)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	server := newTestServer(t)
+	client := dial(t, server, "guest", "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	messages := make(chan Message)
+	errs := make(chan error, 1)
+	go func() { errs <- client.Run(ctx, messages) }()
+
+	if err := server.Send("{Game 42 (GuestTEST vs. Bob) Creating unrated blitz match.}", "", startLine, "<12> broken"); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if m := <-messages; m != (GameStart{Game: 42, White: "GuestTEST", Black: "Bob", Info: "Creating unrated blitz match."}) {
+		t.Errorf("got %#v, want the game start", m)
+	}
+	if s, ok := (<-messages).(Style12); !ok || s.Game != 42 {
+		t.Errorf("got %#v, want the board of game 42", s)
+	}
+
+	// the broken update is skipped
+	if err := server.Send("Bob says: hi"); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if m := <-messages; m != Text("Bob says: hi") {
+		t.Errorf("got %#v, want the chat line", m)
+	}
+
+	cancel()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run error %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestGame(t *testing.T) {
+	server := newTestServer(t)
+	client := dial(t, server, "guest", "")
+	for range 3 {
+		nextCommand(t, server)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	messages := make(chan Message)
+	go client.Run(ctx, messages)
+
+	board := chessboard.NewWidget(material.NewTheme(), chessboard.Config{})
+	game := NewGame(client, 0, board, nil)
+	receive := func(lines ...string) {
+		t.Helper()
+		if err := server.Send(lines...); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+		for range lines {
+			game.HandleMessage(<-messages)
+		}
+	}
+	placement := func() string {
+		placement, _, _ := strings.Cut(board.FEN(), " ")
+		return placement
+	}
+
+	receive("{Game 42 (GuestTEST vs. Bob) Creating unrated blitz match.}", startLine)
+	if game.Number != 42 || game.Color != rules.White || board.Player() != rules.White {
+		t.Fatalf("following game %d as %s, want game 42 as white", game.Number, game.Color)
+	}
+
+	move := rules.Move{From: rules.NewSquare(4, 1), To: rules.NewSquare(4, 3)}
+	if err := board.Rules().Move(move); err != nil {
+		t.Fatalf("can't play e2e4: %v", err)
+	}
+	game.HandleEvent(chessboard.Event{Type: chessboard.MoveEvent, Move: move})
+	if got := nextCommand(t, server); got != "e2e4" {
+		t.Errorf("sent %q, want e2e4", got)
+	}
+
+	receive(e4Line, e5Line)
+	if got, want := placement(), "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR"; got != want {
+		t.Errorf("board %s, want %s", got, want)
+	}
+
+	receive("Illegal move (e2e5).")
+	if got := nextCommand(t, server); got != "refresh" {
+		t.Errorf("sent %q after an illegal move, want refresh", got)
+	}
+
+	receive("{Game 42 (GuestTEST vs. Bob) Bob resigns} 1-0")
+	if outcome, method := board.Outcome(); outcome != rules.WhiteWon || method != rules.Resignation {
+		t.Errorf("outcome %s by %s, want 1-0 by resignation", outcome, method)
+	}
+}
+
+func TestServerDropsUnreadCommands(t *testing.T) {
+	server := newTestServer(t)
+	client := dial(t, server, "guest", "")
+
+	for i := range 100 {
+		if err := client.Send("say " + strings.Repeat("x", i)); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	client.Close()
+
+	// the server reads on past the full channel and sees the client leave
+	deadline := time.Now().Add(5 * time.Second)
+	for server.Clients() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the server blocked on the commands nobody read")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,251 @@
+package ics
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/failosof/chessboard"
+	"github.com/failosof/chessboard/rules"
+)
+
+// Game plays or observes a game of the server on a widget. The app hands it
+// the messages of Client.Run and the board events, moves made on the board
+// are sent as commands and the board and the clock follow the board updates.
+type Game struct {
+	// Number is the game on the server, zero follows the next game we play.
+	Number int
+	// Color is the side we play, NoColor when observing.
+	Color rules.Color
+
+	// OnUpdate is called after a message changed the board or the clock, to
+	// have the window redrawn.
+	OnUpdate func()
+
+	client *Client
+	board  *chessboard.Widget
+	clock  *chessboard.Clock
+
+	server   *rules.Game // the game as the server has it, for translating moves
+	relation Relation
+	started  bool
+	finished bool
+
+	mu sync.Mutex
+}
+
+// NewGame follows a game on the board, the clock may be nil. Observing needs
+// the observe command sent first, the board isn't locked then but moves made
+// on it aren't sent.
+func NewGame(client *Client, number int, board *chessboard.Widget, clock *chessboard.Clock) *Game {
+	return &Game{Number: number, client: client, board: board, clock: clock}
+}
+
+// HandleMessage applies a message of the game and tells whether it was one.
+func (g *Game) HandleMessage(m Message) bool {
+	g.mu.Lock()
+	var err error
+	switch m := m.(type) {
+	case Style12:
+		if g.Number == 0 && m.Relation.Playing() {
+			g.Number = m.Game
+		}
+		if m.Game != g.Number {
+			g.mu.Unlock()
+			return false
+		}
+		err = g.sync(m)
+	case GameEnd:
+		if m.Game != g.Number {
+			g.mu.Unlock()
+			return false
+		}
+		g.end(m)
+	case Text:
+		// the server doesn't send the board back after an illegal move
+		if !g.relation.Playing() || !(strings.HasPrefix(string(m), "Illegal move") ||
+			strings.HasPrefix(string(m), "It is not your move")) {
+			g.mu.Unlock()
+			return false
+		}
+		err = g.client.Send("refresh")
+	default:
+		g.mu.Unlock()
+		return false
+	}
+	g.mu.Unlock()
+
+	if err != nil {
+		slog.Error("can't follow ics game", "game", g.Number, "err", err)
+	}
+	if g.OnUpdate != nil {
+		g.OnUpdate()
+	}
+	return true
+}
+
+// HandleEvent sends the moves made on the board of a game we play.
+func (g *Game) HandleEvent(e chessboard.Event) {
+	if e.Type != chessboard.MoveEvent {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.server == nil || !g.relation.Playing() || g.finished {
+		return
+	}
+
+	command := MoveCommand(g.server, e.Move)
+	if err := g.server.Move(e.Move); err != nil {
+		slog.Error("can't send ics move", "game", g.Number, "move", e.Move, "err", err)
+		return
+	}
+	if g.clock != nil && g.started {
+		g.clock.Press(g.Color, time.Now())
+	}
+	if err := g.client.Send(command); err != nil {
+		slog.Error("can't send ics move", "game", g.Number, "move", command, "err", err)
+	}
+}
+
+func (g *Game) Resign() error {
+	return g.client.Send("resign")
+}
+
+// OfferDraw offers a draw, or accepts the one the opponent offered.
+func (g *Game) OfferDraw() error {
+	return g.client.Send("draw")
+}
+
+func (g *Game) Abort() error {
+	return g.client.Send("abort")
+}
+
+// MoveCommand writes a move the way the server takes it, in coordinate
+// notation with castling as o-o and the promotion piece after an equals sign.
+// It needs the position before the move.
+func MoveCommand(r rules.Rules, move rules.Move) string {
+	if castling := castlingNotation(r, move); castling != "" {
+		return castling
+	}
+	command := move.From.String() + move.To.String()
+	if move.Promo != rules.NoPieceType {
+		command += "=" + strings.ToUpper(move.Promo.String())
+	}
+	return command
+}
+
+// sync catches up with a board update. The move of the update is played on
+// the board, so it's animated, when the board had the position before it,
+// otherwise the board is set to the position of the update.
+func (g *Game) sync(s Style12) error {
+	// the relation swaps with every move, the side only when a game starts
+	if g.server == nil || s.Relation.Playing() != g.relation.Playing() {
+		switch s.Relation {
+		case MyMove:
+			g.Color = s.Turn
+		case OpponentsMove:
+			g.Color = s.Turn.Other()
+		default:
+			g.Color = rules.NoColor
+		}
+		g.board.SetFlipped(s.Flipped)
+		g.board.SetPlayer(g.Color)
+	}
+	g.relation = s.Relation
+
+	switch {
+	case g.server != nil && placement(g.server) == s.Placement() && g.server.Turn() == s.Turn:
+		// our own move coming back
+	case g.server != nil && g.played(s):
+	default:
+		if err := g.reset(s); err != nil {
+			return err
+		}
+	}
+
+	g.started = g.started || s.VerboseMove != "none"
+	g.syncClock(s)
+	return nil
+}
+
+func (g *Game) played(s Style12) bool {
+	move, ok := s.Move(g.server)
+	if !ok || g.server.Move(move) != nil {
+		return false
+	}
+	if placement(g.server) != s.Placement() {
+		return false
+	}
+	return g.board.Play(move) == nil
+}
+
+func (g *Game) reset(s Style12) error {
+	server, err := rules.NewGameFromFEN(s.FEN())
+	if err != nil {
+		return fmt.Errorf("can't set up %s: %w", s.FEN(), err)
+	}
+	board, err := rules.NewGameFromFEN(s.FEN())
+	if err != nil {
+		return fmt.Errorf("can't set up %s: %w", s.FEN(), err)
+	}
+
+	g.server = server
+	g.finished = false
+	g.board.SetRules(board)
+	return nil
+}
+
+func (g *Game) syncClock(s Style12) {
+	if g.clock == nil {
+		return
+	}
+
+	now := time.Now()
+	switch {
+	case !s.Ticking || !g.started || g.finished:
+		g.clock.Stop(now)
+	case g.clock.Running() != s.Turn:
+		g.clock.Press(s.Turn.Other(), now)
+	}
+	g.clock.Set(rules.White, s.WhiteTime, now)
+	g.clock.Set(rules.Black, s.BlackTime, now)
+}
+
+// end shows how the game ended when the board can't tell by itself. Games
+// that were aborted or adjourned have no result and just stop.
+func (g *Game) end(e GameEnd) {
+	if g.finished {
+		return
+	}
+	g.finished = true
+	if g.clock != nil {
+		g.clock.Stop(time.Now())
+	}
+	if outcome, _ := g.board.Outcome(); outcome != rules.NoOutcome {
+		return
+	}
+
+	var err error
+	switch loser := e.Loser(); {
+	case e.Result == rules.Draw:
+		err = g.board.AgreeDraw()
+	case loser == rules.NoColor:
+	case strings.Contains(e.Reason, "on time"):
+		g.board.Timeout(loser)
+	default:
+		err = g.board.Resign(loser)
+	}
+	if err != nil && !errors.Is(err, errors.ErrUnsupported) {
+		slog.Error("can't end ics game", "game", g.Number, "reason", e.Reason, "err", err)
+	}
+}
+
+func placement(g *rules.Game) string {
+	placement, _, _ := strings.Cut(g.FEN(), " ")
+	return placement
+}
@@ -0,0 +1,160 @@
+// Package icstest runs a fake Internet Chess Server on the loopback, to try
+// out clients without connecting to a real one.
+package icstest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const GuestName = "GuestTEST"
+
+// Server logs in anyone and hands their commands to Commands. What it sends
+// is up to the caller, like a transcript of a game.
+type Server struct {
+	Addr string
+	// Passwords of the registered names, others log in as guests.
+	Passwords map[string]string
+
+	listener net.Listener
+	commands chan string
+	conns    []net.Conn
+
+	mu sync.Mutex
+}
+
+// NewServer starts listening on a free port of the loopback.
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("can't listen: %w", err)
+	}
+
+	s := &Server{
+		Addr:     listener.Addr().String(),
+		listener: listener,
+		commands: make(chan string, 64),
+	}
+	go s.accept()
+	return s, nil
+}
+
+// Commands come in as the clients send them, after their login. The channel
+// buffers 64 of them and drops the ones sent while it's full, so a test that
+// doesn't care about them needn't drain it.
+func (s *Server) Commands() <-chan string {
+	return s.commands
+}
+
+// Send writes lines to every logged in client, with the line endings and the
+// prompt of the server.
+func (s *Server) Send(lines ...string) error {
+	s.mu.Lock()
+	conns := slices.Clone(s.conns)
+	s.mu.Unlock()
+
+	text := strings.Join(lines, "\n\r") + "\n\rfics% "
+	for _, conn := range conns {
+		if _, err := io.WriteString(conn, text); err != nil {
+			return fmt.Errorf("can't send to %s: %w", conn.RemoteAddr(), err)
+		}
+	}
+	return nil
+}
+
+// Clients tells how many clients are logged in.
+func (s *Server) Clients() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+	return err
+}
+
+func (s *Server) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.serve(conn)
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	readLine := func() (string, error) {
+		line, err := r.ReadString('\n')
+		return strings.TrimRight(line, "\r\n"), err
+	}
+
+	name := ""
+	for name == "" {
+		io.WriteString(conn, "login: ")
+		username, err := readLine()
+		if err != nil {
+			return
+		}
+
+		password, registered := s.Passwords[username]
+		if !registered {
+			fmt.Fprintf(conn, "\"%s\" is not a registered name.\n\rPress return to enter the server as \"%s\":", username, GuestName)
+			if _, err := readLine(); err != nil {
+				return
+			}
+			name = GuestName + "(U)"
+			break
+		}
+
+		fmt.Fprintf(conn, "\"%s\" is a registered name.\n\rpassword: ", username)
+		got, err := readLine()
+		if err != nil {
+			return
+		}
+		if got != password {
+			io.WriteString(conn, "\n\r**** Invalid password! ****\n\r\n\r")
+			continue
+		}
+		name = username
+	}
+
+	s.mu.Lock()
+	s.conns = append(s.conns, conn)
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.conns = slices.DeleteFunc(s.conns, func(c net.Conn) bool { return c == conn })
+		s.mu.Unlock()
+	}()
+
+	fmt.Fprintf(conn, "\n\r**** Starting FICS session as %s ****\n\r\n\rfics%% ", name)
+
+	for {
+		command, err := readLine()
+		if err != nil {
+			return
+		}
+		select {
+		case s.commands <- command:
+		default:
+		}
+		if command == "quit" {
+			return
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package ics
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/failosof/chessboard/rules"
+)
+
+// Message is what a line from the server is parsed into: Style12, GameStart,
+// GameEnd or Text.
+type Message any
+
+// GameStart is sent when a game we play or observe is created, or continued
+// after an adjournment.
+type GameStart struct {
+	Game  int
+	White string
+	Black string
+	Info  string // like Creating rated blitz match.
+}
+
+type GameEnd struct {
+	Game   int
+	White  string
+	Black  string
+	Reason string // like Einstein resigns
+	Result rules.Outcome
+}
+
+// Loser is the side the result is against, NoColor for draws and games that
+// ended without a result.
+func (e GameEnd) Loser() rules.Color {
+	switch e.Result {
+	case rules.WhiteWon:
+		return rules.Black
+	case rules.BlackWon:
+		return rules.White
+	default:
+		return rules.NoColor
+	}
+}
+
+// Text is any other line.
+type Text string
+
+var gameLine = regexp.MustCompile(`^\{Game (\d+) \((\S+) vs\. (\S+)\) (.*)\}\s*(1-0|0-1|1/2-1/2|\*)?$`)
+
+// ParseLine parses a line without its line ending, after the prompts the
+// server puts in front of it are removed. Malformed board updates come back
+// as an error.
+func ParseLine(line string) (Message, error) {
+	line = stripPrompt(strings.TrimRight(line, "\r\n"))
+
+	if strings.HasPrefix(line, "<12> ") {
+		s, err := ParseStyle12(line)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+
+	if m := gameLine.FindStringSubmatch(line); m != nil {
+		game, _ := strconv.Atoi(m[1])
+		if m[5] != "" {
+			return GameEnd{Game: game, White: m[2], Black: m[3], Reason: m[4], Result: rules.Outcome(m[5])}, nil
+		}
+		if strings.HasPrefix(m[4], "Creating ") || strings.HasPrefix(m[4], "Continuing ") {
+			return GameStart{Game: game, White: m[2], Black: m[3], Info: m[4]}, nil
+		}
+	}
+	return Text(line), nil
+}
+
+var prompts = []string{"fics% ", "aics% ", "ics% "}
+
+func stripPrompt(line string) string {
+	for trimmed := true; trimmed; {
+		trimmed = false
+		line = strings.TrimLeft(line, "\r\n")
+		for _, prompt := range prompts {
+			if strings.HasPrefix(line, prompt) {
+				line, trimmed = line[len(prompt):], true
+			}
+		}
+	}
+	return line
+}
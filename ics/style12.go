@@ -0,0 +1,249 @@
+package ics
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/failosof/chessboard/rules"
+)
+
+// Relation is how we are related to the game of a board update.
+type Relation int8
+
+const (
+	IsolatedPosition  Relation = -3
+	ObservingExamined Relation = -2
+	OpponentsMove     Relation = -1
+	Observing         Relation = 0
+	MyMove            Relation = 1
+	Examining         Relation = 2
+)
+
+func (r Relation) Playing() bool {
+	return r == MyMove || r == OpponentsMove
+}
+
+// Style12 is a board update. Ranks are from the 8th to the 1st with '-' for
+// empty squares, times are what the players had left after the last move.
+type Style12 struct {
+	Ranks          [8]string
+	Turn           rules.Color
+	DoublePushFile int // -1 when the last move wasn't a double pawn push
+	Castling       [4]bool
+	Irreversible   int
+	Game           int
+	White          string
+	Black          string
+	Relation       Relation
+	Initial        time.Duration
+	Increment      time.Duration
+	WhiteMaterial  int
+	BlackMaterial  int
+	WhiteTime      time.Duration
+	BlackTime      time.Duration
+	MoveNumber     int
+	VerboseMove    string // like P/e2-e4, o-o or none
+	MoveTime       string
+	PrettyMove     string // like e4 or none
+	Flipped        bool
+	// Ticking is whether the clock runs, servers that don't send it have
+	// it true.
+	Ticking bool
+}
+
+const (
+	WhiteShort = iota
+	WhiteLong
+	BlackShort
+	BlackLong
+)
+
+// ParseStyle12 reads a line starting with <12>.
+func ParseStyle12(line string) (Style12, error) {
+	var s Style12
+	fields := strings.Fields(line)
+	if len(fields) < 31 || fields[0] != "<12>" {
+		return s, fmt.Errorf("invalid style 12 line %q", line)
+	}
+	fields = fields[1:]
+
+	for i := range s.Ranks {
+		if len(fields[i]) != 8 {
+			return s, fmt.Errorf("invalid style 12 rank %q", fields[i])
+		}
+		s.Ranks[i] = fields[i]
+	}
+
+	switch fields[8] {
+	case "W":
+		s.Turn = rules.White
+	case "B":
+		s.Turn = rules.Black
+	default:
+		return s, fmt.Errorf("invalid style 12 side to move %q", fields[8])
+	}
+
+	numbers := make([]int, 0, 16)
+	for _, i := range []int{9, 10, 11, 12, 13, 14, 15, 18, 19, 20, 21, 22, 23, 24, 25, 29} {
+		n, err := strconv.Atoi(fields[i])
+		if err != nil {
+			return s, fmt.Errorf("invalid style 12 field %d %q", i+1, fields[i])
+		}
+		numbers = append(numbers, n)
+	}
+
+	s.DoublePushFile = numbers[0]
+	for i := range s.Castling {
+		s.Castling[i] = numbers[1+i] == 1
+	}
+	s.Irreversible = numbers[5]
+	s.Game = numbers[6]
+	s.White, s.Black = fields[16], fields[17]
+	s.Relation = Relation(numbers[7])
+	s.Initial = time.Duration(numbers[8]) * time.Minute
+	s.Increment = time.Duration(numbers[9]) * time.Second
+	s.WhiteMaterial, s.BlackMaterial = numbers[10], numbers[11]
+	s.WhiteTime = time.Duration(numbers[12]) * time.Second
+	s.BlackTime = time.Duration(numbers[13]) * time.Second
+	s.MoveNumber = numbers[14]
+	s.VerboseMove = fields[26]
+	s.MoveTime = strings.Trim(fields[27], "()")
+	s.PrettyMove = fields[28]
+	s.Flipped = numbers[15] == 1
+	s.Ticking = len(fields) <= 30 || fields[30] != "0"
+	return s, nil
+}
+
+// String writes the update as the server sends it.
+func (s Style12) String() string {
+	return fmt.Sprintf("<12> %s %s %d %d %d %d %d %d %d %s %s %d %d %d %d %d %d %d %d %s (%s) %s %d %d",
+		strings.Join(s.Ranks[:], " "), strings.ToUpper(s.Turn.String()), s.DoublePushFile,
+		boolInt(s.Castling[WhiteShort]), boolInt(s.Castling[WhiteLong]),
+		boolInt(s.Castling[BlackShort]), boolInt(s.Castling[BlackLong]),
+		s.Irreversible, s.Game, s.White, s.Black, s.Relation,
+		int(s.Initial/time.Minute), int(s.Increment/time.Second), s.WhiteMaterial, s.BlackMaterial,
+		int(s.WhiteTime/time.Second), int(s.BlackTime/time.Second), s.MoveNumber,
+		s.VerboseMove, s.MoveTime, s.PrettyMove, boolInt(s.Flipped), boolInt(s.Ticking))
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Placement is the board part of the FEN of the update.
+func (s Style12) Placement() string {
+	var sb strings.Builder
+	for i, rank := range s.Ranks {
+		empty := 0
+		for _, c := range rank {
+			if c == '-' {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				sb.WriteString(strconv.Itoa(empty))
+				empty = 0
+			}
+			sb.WriteRune(c)
+		}
+		if empty > 0 {
+			sb.WriteString(strconv.Itoa(empty))
+		}
+		if i < len(s.Ranks)-1 {
+			sb.WriteByte('/')
+		}
+	}
+	return sb.String()
+}
+
+func (s Style12) FEN() string {
+	castling := ""
+	for i, letter := range "KQkq" {
+		if s.Castling[i] {
+			castling += string(letter)
+		}
+	}
+	if castling == "" {
+		castling = "-"
+	}
+
+	enPassant := "-"
+	if 0 <= s.DoublePushFile && s.DoublePushFile < 8 {
+		rank := 6
+		if s.Turn == rules.Black {
+			rank = 3
+		}
+		enPassant = fmt.Sprintf("%c%d", 'a'+s.DoublePushFile, rank)
+	}
+
+	return fmt.Sprintf("%s %s %s %s %d %d", s.Placement(), s.Turn, castling, enPassant, s.Irreversible, s.MoveNumber)
+}
+
+// Move finds the last move in the position before it, where castling is
+// written as o-o and promotions as P/e7-e8=Q.
+func (s Style12) Move(before rules.Rules) (rules.Move, bool) {
+	verbose := s.VerboseMove
+	if verbose == "none" {
+		return rules.NoMove, false
+	}
+
+	mover := s.Turn.Other()
+	for _, from := range kingSquares(before, mover) {
+		for _, move := range before.LegalMoves(from) {
+			if castling := castlingNotation(before, move); castling != "" && castling == verbose {
+				return move, true
+			}
+		}
+	}
+
+	_, squares, ok := strings.Cut(verbose, "/")
+	if !ok || len(squares) < 5 {
+		return rules.NoMove, false
+	}
+	squares, promo, _ := strings.Cut(squares, "=")
+	from, err1 := rules.ParseSquare(squares[:2])
+	to, err2 := rules.ParseSquare(squares[3:5])
+	if err1 != nil || err2 != nil {
+		return rules.NoMove, false
+	}
+
+	want := rules.Move{From: from, To: to}
+	if promo != "" {
+		want.Promo = rules.PieceTypeFromLetter(promo[0])
+	}
+	for _, move := range before.LegalMoves(from) {
+		if move == want {
+			return move, true
+		}
+	}
+	return rules.NoMove, false
+}
+
+func kingSquares(r rules.Rules, color rules.Color) []rules.Square {
+	king := rules.NewPiece(rules.King, color)
+	var squares []rules.Square
+	for rank := 0; rank < 8; rank++ {
+		for file := 0; file < 8; file++ {
+			if square := rules.NewSquare(file, rank); r.Piece(square) == king {
+				squares = append(squares, square)
+			}
+		}
+	}
+	return squares
+}
+
+func castlingNotation(r rules.Rules, move rules.Move) string {
+	king := r.Piece(move.From)
+	if king.Type() != rules.King || r.Piece(move.To) != rules.NewPiece(rules.Rook, king.Color()) {
+		return ""
+	}
+	if move.To.File() > move.From.File() {
+		return "o-o"
+	}
+	return "o-o-o"
+}
@@ -0,0 +1,170 @@
+package ics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/failosof/chessboard/rules"
+)
+
+// Board updates as FICS sends them to the white player of game 42.
+const (
+	startLine     = "<12> rnbqkbnr pppppppp -------- -------- -------- -------- PPPPPPPP RNBQKBNR W -1 1 1 1 1 0 42 GuestTEST Bob 1 3 0 39 39 180 180 1 none (0:00.000) none 0 0 0"
+	e4Line        = "<12> rnbqkbnr pppppppp -------- -------- ----P--- -------- PPPP-PPP RNBQKBNR B 4 1 1 1 1 0 42 GuestTEST Bob -1 3 0 39 39 180 180 1 P/e2-e4 (0:00.000) e4 0 1 0"
+	e5Line        = "<12> rnbqkbnr pppp-ppp -------- ----p--- ----P--- -------- PPPP-PPP RNBQKBNR W 4 1 1 1 1 0 42 GuestTEST Bob 1 3 0 39 39 180 178 2 P/e7-e5 (0:02.312) e5 0 1 261"
+	castlingLine  = "<12> r-bqkbnr pppp-ppp --n----- -B--p--- ----P--- -----N-- PPPP-PPP RNBQ-RK- B -1 0 0 1 1 4 42 GuestTEST Bob -1 3 0 39 39 171 176 3 o-o (0:01.050) O-O 0 1 0"
+	promotionLine = "<12> ----Q--- -------- -------- -------- -------- -------- k------- ----K--- B -1 0 0 0 0 0 42 GuestTEST Bob -1 3 0 9 0 12 30 50 P/e7-e8=Q (0:00.500) e8=Q 1 1 0"
+	// servers that predate the ticking field leave it out
+	oldLine = "<12> rnbqkbnr pppppppp -------- -------- -------- -------- PPPPPPPP RNBQKBNR W -1 1 1 1 1 0 7 Alice Bob 0 2 12 39 39 120 120 1 none (0:00) none 0"
+)
+
+func TestParseStyle12(t *testing.T) {
+	s, err := ParseStyle12(e5Line)
+	if err != nil {
+		t.Fatalf("ParseStyle12: %v", err)
+	}
+
+	want := Style12{
+		Ranks:          [8]string{"rnbqkbnr", "pppp-ppp", "--------", "----p---", "----P---", "--------", "PPPP-PPP", "RNBQKBNR"},
+		Turn:           rules.White,
+		DoublePushFile: 4,
+		Castling:       [4]bool{true, true, true, true},
+		Game:           42,
+		White:          "GuestTEST",
+		Black:          "Bob",
+		Relation:       MyMove,
+		Initial:        3 * time.Minute,
+		WhiteMaterial:  39,
+		BlackMaterial:  39,
+		WhiteTime:      180 * time.Second,
+		BlackTime:      178 * time.Second,
+		MoveNumber:     2,
+		VerboseMove:    "P/e7-e5",
+		MoveTime:       "0:02.312",
+		PrettyMove:     "e5",
+		Ticking:        true,
+	}
+	if s != want {
+		t.Errorf("got %+v, want %+v", s, want)
+	}
+	if got, want := s.FEN(), "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2"; got != want {
+		t.Errorf("FEN() = %s, want %s", got, want)
+	}
+
+	again, err := ParseStyle12(s.String())
+	if err != nil || again != s {
+		t.Errorf("String() doesn't parse back: %+v, %v", again, err)
+	}
+}
+
+func TestParseStyle12Fields(t *testing.T) {
+	tests := []struct {
+		line     string
+		relation Relation
+		fen      string
+		flipped  bool
+		ticking  bool
+	}{
+		{startLine, MyMove, rules.Standard.StartingFEN(), false, false},
+		{e4Line, OpponentsMove, "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", false, true},
+		{castlingLine, OpponentsMove, "r1bqkbnr/pppp1ppp/2n5/1B2p3/4P3/5N2/PPPP1PPP/RNBQ1RK1 b kq - 4 3", false, true},
+		{promotionLine, OpponentsMove, "4Q3/8/8/8/8/8/k7/4K3 b - - 0 50", true, true},
+		{oldLine, Observing, rules.Standard.StartingFEN(), false, true},
+	}
+
+	for _, test := range tests {
+		s, err := ParseStyle12(test.line)
+		if err != nil {
+			t.Errorf("ParseStyle12(%q): %v", test.line, err)
+			continue
+		}
+		if s.Relation != test.relation || s.FEN() != test.fen || s.Flipped != test.flipped || s.Ticking != test.ticking {
+			t.Errorf("ParseStyle12(%q) = relation %d, %s, flipped %t, ticking %t, want relation %d, %s, flipped %t, ticking %t",
+				test.line, s.Relation, s.FEN(), s.Flipped, s.Ticking, test.relation, test.fen, test.flipped, test.ticking)
+		}
+	}
+}
+
+func TestParseStyle12Errors(t *testing.T) {
+	for _, line := range []string{
+		"<12> rnbqkbnr pppppppp W -1",
+		"<10> rnbqkbnr pppppppp -------- -------- -------- -------- PPPPPPPP RNBQKBNR W -1 1 1 1 1 0 42 A B 1 3 0 39 39 180 180 1 none (0:00.000) none 0 0 0",
+		"<12> rnbqkbnr pppppppp -------- -------- -------- -------- PPPPPPPP RNBQKBN W -1 1 1 1 1 0 42 A B 1 3 0 39 39 180 180 1 none (0:00.000) none 0 0 0",
+		"<12> rnbqkbnr pppppppp -------- -------- -------- -------- PPPPPPPP RNBQKBNR X -1 1 1 1 1 0 42 A B 1 3 0 39 39 180 180 1 none (0:00.000) none 0 0 0",
+		"<12> rnbqkbnr pppppppp -------- -------- -------- -------- PPPPPPPP RNBQKBNR W -1 1 1 1 1 0 42 A B 1 3 0 39 39 1:80 180 1 none (0:00.000) none 0 0 0",
+	} {
+		if _, err := ParseStyle12(line); err == nil {
+			t.Errorf("ParseStyle12(%q) didn't fail", line)
+		}
+	}
+}
+
+func TestStyle12Move(t *testing.T) {
+	tests := []struct {
+		before  string
+		line    string
+		want    rules.Move
+		command string
+	}{
+		{rules.Standard.StartingFEN(), e4Line, rules.Move{From: rules.NewSquare(4, 1), To: rules.NewSquare(4, 3)}, "e2e4"},
+		// castling is king takes rook
+		{"r1bqkbnr/pppp1ppp/2n5/1B2p3/4P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 3 3", castlingLine,
+			rules.Move{From: rules.NewSquare(4, 0), To: rules.NewSquare(7, 0)}, "o-o"},
+		{"8/4P3/8/8/8/8/k7/4K3 w - - 0 50", promotionLine,
+			rules.Move{From: rules.NewSquare(4, 6), To: rules.NewSquare(4, 7), Promo: rules.Queen}, "e7e8=Q"},
+	}
+
+	for _, test := range tests {
+		s, err := ParseStyle12(test.line)
+		if err != nil {
+			t.Fatalf("ParseStyle12: %v", err)
+		}
+		before, err := rules.NewGameFromFEN(test.before)
+		if err != nil {
+			t.Fatalf("NewGameFromFEN: %v", err)
+		}
+
+		move, ok := s.Move(before)
+		if !ok || move != test.want {
+			t.Errorf("%s: Move() = %s, %t, want %s", s.VerboseMove, move, ok, test.want)
+		}
+		if got := MoveCommand(before, move); got != test.command {
+			t.Errorf("MoveCommand(%s) = %s, want %s", move, got, test.command)
+		}
+	}
+
+	s, _ := ParseStyle12(startLine)
+	if move, ok := s.Move(rules.NewGame(rules.StartingPosition())); ok {
+		t.Errorf("found %s in an update without a move", move)
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want Message
+	}{
+		{"{Game 42 (GuestTEST vs. Bob) Creating unrated blitz match.}",
+			GameStart{Game: 42, White: "GuestTEST", Black: "Bob", Info: "Creating unrated blitz match."}},
+		{"fics% {Game 42 (GuestTEST vs. Bob) Bob resigns} 1-0",
+			GameEnd{Game: 42, White: "GuestTEST", Black: "Bob", Reason: "Bob resigns", Result: rules.WhiteWon}},
+		{"{Game 42 (GuestTEST vs. Bob) Game aborted on move 1} *",
+			GameEnd{Game: 42, White: "GuestTEST", Black: "Bob", Reason: "Game aborted on move 1", Result: rules.NoOutcome}},
+		{"{Game 42 (GuestTEST vs. Bob) Bob forfeits on time} 1-0\r",
+			GameEnd{Game: 42, White: "GuestTEST", Black: "Bob", Reason: "Bob forfeits on time", Result: rules.WhiteWon}},
+		{"Illegal move (e2e5).", Text("Illegal move (e2e5).")},
+	}
+
+	for _, test := range tests {
+		got, err := ParseLine(test.line)
+		if err != nil || got != test.want {
+			t.Errorf("ParseLine(%q) = %#v, %v, want %#v", test.line, got, err, test.want)
+		}
+	}
+
+	if m, err := ParseLine("fics% " + e4Line); err != nil {
+		t.Errorf("ParseLine with a prompt: %v", err)
+	} else if s, ok := m.(Style12); !ok || s.PrettyMove != "e4" {
+		t.Errorf("ParseLine with a prompt = %#v, want the update", m)
+	}
+}
@@ -0,0 +1,262 @@
+package lan
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/failosof/chessboard"
+	"github.com/failosof/chessboard/rules"
+	"golang.org/x/net/websocket"
+)
+
+const origin = "http://localhost/"
+
+// Client plays a seat of a relay server on a widget, or watches the game
+// when the seat is NoColor or taken. The app hands it the board events,
+// moves made on the board are sent and the moves and annotations of the
+// others are shown. When the connection drops it reconnects and the board is
+// set to the game the server has.
+type Client struct {
+	URL string
+	// Seat is the seat asked for, Seated tells which one the server gave.
+	Seat  rules.Color
+	Retry time.Duration
+	// Keepalive is the server's, a server not heard from for three times as
+	// long is given up on and connected to again. Zero waits forever.
+	Keepalive time.Duration
+
+	// OnUpdate is called from the connection's goroutine after the board
+	// changed, to have the window redrawn.
+	OnUpdate func()
+
+	board *chessboard.Widget
+	conn  *websocket.Conn
+	seat  rules.Color
+	start string
+	moves []rules.Move
+	game  *rules.Game // the game as the server has it, to check moves
+
+	mu sync.Mutex
+}
+
+// NewClient connects to a server at a ws:// URL once Run is called.
+func NewClient(url string, seat rules.Color, board *chessboard.Widget) *Client {
+	return &Client{URL: url, Seat: seat, Retry: time.Second, Keepalive: DefaultKeepalive, board: board}
+}
+
+// Run stays connected until the context is done.
+func (c *Client) Run(ctx context.Context) error {
+	for {
+		err := c.session(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		slog.Warn("lost lan server", "url", c.URL, "err", err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.Retry):
+		}
+	}
+}
+
+func (c *Client) Seated() rules.Color {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.seat
+}
+
+// Connected tells whether the client has the state of the server.
+func (c *Client) Connected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn != nil && c.game != nil
+}
+
+// HandleEvent sends the moves and the annotations made on the board. Moves
+// that can't be sent, like a spectator's, are taken back.
+func (c *Client) HandleEvent(e chessboard.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch e.Type {
+	case chessboard.MoveEvent:
+		if c.game == nil {
+			break
+		}
+		if c.conn == nil || c.seat == rules.NoColor || c.game.Move(e.Move) != nil {
+			if err := c.rebuild(); err != nil {
+				slog.Error("can't take back lan move", "url", c.URL, "err", err)
+			}
+			break
+		}
+		c.moves = append(c.moves, e.Move)
+		c.send(Message{Type: MoveMessage, Move: e.Move.String(), Ply: len(c.moves) - 1})
+	case chessboard.AnnotateEvent:
+		if c.conn != nil {
+			c.send(Message{Type: AnnotateMessage, Annotations: FromAnnotations(c.board.Annotations())})
+		}
+	}
+}
+
+func (c *Client) session(ctx context.Context) error {
+	config, err := websocket.NewConfig(c.URL, origin)
+	if err != nil {
+		return fmt.Errorf("can't connect to %s: %w", c.URL, err)
+	}
+	conn, err := config.DialContext(ctx)
+	if err != nil {
+		return fmt.Errorf("can't connect to %s: %w", c.URL, err)
+	}
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	defer stop()
+
+	c.mu.Lock()
+	c.conn = conn
+	c.send(Message{Type: HelloMessage, Seat: seatName(c.Seat)})
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		c.conn = nil
+		c.mu.Unlock()
+		conn.Close()
+	}()
+
+	for {
+		if c.Keepalive > 0 {
+			conn.SetReadDeadline(time.Now().Add(3 * c.Keepalive))
+		}
+		var m Message
+		if err := websocket.JSON.Receive(conn, &m); err != nil {
+			return fmt.Errorf("can't read from %s: %w", c.URL, err)
+		}
+		if m.Type == PingMessage {
+			c.mu.Lock()
+			c.send(Message{Type: PongMessage})
+			c.mu.Unlock()
+			continue
+		}
+
+		c.mu.Lock()
+		err := c.handle(m)
+		c.mu.Unlock()
+		if err != nil {
+			slog.Error("can't follow lan game", "url", c.URL, "err", err)
+		}
+		if c.OnUpdate != nil {
+			c.OnUpdate()
+		}
+	}
+}
+
+func (c *Client) handle(m Message) error {
+	switch m.Type {
+	case StateMessage:
+		return c.sync(m)
+	case MoveMessage:
+		move, err := rules.ParseMove(m.Move)
+		if err != nil {
+			return err
+		}
+		switch {
+		case m.Ply < len(c.moves) && c.moves[m.Ply] == move:
+			// our own move coming back
+		case m.Ply == len(c.moves) && c.game != nil && c.game.Move(move) == nil:
+			c.moves = append(c.moves, move)
+			return c.board.Play(move)
+		default:
+			c.send(Message{Type: SyncMessage})
+		}
+	case AnnotateMessage:
+		c.board.SetAnnotations(ToAnnotations(m.Annotations))
+	case ErrorMessage:
+		slog.Warn("lan server refused", "url", c.URL, "err", m.Error)
+	}
+	return nil
+}
+
+// sync catches up with the state of the server. Moves that only add to the
+// ones on the board are played, so they are animated, anything else sets
+// the board to the server's game.
+func (c *Client) sync(m Message) error {
+	moves := make([]rules.Move, 0, len(m.Moves))
+	for _, s := range m.Moves {
+		move, err := rules.ParseMove(s)
+		if err != nil {
+			return err
+		}
+		moves = append(moves, move)
+	}
+
+	if seat := parseSeat(m.Seat); seat != c.seat || c.game == nil {
+		c.seat = seat
+		c.board.SetFlipped(seat == rules.Black)
+		c.board.SetPlayer(seat)
+	}
+	c.board.SetAnnotations(ToAnnotations(m.Annotations))
+
+	played := len(c.moves)
+	if c.game != nil && m.FEN == c.start && played <= len(moves) && slices.Equal(moves[:played], c.moves) {
+		for _, move := range moves[played:] {
+			if err := c.game.Move(move); err != nil {
+				return fmt.Errorf("can't play %s: %w", move, err)
+			}
+			if err := c.board.Play(move); err != nil {
+				return err
+			}
+			c.moves = append(c.moves, move)
+		}
+		return nil
+	}
+
+	c.start, c.moves = m.FEN, moves
+	return c.rebuild()
+}
+
+// rebuild sets the board to the server's game.
+func (c *Client) rebuild() error {
+	game, err := replay(c.start, c.moves)
+	if err != nil {
+		return err
+	}
+	board, err := replay(c.start, c.moves)
+	if err != nil {
+		return err
+	}
+	c.game = game
+	c.board.SetRules(board)
+	return nil
+}
+
+func (c *Client) send(m Message) {
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err := websocket.JSON.Send(c.conn, m); err != nil {
+		// the read fails too and the client reconnects
+		slog.Warn("can't send to lan server", "url", c.URL, "err", err)
+		c.conn.Close()
+	}
+}
+
+func replay(fen string, moves []rules.Move) (*rules.Game, error) {
+	if fen == "" {
+		fen = rules.Standard.StartingFEN()
+	}
+	game, err := rules.NewGameFromFEN(fen)
+	if err != nil {
+		return nil, err
+	}
+	for _, move := range moves {
+		if err := game.Move(move); err != nil {
+			return nil, fmt.Errorf("can't replay %s: %w", move, err)
+		}
+	}
+	return game, nil
+}
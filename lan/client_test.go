@@ -0,0 +1,139 @@
+package lan
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"gioui.org/widget/material"
+	"github.com/failosof/chessboard"
+	"github.com/failosof/chessboard/rules"
+)
+
+func eventually(t *testing.T, what string, done func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func newTestClient(t *testing.T, url string, seat rules.Color, keepalive time.Duration) (*Client, *chessboard.Widget) {
+	t.Helper()
+
+	board := chessboard.NewWidget(material.NewTheme(), chessboard.Config{})
+	c := NewClient(url, seat, board)
+	c.Retry = 10 * time.Millisecond
+	c.Keepalive = keepalive
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	eventually(t, "the client to connect", c.Connected)
+	return c, board
+}
+
+func placement(board *chessboard.Widget) string {
+	placement, _, _ := strings.Cut(board.FEN(), " ")
+	return placement
+}
+
+func TestClientReconnects(t *testing.T) {
+	s, url := newTestServer(t, 0)
+	c, board := newTestClient(t, url, rules.White, 0)
+	black, _ := connect(t, url, "black")
+	if c.Seated() != rules.White {
+		t.Fatalf("seated as %s, want white", c.Seated())
+	}
+
+	move := rules.Move{From: rules.NewSquare(4, 1), To: rules.NewSquare(4, 3)}
+	if err := board.Rules().Move(move); err != nil {
+		t.Fatalf("can't play e2e4: %v", err)
+	}
+	c.HandleEvent(chessboard.Event{Type: chessboard.MoveEvent, Move: move})
+	if m := black.receive(); m.Move != "e2e4" {
+		t.Fatalf("black got %+v, want e2e4", m)
+	}
+
+	// the white connection drops and black moves while it's gone
+	s.mu.Lock()
+	for _, p := range s.peers {
+		if p.seat == rules.White {
+			p.conn.Close()
+		}
+	}
+	s.mu.Unlock()
+	black.send(Message{Type: MoveMessage, Move: "e7e5", Ply: 1})
+
+	eventually(t, "the board to catch up", func() bool {
+		return placement(board) == "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR"
+	})
+	eventually(t, "the client to reconnect", c.Connected)
+	if c.Seated() != rules.White {
+		t.Errorf("seated as %s after reconnecting, want white", c.Seated())
+	}
+}
+
+func TestClientRefusedMove(t *testing.T) {
+	_, url := newTestServer(t, 0)
+	c, board := newTestClient(t, url, rules.Black, 0)
+
+	// not black's move, the server sends the state back
+	move := rules.Move{From: rules.NewSquare(4, 1), To: rules.NewSquare(4, 3)}
+	if err := board.Rules().Move(move); err != nil {
+		t.Fatalf("can't play e2e4: %v", err)
+	}
+	c.HandleEvent(chessboard.Event{Type: chessboard.MoveEvent, Move: move})
+	eventually(t, "the move to be taken back", func() bool {
+		return placement(board) == "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR"
+	})
+}
+
+func TestClientAnnotations(t *testing.T) {
+	_, url := newTestServer(t, 0)
+	c, board := newTestClient(t, url, rules.White, 0)
+	black, _ := connect(t, url, "black")
+
+	arrow := chessboard.Annotation{Type: chessboard.ArrowAnno, Start: rules.NewSquare(4, 1), End: rules.NewSquare(4, 3)}
+	board.SetAnnotations([]chessboard.Annotation{arrow})
+	c.HandleEvent(chessboard.Event{Type: chessboard.AnnotateEvent})
+	if m := black.receive(); m.Type != AnnotateMessage || len(m.Annotations) != 1 || m.Annotations[0].End != arrow.End {
+		t.Errorf("black got %+v, want the arrow", m)
+	}
+
+	circle := Annotation{Type: chessboard.CircleAnno, Start: rules.NewSquare(3, 6)}
+	black.send(Message{Type: AnnotateMessage, Annotations: []Annotation{circle}})
+	eventually(t, "the circle", func() bool {
+		annos := board.Annotations()
+		return len(annos) == 1 && annos[0].Type == chessboard.CircleAnno
+	})
+}
+
+func TestClientKeepalive(t *testing.T) {
+	s, url := newTestServer(t, 20*time.Millisecond)
+	newTestClient(t, url, rules.White, 20*time.Millisecond)
+
+	seated := func() *peer {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.seats[rules.White]
+	}
+	first := seated()
+	time.Sleep(200 * time.Millisecond)
+	if seated() != first {
+		t.Error("the client answering pings was dropped")
+	}
+}
@@ -0,0 +1,96 @@
+// Package lan lets boards in different apps play each other over a local
+// network. A relay server keeps the game and checks the moves, players and
+// spectators connect to it over WebSocket and get the moves and the
+// annotations drawn on other boards.
+package lan
+
+import (
+	"image/color"
+
+	"github.com/failosof/chessboard"
+	"github.com/failosof/chessboard/rules"
+)
+
+// Message types. Clients start with hello to take a seat and get the state
+// back, after that moves and annotations go both ways. Moves are numbered by
+// their ply, so a client that missed one asks for the state with sync. The
+// server pings every client now and then, and they answer with pong.
+const (
+	HelloMessage    = "hello"
+	StateMessage    = "state"
+	MoveMessage     = "move"
+	AnnotateMessage = "annotate"
+	SyncMessage     = "sync"
+	ErrorMessage    = "error"
+	PingMessage     = "ping"
+	PongMessage     = "pong"
+)
+
+// Message is sent as JSON, one per WebSocket frame.
+type Message struct {
+	Type string `json:"type"`
+	// Seat is white or black, or empty for spectators. Clients ask for it in
+	// hello, the server says which they got in state.
+	Seat        string       `json:"seat,omitempty"`
+	FEN         string       `json:"fen,omitempty"`
+	Moves       []string     `json:"moves,omitempty"`
+	Move        string       `json:"move,omitempty"`
+	Ply         int          `json:"ply"`
+	Annotations []Annotation `json:"annotations,omitempty"`
+	Error       string       `json:"error,omitempty"`
+}
+
+// Annotation is a chessboard.Annotation without its drawing state.
+type Annotation struct {
+	Type  chessboard.AnnoType `json:"type"`
+	Start rules.Square        `json:"start"`
+	End   rules.Square        `json:"end"`
+	NAG   chessboard.NAG      `json:"nag,omitempty"`
+	Color color.NRGBA         `json:"color"`
+}
+
+func FromAnnotations(annos []chessboard.Annotation) []Annotation {
+	out := make([]Annotation, 0, len(annos))
+	for _, a := range annos {
+		out = append(out, Annotation{Type: a.Type, Start: a.Start, End: a.End, NAG: a.NAG, Color: a.Color})
+	}
+	return out
+}
+
+func ToAnnotations(annos []Annotation) []chessboard.Annotation {
+	out := make([]chessboard.Annotation, 0, len(annos))
+	for _, a := range annos {
+		out = append(out, chessboard.Annotation{Type: a.Type, Start: a.Start, End: a.End, NAG: a.NAG, Color: a.Color})
+	}
+	return out
+}
+
+func seatName(seat rules.Color) string {
+	switch seat {
+	case rules.White:
+		return "white"
+	case rules.Black:
+		return "black"
+	default:
+		return ""
+	}
+}
+
+func parseSeat(name string) rules.Color {
+	switch name {
+	case "white":
+		return rules.White
+	case "black":
+		return rules.Black
+	default:
+		return rules.NoColor
+	}
+}
+
+func formatMoves(moves []rules.Move) []string {
+	out := make([]string, 0, len(moves))
+	for _, move := range moves {
+		out = append(out, move.String())
+	}
+	return out
+}
@@ -0,0 +1,287 @@
+package lan
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/failosof/chessboard/rules"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	helloTimeout = 10 * time.Second
+	writeTimeout = 5 * time.Second
+	// queueSize is how many messages a client may fall behind before it's
+	// dropped.
+	queueSize = 64
+)
+
+// DefaultKeepalive is how often servers ping their clients.
+const DefaultKeepalive = 10 * time.Second
+
+// Server relays a game between the clients connected to it, serving
+// WebSocket on any path it's mounted at. Its game is the one that counts:
+// moves of the wrong seat or that aren't legal are answered with an error
+// and the state. A seat is free again when its client disconnects, so a
+// player that reconnects gets it back. Clients that stop answering pings
+// are disconnected, which frees the seat of a connection that died without
+// being closed.
+type Server struct {
+	// Keepalive is how often clients are pinged, those not heard from for
+	// three times as long are dropped. Zero never pings.
+	Keepalive time.Duration
+
+	start       string
+	game        *rules.Game
+	moves       []rules.Move
+	annotations []Annotation
+
+	peers []*peer
+	seats [3]*peer // by color
+
+	mu sync.Mutex
+}
+
+// peer is a connected client. Messages are queued for a goroutine of its
+// own to write, so a slow client doesn't hold up the others.
+type peer struct {
+	conn   *websocket.Conn
+	seat   rules.Color
+	out    chan Message
+	closed bool
+
+	mu sync.Mutex
+}
+
+// NewServer starts a game from the FEN, or from the starting position when
+// it's empty.
+func NewServer(fen string) (*Server, error) {
+	s := &Server{Keepalive: DefaultKeepalive}
+	if err := s.reset(fen); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// any origin, the apps on the network aren't browsers
+	websocket.Server{Handler: s.serve}.ServeHTTP(w, r)
+}
+
+// Reset starts a new game and sends it to everyone.
+func (s *Server) Reset(fen string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.reset(fen); err != nil {
+		return err
+	}
+	for _, p := range s.peers {
+		p.send(s.state(p))
+	}
+	return nil
+}
+
+// Game returns the starting FEN and the moves played since.
+func (s *Server) Game() (string, []rules.Move) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.start, slices.Clone(s.moves)
+}
+
+func (s *Server) reset(fen string) error {
+	if fen == "" {
+		fen = rules.Standard.StartingFEN()
+	}
+	game, err := rules.NewGameFromFEN(fen)
+	if err != nil {
+		return fmt.Errorf("can't start lan game: %w", err)
+	}
+	s.start, s.game, s.moves, s.annotations = fen, game, nil, nil
+	return nil
+}
+
+func (s *Server) serve(conn *websocket.Conn) {
+	defer conn.Close()
+
+	var hello Message
+	conn.SetReadDeadline(time.Now().Add(helloTimeout))
+	if err := websocket.JSON.Receive(conn, &hello); err != nil || hello.Type != HelloMessage {
+		slog.Warn("lan client didn't say hello", "addr", conn.Request().RemoteAddr, "err", err)
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	p := s.join(conn, parseSeat(hello.Seat))
+	defer s.leave(p)
+	go p.write()
+
+	s.mu.Lock()
+	keepalive := s.Keepalive
+	s.mu.Unlock()
+	if keepalive > 0 {
+		go p.ping(keepalive)
+	}
+
+	for {
+		if keepalive > 0 {
+			conn.SetReadDeadline(time.Now().Add(3 * keepalive))
+		}
+		var m Message
+		if err := websocket.JSON.Receive(conn, &m); err != nil {
+			return
+		}
+		s.handle(p, m)
+	}
+}
+
+func (s *Server) join(conn *websocket.Conn, seat rules.Color) *peer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if seat != rules.NoColor && s.seats[seat] != nil {
+		seat = rules.NoColor
+	}
+	p := &peer{conn: conn, seat: seat, out: make(chan Message, queueSize)}
+	if seat != rules.NoColor {
+		s.seats[seat] = p
+	}
+	s.peers = append(s.peers, p)
+	p.send(s.state(p))
+	return p
+}
+
+func (s *Server) leave(p *peer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p.seat != rules.NoColor && s.seats[p.seat] == p {
+		s.seats[p.seat] = nil
+	}
+	s.peers = slices.DeleteFunc(s.peers, func(other *peer) bool {
+		return other == p
+	})
+	p.close()
+}
+
+func (s *Server) handle(p *peer, m Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch m.Type {
+	case MoveMessage:
+		if err := s.move(p, m); err != nil {
+			p.send(Message{Type: ErrorMessage, Error: err.Error()})
+			p.send(s.state(p))
+			return
+		}
+		played := Message{Type: MoveMessage, Move: m.Move, Ply: m.Ply}
+		for _, other := range s.peers {
+			other.send(played)
+		}
+	case AnnotateMessage:
+		s.annotations = m.Annotations
+		for _, other := range s.peers {
+			if other != p {
+				other.send(Message{Type: AnnotateMessage, Annotations: m.Annotations})
+			}
+		}
+	case SyncMessage:
+		p.send(s.state(p))
+	}
+}
+
+func (s *Server) move(p *peer, m Message) error {
+	switch outcome, _ := s.game.Outcome(); {
+	case outcome != rules.NoOutcome:
+		return fmt.Errorf("the game is over")
+	case p.seat != s.game.Turn():
+		return fmt.Errorf("it isn't %s's move", seatName(s.game.Turn()))
+	case m.Ply != len(s.moves):
+		return fmt.Errorf("move for ply %d, the game is at ply %d", m.Ply, len(s.moves))
+	}
+
+	move, err := rules.ParseMove(m.Move)
+	if err != nil {
+		return err
+	}
+	if err := s.game.Move(move); err != nil {
+		return fmt.Errorf("can't play %s: %w", m.Move, err)
+	}
+	s.moves = append(s.moves, move)
+	return nil
+}
+
+func (s *Server) state(p *peer) Message {
+	return Message{
+		Type:        StateMessage,
+		Seat:        seatName(p.seat),
+		FEN:         s.start,
+		Moves:       formatMoves(s.moves),
+		Ply:         len(s.moves),
+		Annotations: s.annotations,
+	}
+}
+
+// send queues a message and tells whether the client is still there. A
+// client whose queue is full is disconnected, serve cleans up then.
+func (p *peer) send(m Message) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return false
+	}
+
+	select {
+	case p.out <- m:
+		return true
+	default:
+		slog.Warn("lan client fell behind", "addr", p.conn.Request().RemoteAddr)
+		p.closeLocked()
+		return false
+	}
+}
+
+// write sends the queued messages until the peer is closed. After a failed
+// write the connection is closed and the rest is dropped.
+func (p *peer) write() {
+	failed := false
+	for m := range p.out {
+		if failed {
+			continue
+		}
+		p.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if err := websocket.JSON.Send(p.conn, m); err != nil {
+			p.conn.Close()
+			failed = true
+		}
+	}
+}
+
+// ping pings the client until it's gone.
+func (p *peer) ping(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if !p.send(Message{Type: PingMessage}) {
+			return
+		}
+	}
+}
+
+func (p *peer) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closeLocked()
+}
+
+func (p *peer) closeLocked() {
+	if !p.closed {
+		p.closed = true
+		close(p.out)
+		p.conn.Close()
+	}
+}
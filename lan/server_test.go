@@ -0,0 +1,227 @@
+package lan
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/failosof/chessboard"
+	"github.com/failosof/chessboard/rules"
+	"golang.org/x/net/websocket"
+)
+
+func newTestServer(t *testing.T, keepalive time.Duration) (*Server, string) {
+	t.Helper()
+
+	s, err := NewServer("")
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	s.Keepalive = keepalive
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return s, "ws" + strings.TrimPrefix(ts.URL, "http")
+}
+
+// peerConn is a client speaking the protocol by hand.
+type peerConn struct {
+	t    *testing.T
+	conn *websocket.Conn
+}
+
+func connect(t *testing.T, url, seat string) (*peerConn, Message) {
+	t.Helper()
+
+	conn, err := websocket.Dial(url, "", origin)
+	if err != nil {
+		t.Fatalf("can't connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	p := &peerConn{t: t, conn: conn}
+	p.send(Message{Type: HelloMessage, Seat: seat})
+	state := p.receive()
+	if state.Type != StateMessage {
+		t.Fatalf("got %s after hello, want state", state.Type)
+	}
+	return p, state
+}
+
+func (p *peerConn) send(m Message) {
+	p.t.Helper()
+	if err := websocket.JSON.Send(p.conn, m); err != nil {
+		p.t.Fatalf("can't send %s: %v", m.Type, err)
+	}
+}
+
+// receive returns the next message that isn't a ping.
+func (p *peerConn) receive() Message {
+	p.t.Helper()
+
+	p.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var m Message
+		if err := websocket.JSON.Receive(p.conn, &m); err != nil {
+			p.t.Fatalf("can't receive: %v", err)
+		}
+		if m.Type != PingMessage {
+			return m
+		}
+	}
+}
+
+func TestSeats(t *testing.T) {
+	_, url := newTestServer(t, 0)
+
+	white, state := connect(t, url, "white")
+	if state.Seat != "white" || state.FEN != rules.Standard.StartingFEN() || len(state.Moves) != 0 {
+		t.Errorf("first state %+v, want the white seat at the start", state)
+	}
+	if _, state := connect(t, url, "white"); state.Seat != "" {
+		t.Errorf("second white got seat %q, want to watch", state.Seat)
+	}
+	if _, state := connect(t, url, "black"); state.Seat != "black" {
+		t.Errorf("black got seat %q", state.Seat)
+	}
+
+	// the seat is free once its player left
+	white.conn.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		peer, state := connect(t, url, "white")
+		if state.Seat == "white" {
+			break
+		}
+		peer.conn.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("the white seat wasn't freed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMoves(t *testing.T) {
+	s, url := newTestServer(t, 0)
+	white, _ := connect(t, url, "white")
+	black, _ := connect(t, url, "black")
+	spectator, _ := connect(t, url, "")
+
+	white.send(Message{Type: MoveMessage, Move: "e2e4", Ply: 0})
+	for _, p := range []*peerConn{white, black, spectator} {
+		if m := p.receive(); m.Type != MoveMessage || m.Move != "e2e4" || m.Ply != 0 {
+			t.Errorf("got %+v, want e2e4 for ply 0", m)
+		}
+	}
+
+	refused := []struct {
+		peer *peerConn
+		move Message
+	}{
+		{white, Message{Type: MoveMessage, Move: "d2d4", Ply: 1}},     // out of turn
+		{spectator, Message{Type: MoveMessage, Move: "e7e5", Ply: 1}}, // not seated
+		{black, Message{Type: MoveMessage, Move: "e7e4", Ply: 1}},     // illegal
+		{black, Message{Type: MoveMessage, Move: "e7e5", Ply: 0}},     // stale
+	}
+	for _, test := range refused {
+		test.peer.send(test.move)
+		if m := test.peer.receive(); m.Type != ErrorMessage {
+			t.Errorf("%s got %+v, want an error", test.move.Move, m)
+		}
+		if m := test.peer.receive(); m.Type != StateMessage || m.Ply != 1 || m.Moves[0] != "e2e4" {
+			t.Errorf("%s got %+v, want the state at ply 1", test.move.Move, m)
+		}
+	}
+
+	black.send(Message{Type: MoveMessage, Move: "e7e5", Ply: 1})
+	if m := white.receive(); m.Move != "e7e5" {
+		t.Errorf("white got %+v, want e7e5", m)
+	}
+	if start, moves := s.Game(); start != rules.Standard.StartingFEN() || len(moves) != 2 {
+		t.Errorf("server game %s %v, want two moves from the start", start, moves)
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	_, url := newTestServer(t, 0)
+	white, _ := connect(t, url, "white")
+	black, _ := connect(t, url, "black")
+
+	arrows := []Annotation{{Type: chessboard.ArrowAnno, Start: rules.NewSquare(4, 1), End: rules.NewSquare(4, 3)}}
+	white.send(Message{Type: AnnotateMessage, Annotations: arrows})
+	if m := black.receive(); m.Type != AnnotateMessage || len(m.Annotations) != 1 || m.Annotations[0] != arrows[0] {
+		t.Errorf("black got %+v, want the arrow", m)
+	}
+
+	// the annotations aren't echoed, and late comers get them with the state
+	white.send(Message{Type: SyncMessage})
+	if m := white.receive(); m.Type != StateMessage {
+		t.Errorf("white got %+v back, want only the state", m)
+	}
+	if _, state := connect(t, url, ""); len(state.Annotations) != 1 {
+		t.Errorf("spectator got annotations %+v, want the arrow", state.Annotations)
+	}
+}
+
+func TestSlowPeer(t *testing.T) {
+	if testing.Short() {
+		t.Skip("fills the socket buffers of a client")
+	}
+
+	s, url := newTestServer(t, 0)
+	white, _ := connect(t, url, "white")
+	black, _ := connect(t, url, "black")
+	// never reads after its state
+	connect(t, url, "")
+
+	peers := func() int {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return len(s.peers)
+	}
+
+	arrows := make([]Annotation, 1000)
+	for i := range arrows {
+		arrows[i] = Annotation{Type: chessboard.ArrowAnno, Start: rules.NewSquare(i%8, 0), End: rules.NewSquare(i%8, 7)}
+	}
+	// the others keep getting the annotations without waiting on the write
+	// timeout until the stalled client's queue overflows and it's dropped
+	for i := 0; peers() == 3; i++ {
+		if i == 1000 {
+			t.Fatal("the stalled client wasn't dropped")
+		}
+		sent := time.Now()
+		white.send(Message{Type: AnnotateMessage, Annotations: arrows})
+		if m := black.receive(); m.Type != AnnotateMessage {
+			t.Fatalf("annotation %d: got %s", i, m.Type)
+		}
+		if took := time.Since(sent); took > writeTimeout/2 {
+			t.Fatalf("annotation %d took %s to relay", i, took)
+		}
+	}
+}
+
+func TestKeepalive(t *testing.T) {
+	_, url := newTestServer(t, 20*time.Millisecond)
+
+	// a connection that died without closing doesn't keep its seat
+	silent, _ := connect(t, url, "white")
+	silent.conn.SetReadDeadline(time.Now().Add(time.Second))
+	var m Message
+	if err := websocket.JSON.Receive(silent.conn, &m); err != nil || m.Type != PingMessage {
+		t.Fatalf("got %+v, %v, want a ping", m, err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		peer, state := connect(t, url, "white")
+		if state.Seat == "white" {
+			break
+		}
+		peer.conn.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("the silent client kept its seat")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,313 @@
+// Package lichess plays games on a board widget through the Lichess Board
+// API, where moves made on the board are sent to Lichess and the opponent's
+// moves and the clocks come back over a stream.
+package lichess
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const DefaultURL = "https://lichess.org"
+
+// API is the part of the Board API the games are played through. The
+// streaming methods block until the stream ends or the context is done,
+// sending what comes in to the channel.
+type API interface {
+	StreamEvents(ctx context.Context, events chan<- Event) error
+	StreamGame(ctx context.Context, gameID string, events chan<- GameEvent) error
+	Move(ctx context.Context, gameID string, move string) error
+	Resign(ctx context.Context, gameID string) error
+	// Draw offers or accepts a draw, or declines an offer.
+	Draw(ctx context.Context, gameID string, accept bool) error
+	Challenge(ctx context.Context, username string, options ChallengeOptions) (Challenge, error)
+	AcceptChallenge(ctx context.Context, challengeID string) error
+	DeclineChallenge(ctx context.Context, challengeID string) error
+}
+
+type Player struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Username string `json:"username"`
+	Title    string `json:"title"`
+	Rating   int    `json:"rating"`
+	AILevel  int    `json:"aiLevel"`
+}
+
+type Variant struct {
+	Key  string `json:"key"`
+	Name string `json:"name"`
+}
+
+// Event is a line of the event stream: gameStart, gameFinish, challenge,
+// challengeCanceled or challengeDeclined.
+type Event struct {
+	Type      string     `json:"type"`
+	Game      *GameInfo  `json:"game"`
+	Challenge *Challenge `json:"challenge"`
+}
+
+type GameInfo struct {
+	GameID      string  `json:"gameId"`
+	FullID      string  `json:"fullId"`
+	Color       string  `json:"color"`
+	FEN         string  `json:"fen"`
+	IsMyTurn    bool    `json:"isMyTurn"`
+	LastMove    string  `json:"lastMove"`
+	Opponent    Player  `json:"opponent"`
+	Rated       bool    `json:"rated"`
+	SecondsLeft int     `json:"secondsLeft"`
+	Speed       string  `json:"speed"`
+	Variant     Variant `json:"variant"`
+}
+
+type Challenge struct {
+	ID          string      `json:"id"`
+	URL         string      `json:"url"`
+	Status      string      `json:"status"`
+	Challenger  Player      `json:"challenger"`
+	DestUser    Player      `json:"destUser"`
+	Variant     Variant     `json:"variant"`
+	Rated       bool        `json:"rated"`
+	Speed       string      `json:"speed"`
+	TimeControl TimeControl `json:"timeControl"`
+	Color       string      `json:"color"`
+}
+
+// TimeControl is in seconds.
+type TimeControl struct {
+	Type      string `json:"type"`
+	Limit     int    `json:"limit"`
+	Increment int    `json:"increment"`
+	Show      string `json:"show"`
+}
+
+// ChallengeOptions leave the clock out when Limit is zero. Color is white,
+// black or random.
+type ChallengeOptions struct {
+	Rated     bool
+	Limit     int
+	Increment int
+	Color     string
+	Variant   string
+	FEN       string
+}
+
+// GameEvent is a line of a game stream: gameFull first, then gameState
+// after every move, and chatLine or opponentGone in between. The state is in
+// State for gameFull and inline for gameState, Current returns either.
+type GameEvent struct {
+	Type       string     `json:"type"`
+	ID         string     `json:"id"`
+	Variant    Variant    `json:"variant"`
+	White      Player     `json:"white"`
+	Black      Player     `json:"black"`
+	InitialFEN string     `json:"initialFen"`
+	State      *GameState `json:"state"`
+	GameState
+
+	Username string `json:"username"`
+	Text     string `json:"text"`
+}
+
+func (e GameEvent) Current() GameState {
+	if e.State != nil {
+		return *e.State
+	}
+	return e.GameState
+}
+
+// GameState has the moves in UCI notation, separated by spaces, and the
+// clocks in milliseconds.
+type GameState struct {
+	Moves     string `json:"moves"`
+	WTime     int64  `json:"wtime"`
+	BTime     int64  `json:"btime"`
+	WInc      int64  `json:"winc"`
+	BInc      int64  `json:"binc"`
+	Status    string `json:"status"`
+	Winner    string `json:"winner"`
+	WDraw     bool   `json:"wdraw"`
+	BDraw     bool   `json:"bdraw"`
+	WTakeback bool   `json:"wtakeback"`
+	BTakeback bool   `json:"btakeback"`
+}
+
+// Client talks to Lichess, or to any server at BaseURL speaking the same
+// API.
+type Client struct {
+	BaseURL string
+	Token   string
+	HTTP    *http.Client
+}
+
+func NewClient(token string) *Client {
+	return &Client{BaseURL: DefaultURL, Token: token, HTTP: http.DefaultClient}
+}
+
+var _ API = (*Client)(nil)
+
+func (c *Client) StreamEvents(ctx context.Context, events chan<- Event) error {
+	return stream(ctx, c, "/api/stream/event", events)
+}
+
+func (c *Client) StreamGame(ctx context.Context, gameID string, events chan<- GameEvent) error {
+	return stream(ctx, c, "/api/board/game/stream/"+url.PathEscape(gameID), events)
+}
+
+func (c *Client) Move(ctx context.Context, gameID string, move string) error {
+	return c.post(ctx, "/api/board/game/"+url.PathEscape(gameID)+"/move/"+url.PathEscape(move), nil, nil)
+}
+
+func (c *Client) Resign(ctx context.Context, gameID string) error {
+	return c.post(ctx, "/api/board/game/"+url.PathEscape(gameID)+"/resign", nil, nil)
+}
+
+func (c *Client) Draw(ctx context.Context, gameID string, accept bool) error {
+	answer := "no"
+	if accept {
+		answer = "yes"
+	}
+	return c.post(ctx, "/api/board/game/"+url.PathEscape(gameID)+"/draw/"+answer, nil, nil)
+}
+
+func (c *Client) Challenge(ctx context.Context, username string, options ChallengeOptions) (Challenge, error) {
+	form := url.Values{}
+	form.Set("rated", strconv.FormatBool(options.Rated))
+	if options.Limit > 0 {
+		form.Set("clock.limit", strconv.Itoa(options.Limit))
+		form.Set("clock.increment", strconv.Itoa(options.Increment))
+	}
+	if options.Color != "" {
+		form.Set("color", options.Color)
+	}
+	if options.Variant != "" {
+		form.Set("variant", options.Variant)
+	}
+	if options.FEN != "" {
+		form.Set("fen", options.FEN)
+	}
+
+	var response struct {
+		Challenge
+		Wrapped *Challenge `json:"challenge"`
+	}
+	if err := c.post(ctx, "/api/challenge/"+url.PathEscape(username), form, &response); err != nil {
+		return Challenge{}, err
+	}
+	if response.Wrapped != nil {
+		return *response.Wrapped, nil
+	}
+	return response.Challenge, nil
+}
+
+func (c *Client) AcceptChallenge(ctx context.Context, challengeID string) error {
+	return c.post(ctx, "/api/challenge/"+url.PathEscape(challengeID)+"/accept", nil, nil)
+}
+
+func (c *Client) DeclineChallenge(ctx context.Context, challengeID string) error {
+	return c.post(ctx, "/api/challenge/"+url.PathEscape(challengeID)+"/decline", nil, nil)
+}
+
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+	return resp, nil
+}
+
+func (c *Client) post(ctx context.Context, path string, form url.Values, response any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("can't post %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if response == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("can't decode response of %s: %w", path, err)
+	}
+	return nil
+}
+
+// stream reads NDJSON, skipping the empty lines Lichess sends to keep the
+// connection alive.
+func stream[T any](ctx context.Context, c *Client, path string, out chan<- T) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/x-ndjson")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("can't stream %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var v T
+		if err := json.Unmarshal(line, &v); err != nil {
+			return fmt.Errorf("can't decode %s: %w", line, err)
+		}
+		select {
+		case out <- v:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("can't read %s: %w", path, err)
+	}
+	return nil
+}
+
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var message struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &message) == nil && message.Error != "" {
+		return fmt.Errorf("%s: %s", resp.Status, message.Error)
+	}
+	return fmt.Errorf("%s", resp.Status)
+}
@@ -0,0 +1,108 @@
+package lichess
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := NewClient("secret")
+	client.BaseURL = server.URL
+	client.HTTP = server.Client()
+	return client
+}
+
+func TestStreamEvents(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/stream/event" || r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, `{"error":"No such token"}`, http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		fmt.Fprintln(w, `{"type":"challenge","challenge":{"id":"c1","challenger":{"name":"Bob"}}}`)
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, `{"type":"gameStart","game":{"gameId":"g1","color":"black","opponent":{"username":"Bob"}}}`)
+	})
+
+	events := make(chan Event, 4)
+	if err := client.StreamEvents(context.Background(), events); err != nil {
+		t.Fatalf("StreamEvents: %v", err)
+	}
+	close(events)
+
+	var got []string
+	for e := range events {
+		switch e.Type {
+		case "challenge":
+			got = append(got, e.Type+" "+e.Challenge.ID)
+		case "gameStart":
+			got = append(got, e.Type+" "+e.Game.GameID+" "+e.Game.Color)
+		}
+	}
+	if want := "challenge c1, gameStart g1 black"; strings.Join(got, ", ") != want {
+		t.Errorf("events %q, want %s", got, want)
+	}
+}
+
+func TestStreamCanceled(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type":"gameFull","id":"g1"}`)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan GameEvent)
+	errs := make(chan error, 1)
+	go func() { errs <- client.StreamGame(ctx, "g1", events) }()
+
+	if e := <-events; e.Type != "gameFull" {
+		t.Errorf("got %s, want gameFull", e.Type)
+	}
+	cancel()
+	if err := <-errs; err != context.Canceled {
+		t.Errorf("StreamGame error %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestResponseError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":"Not your turn, or game already over"}`)
+	})
+
+	err := client.Move(context.Background(), "g1", "e2e4")
+	if err == nil || !strings.Contains(err.Error(), "400 Bad Request: Not your turn") {
+		t.Errorf("Move error %v, want the server's message", err)
+	}
+}
+
+func TestChallenge(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("can't parse form: %v", err)
+		}
+		if r.URL.Path != "/api/challenge/bob" || r.Form.Get("clock.limit") != "300" ||
+			r.Form.Get("clock.increment") != "3" || r.Form.Get("variant") != "chess960" {
+			t.Errorf("challenge posted to %s with %v", r.URL.Path, r.Form)
+		}
+		fmt.Fprint(w, `{"challenge":{"id":"c1","status":"created","timeControl":{"limit":300,"increment":3}}}`)
+	})
+
+	challenge, err := client.Challenge(context.Background(), "bob", ChallengeOptions{Limit: 300, Increment: 3, Variant: "chess960"})
+	if err != nil {
+		t.Fatalf("Challenge: %v", err)
+	}
+	if challenge.ID != "c1" || challenge.TimeControl.Limit != 300 {
+		t.Errorf("challenge %+v, want c1 with 5 minutes", challenge)
+	}
+}
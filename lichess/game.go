@@ -0,0 +1,329 @@
+package lichess
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/failosof/chessboard"
+	"github.com/failosof/chessboard/rules"
+)
+
+var variants = map[string]rules.Variant{
+	"kingOfTheHill": rules.KingOfTheHill,
+	"threeCheck":    rules.ThreeCheck,
+	"atomic":        rules.Atomic,
+	"antichess":     rules.Antichess,
+	"horde":         rules.Horde,
+	"racingKings":   rules.RacingKings,
+}
+
+// Game plays a Lichess game on a widget. Moves made on the board are sent
+// once the app hands the board events to HandleEvent, the opponent's moves
+// are played on the board as they come in and the clock follows the times
+// of the server.
+type Game struct {
+	ID    string
+	Color rules.Color
+
+	// OnUpdate is called from the streaming goroutine after the board or
+	// the clock changed, to have the window redrawn.
+	OnUpdate func()
+
+	api   API
+	board *chessboard.Widget
+	clock *chessboard.Clock
+
+	variant  string
+	start    string
+	server   *rules.Game // the game as the server has it, for translating moves
+	moves    []string
+	state    GameState
+	finished bool
+
+	mu sync.Mutex
+}
+
+// NewGame sets up the board for a game from a gameStart event, the clock
+// may be nil.
+func NewGame(api API, info GameInfo, board *chessboard.Widget, clock *chessboard.Clock) *Game {
+	color := rules.White
+	if info.Color == "black" {
+		color = rules.Black
+	}
+
+	board.SetFlipped(color == rules.Black)
+	board.SetPlayer(color)
+	return &Game{ID: info.GameID, Color: color, api: api, board: board, clock: clock}
+}
+
+// Run follows the game until it's over, the stream ends or the context is
+// done.
+func (g *Game) Run(ctx context.Context) error {
+	events := make(chan GameEvent)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- g.api.StreamGame(ctx, g.ID, events)
+		close(events)
+	}()
+
+	for e := range events {
+		var err error
+		switch e.Type {
+		case "gameFull":
+			err = g.setUp(e)
+		case "gameState":
+			err = g.sync(e.Current())
+		default:
+			continue
+		}
+		if err != nil {
+			slog.Error("can't follow lichess game", "game", g.ID, "err", err)
+		}
+		if g.OnUpdate != nil {
+			g.OnUpdate()
+		}
+	}
+	return <-errs
+}
+
+// HandleEvent sends the moves made on the board.
+func (g *Game) HandleEvent(ctx context.Context, e chessboard.Event) {
+	if e.Type != chessboard.MoveEvent {
+		return
+	}
+
+	g.mu.Lock()
+	if g.server == nil {
+		g.mu.Unlock()
+		return
+	}
+	uci := rules.UCI(g.server, e.Move, g.variant == "chess960")
+	if err := g.server.Move(e.Move); err != nil {
+		g.mu.Unlock()
+		slog.Error("can't send lichess move", "game", g.ID, "move", e.Move, "err", err)
+		return
+	}
+	g.moves = append(g.moves, uci)
+	if g.clock != nil && len(g.moves) > 1 {
+		g.clock.Press(g.Color, time.Now())
+	}
+	g.mu.Unlock()
+
+	go func() {
+		if err := g.api.Move(ctx, g.ID, uci); err != nil {
+			slog.Error("lichess rejected move", "game", g.ID, "move", uci, "err", err)
+			g.takeBack(uci)
+		}
+	}()
+}
+
+func (g *Game) Resign(ctx context.Context) error {
+	return g.api.Resign(ctx, g.ID)
+}
+
+// OfferDraw offers a draw, or accepts the one the opponent offered.
+func (g *Game) OfferDraw(ctx context.Context) error {
+	return g.api.Draw(ctx, g.ID, true)
+}
+
+func (g *Game) DeclineDraw(ctx context.Context) error {
+	return g.api.Draw(ctx, g.ID, false)
+}
+
+// DrawOffered tells whether the opponent is offering a draw.
+func (g *Game) DrawOffered() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.Color == rules.White {
+		return g.state.BDraw
+	}
+	return g.state.WDraw
+}
+
+func (g *Game) State() GameState {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.state
+}
+
+func (g *Game) setUp(e GameEvent) error {
+	g.mu.Lock()
+	g.variant = e.Variant.Key
+	g.start = e.InitialFEN
+	g.server = nil
+	g.moves = nil
+	g.mu.Unlock()
+	return g.sync(e.Current())
+}
+
+// sync catches up with the moves of the server. Moves that only add to the
+// ones on the board are played, so they are animated, anything else sets
+// the board to the server's game. So does a move the board refuses, since
+// the board went its own way then.
+func (g *Game) sync(state GameState) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.state = state
+	moves := strings.Fields(state.Moves)
+	played := len(g.moves)
+	continued := g.server != nil && played <= len(moves) && slices.Equal(moves[:played], g.moves)
+	if continued {
+		for _, uci := range moves[played:] {
+			move, err := rules.ParseUCI(g.server, uci)
+			if err == nil {
+				err = g.server.Move(move)
+			}
+			if err != nil {
+				return fmt.Errorf("can't play %s: %w", uci, err)
+			}
+			g.moves = append(g.moves, uci)
+			if err := g.board.Play(move); err != nil {
+				slog.Warn("lichess board is out of sync", "game", g.ID, "move", uci, "err", err)
+				continued = false
+				break
+			}
+		}
+	}
+	if !continued {
+		if err := g.reset(moves); err != nil {
+			return err
+		}
+	}
+
+	g.syncClock(state, len(moves))
+	g.syncOutcome(state)
+	return nil
+}
+
+func (g *Game) reset(moves []string) error {
+	server, err := g.replay(moves)
+	if err != nil {
+		return err
+	}
+	board, err := g.replay(moves)
+	if err != nil {
+		return err
+	}
+
+	g.server, g.moves = server, moves
+	g.finished = false
+	g.board.SetRules(board)
+	return nil
+}
+
+func (g *Game) replay(moves []string) (*rules.Game, error) {
+	fen := g.start
+	if fen == "" || fen == "startpos" {
+		fen = rules.Standard.StartingFEN()
+		if variant, ok := variants[g.variant]; ok {
+			fen = variant.StartingFEN()
+		}
+		if g.variant == "crazyhouse" {
+			fen = strings.Replace(fen, " ", "[] ", 1)
+		}
+	}
+
+	variant := rules.Standard
+	if v, ok := variants[g.variant]; ok {
+		variant = v
+	}
+	game, err := rules.NewVariantGameFromFEN(variant, fen)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, uci := range moves {
+		move, err := rules.ParseUCI(game, uci)
+		if err == nil {
+			err = game.Move(move)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("can't replay %s: %w", uci, err)
+		}
+	}
+	return game, nil
+}
+
+// takeBack undoes a move the server didn't take.
+func (g *Game) takeBack(uci string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(g.moves) == 0 || g.moves[len(g.moves)-1] != uci {
+		return
+	}
+	if err := g.reset(g.moves[:len(g.moves)-1]); err != nil {
+		slog.Error("can't take back lichess move", "game", g.ID, "err", err)
+	}
+	if g.OnUpdate != nil {
+		g.OnUpdate()
+	}
+}
+
+// syncClock sets the clock to the server's times, which only start running
+// once both sides made a move.
+func (g *Game) syncClock(state GameState, plies int) {
+	if g.clock == nil {
+		return
+	}
+
+	now := time.Now()
+	turn := g.server.Turn()
+	switch {
+	case state.Status != "started":
+		g.clock.Stop(now)
+	case plies >= 2 && g.clock.Running() != turn:
+		g.clock.Press(turn.Other(), now)
+	}
+	g.clock.Set(rules.White, time.Duration(state.WTime)*time.Millisecond, now)
+	g.clock.Set(rules.Black, time.Duration(state.BTime)*time.Millisecond, now)
+}
+
+// syncOutcome ends the game on the board when the server ended it in a way
+// the board can't tell by itself.
+func (g *Game) syncOutcome(state GameState) {
+	if g.finished {
+		return
+	}
+
+	var err error
+	loser := winner(state).Other()
+	switch state.Status {
+	case "created", "started":
+		return
+	case "resign":
+		if loser != rules.NoColor {
+			err = g.board.Resign(loser)
+		}
+	case "outoftime", "timeout":
+		if loser != rules.NoColor {
+			g.board.Timeout(loser)
+		}
+	case "draw":
+		err = g.board.AgreeDraw()
+	case "aborted":
+		g.board.Abort()
+	}
+	g.finished = true
+	if err != nil && !errors.Is(err, errors.ErrUnsupported) {
+		slog.Error("can't end lichess game", "game", g.ID, "status", state.Status, "err", err)
+	}
+}
+
+func winner(state GameState) rules.Color {
+	switch state.Winner {
+	case "white":
+		return rules.White
+	case "black":
+		return rules.Black
+	default:
+		return rules.NoColor
+	}
+}
@@ -0,0 +1,189 @@
+package lichess
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gioui.org/widget/material"
+	"github.com/failosof/chessboard"
+	"github.com/failosof/chessboard/rules"
+)
+
+// gameFull starts a standard game of Alice, who is white, against Bob.
+const gameFull = `{"type":"gameFull","id":"g1","variant":{"key":"standard"},"white":{"name":"Alice"},"black":{"name":"Bob"},"initialFen":"startpos","state":{"type":"gameState","moves":"%s","wtime":60000,"btime":60000,"status":"started"}}`
+
+func gameState(moves, status, winner string) string {
+	return fmt.Sprintf(`{"type":"gameState","moves":"%s","wtime":55000,"btime":58000,"status":"%s","winner":"%s"}`, moves, status, winner)
+}
+
+// newTestGame streams the lines of the game, waiting for the next step
+// before each line after the first.
+func newTestGame(t *testing.T, steps chan struct{}, moves chan string, lines ...string) (*Game, *chessboard.Widget) {
+	t.Helper()
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/board/game/stream/g1":
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			for i, line := range lines {
+				if i > 0 && steps != nil {
+					<-steps
+				}
+				fmt.Fprintln(w, line)
+				fmt.Fprintln(w) // keep alive
+				w.(http.Flusher).Flush()
+			}
+		case strings.HasPrefix(r.URL.Path, "/api/board/game/g1/move/"):
+			move := strings.TrimPrefix(r.URL.Path, "/api/board/game/g1/move/")
+			if moves != nil {
+				moves <- move
+			}
+			if move == "d2d4" {
+				http.Error(w, `{"error":"Not your turn, or game already over"}`, http.StatusBadRequest)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	board := chessboard.NewWidget(material.NewTheme(), chessboard.Config{})
+	game := NewGame(client, GameInfo{GameID: "g1", Color: "white"}, board, nil)
+	return game, board
+}
+
+func placement(board *chessboard.Widget) string {
+	placement, _, _ := strings.Cut(board.FEN(), " ")
+	return placement
+}
+
+func TestGameRun(t *testing.T) {
+	game, board := newTestGame(t, nil, nil,
+		fmt.Sprintf(gameFull, "e2e4"),
+		`{"type":"chatLine","username":"Bob","text":"hi"}`,
+		gameState("e2e4 e7e5", "started", ""),
+		gameState("e2e4 e7e5 g1f3", "started", ""),
+	)
+
+	updates := 0
+	game.OnUpdate = func() { updates++ }
+	if err := game.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if got, want := placement(board), "rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R"; got != want {
+		t.Errorf("board %s, want %s", got, want)
+	}
+	if updates != 3 {
+		t.Errorf("updated %d times, want once per game line", updates)
+	}
+	if state := game.State(); state.WTime != 55000 || state.Moves != "e2e4 e7e5 g1f3" {
+		t.Errorf("state %+v", state)
+	}
+}
+
+func TestGameEnds(t *testing.T) {
+	tests := []struct {
+		state   string
+		outcome rules.Outcome
+		method  rules.Method
+	}{
+		{gameState("e2e4", "resign", "black"), rules.BlackWon, rules.Resignation},
+		{gameState("e2e4", "outoftime", "white"), rules.WhiteWon, rules.Timeout},
+		{gameState("e2e4", "draw", ""), rules.Draw, rules.Agreement},
+		{gameState("e2e4", "aborted", ""), rules.NoOutcome, rules.Aborted},
+		{gameState("f2f3 e7e5 g2g4 d8h4", "mate", "black"), rules.BlackWon, rules.Checkmate},
+	}
+
+	for _, test := range tests {
+		game, board := newTestGame(t, nil, nil, fmt.Sprintf(gameFull, ""), test.state)
+		if err := game.Run(context.Background()); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		if outcome, method := board.Outcome(); outcome != test.outcome || method != test.method {
+			t.Errorf("%s: outcome %s by %s, want %s by %s", test.state, outcome, method, test.outcome, test.method)
+		}
+	}
+}
+
+func TestGameOutOfSync(t *testing.T) {
+	steps := make(chan struct{})
+	game, board := newTestGame(t, steps, nil,
+		fmt.Sprintf(gameFull, "e2e4"),
+		gameState("e2e4 c7c5", "started", ""),
+	)
+
+	synced := make(chan struct{}, 2)
+	game.OnUpdate = func() { synced <- struct{}{} }
+	errs := make(chan error, 1)
+	go func() { errs <- game.Run(context.Background()) }()
+
+	<-synced
+	// the board went its own way before the server's move came in
+	if err := board.Play(rules.Move{From: rules.NewSquare(4, 6), To: rules.NewSquare(4, 4)}); err != nil {
+		t.Fatalf("can't play e7e5: %v", err)
+	}
+	close(steps)
+	<-synced
+	if err := <-errs; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if got, want := placement(board), "rnbqkbnr/pp1ppppp/8/2p5/4P3/8/PPPP1PPP/RNBQKBNR"; got != want {
+		t.Errorf("board %s, want the server's %s", got, want)
+	}
+}
+
+// playOn makes a move on the board the way the widget does, playing it
+// before emitting the event.
+func playOn(t *testing.T, game *Game, board *chessboard.Widget, uci string) {
+	t.Helper()
+
+	move, err := rules.ParseUCI(board.Rules(), uci)
+	if err != nil {
+		t.Fatalf("can't parse %s: %v", uci, err)
+	}
+	if err := board.Rules().Move(move); err != nil {
+		t.Fatalf("can't play %s: %v", uci, err)
+	}
+	game.HandleEvent(context.Background(), chessboard.Event{Type: chessboard.MoveEvent, Move: move})
+}
+
+func TestGameSendsMoves(t *testing.T) {
+	tests := []struct {
+		move      string
+		moves     string
+		placement string
+	}{
+		{"e2e4", "e2e4", "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR"},
+		// the server refuses it, so it's taken back
+		{"d2d4", "", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR"},
+	}
+
+	for _, test := range tests {
+		steps := make(chan struct{})
+		moves := make(chan string, 1)
+		game, board := newTestGame(t, steps, moves, fmt.Sprintf(gameFull, ""), gameState(test.moves, "started", ""))
+
+		synced := make(chan struct{}, 2)
+		game.OnUpdate = func() { synced <- struct{}{} }
+		go game.Run(context.Background())
+		<-synced
+
+		playOn(t, game, board, test.move)
+		if got := <-moves; got != test.move {
+			t.Errorf("sent %s, want %s", got, test.move)
+		}
+		if test.move == "d2d4" {
+			<-synced
+		}
+		close(steps)
+		<-synced
+
+		if got := placement(board); got != test.placement {
+			t.Errorf("%s: board %s, want %s", test.move, got, test.placement)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package chessboard
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/failosof/chessboard/rules"
+	"github.com/failosof/chessboard/util"
+)
+
+// DefaultSpriteOrder is the order of the pieces in the usual 6x2 sprite
+// sheets, white on the first row. A '.' leaves a cell out.
+const DefaultSpriteOrder = "KQBNRPkqbnrp"
+
+var pieceExtensions = []string{".svg", ".png", ".jpg", ".jpeg"}
+
+// MissingPiecesError lists the standard pieces a set has no image for.
+type MissingPiecesError struct {
+	Pieces []rules.Piece
+}
+
+func (e *MissingPiecesError) Error() string {
+	names := make([]string, len(e.Pieces))
+	for i, piece := range e.Pieces {
+		names[i] = pieceName(piece, " ")
+	}
+	return "missing pieces: " + strings.Join(names, ", ")
+}
+
+func pieceName(piece rules.Piece, sep string) string {
+	color := "white"
+	if piece.Color() == rules.Black {
+		color = "black"
+	}
+	return color + sep + pieceTypeNames[piece.Type()]
+}
+
+var pieceTypeNames = map[rules.PieceType]string{
+	rules.King:       "king",
+	rules.Queen:      "queen",
+	rules.Rook:       "rook",
+	rules.Bishop:     "bishop",
+	rules.Knight:     "knight",
+	rules.Pawn:       "pawn",
+	rules.Archbishop: "archbishop",
+	rules.Chancellor: "chancellor",
+}
+
+// PieceNaming gives the file names a set may use for a piece, without the
+// extension.
+type PieceNaming func(piece rules.Piece) []string
+
+// ShortNaming names pieces like wk.png or wK.png.
+func ShortNaming(piece rules.Piece) []string {
+	short := piece.Color().String() + piece.Type().String()
+	return []string{short, piece.Color().String() + strings.ToUpper(piece.Type().String())}
+}
+
+// LongNaming names pieces like white_king.png or white-king.png.
+func LongNaming(piece rules.Piece) []string {
+	return []string{pieceName(piece, "_"), pieceName(piece, "-")}
+}
+
+// LetterNaming names pieces by their FEN letter, like K.png and k.png.
+func LetterNaming(piece rules.Piece) []string {
+	return []string{piece.String()}
+}
+
+var PieceNamings = []PieceNaming{ShortNaming, LongNaming, LetterNaming}
+
+// LoadPieces loads a piece set from a folder with a file per piece, SVGs or
+// images. Without a naming, the first of PieceNamings the folder has a white
+// king for is used.
+func LoadPieces(dir string, naming PieceNaming) (Piece, error) {
+	piece, err := LoadPiecesFS(os.DirFS(dir), naming)
+	if err != nil {
+		return piece, fmt.Errorf("can't load pieces from %q: %w", dir, err)
+	}
+	return piece, nil
+}
+
+func LoadPiecesFS(fsys fs.FS, naming PieceNaming) (p Piece, err error) {
+	if naming == nil {
+		naming = detectNaming(fsys)
+	}
+
+	var missing []rules.Piece
+	for piece := rules.WhiteKing; int(piece) < rules.PieceCount; piece++ {
+		found, err := loadPiece(fsys, &p, piece, naming(piece))
+		if err != nil {
+			return p, err
+		}
+		if !found && piece <= rules.BlackPawn {
+			missing = append(missing, piece)
+		}
+	}
+
+	if len(missing) > 0 {
+		return p, &MissingPiecesError{Pieces: missing}
+	}
+	return p, nil
+}
+
+func detectNaming(fsys fs.FS) PieceNaming {
+	for _, naming := range PieceNamings {
+		for _, name := range naming(rules.WhiteKing) {
+			for _, ext := range pieceExtensions {
+				if _, err := fs.Stat(fsys, name+ext); err == nil {
+					return naming
+				}
+			}
+		}
+	}
+	return ShortNaming
+}
+
+func loadPiece(fsys fs.FS, p *Piece, piece rules.Piece, names []string) (bool, error) {
+	for _, name := range names {
+		for _, ext := range pieceExtensions {
+			fileName := name + ext
+			var err error
+			if ext == ".svg" {
+				var svg *util.SVG
+				if svg, err = util.ReadSVG(fsys, fileName); err == nil {
+					p.SetSVG(piece, svg)
+				}
+			} else {
+				var img image.Image
+				if img, err = util.ReadImage(fsys, fileName); err == nil {
+					p.Set(piece, img)
+				}
+			}
+
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return false, fmt.Errorf("failed to open piece file %q: %w", fileName, err)
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// LoadSpriteSheet loads a piece set drawn in one image, a grid of columns x
+// rows pieces in the given order of FEN letters.
+func LoadSpriteSheet(filename string, columns, rows int, order string) (Piece, error) {
+	sheet, err := util.OpenImage(filename)
+	if err != nil {
+		return Piece{}, fmt.Errorf("can't load sprite sheet %q: %w", filename, err)
+	}
+	return SpriteSheetPieces(sheet, columns, rows, order)
+}
+
+func SpriteSheetPieces(sheet image.Image, columns, rows int, order string) (Piece, error) {
+	cells, err := spriteCells(columns, rows, order)
+	if err != nil {
+		return Piece{}, err
+	}
+
+	var p Piece
+	bounds := sheet.Bounds()
+	cellSize := image.Pt(bounds.Dx()/columns, bounds.Dy()/rows)
+	for piece, cell := range cells {
+		origin := bounds.Min.Add(image.Pt(cell.X*cellSize.X, cell.Y*cellSize.Y))
+		p.Set(piece, crop(sheet, image.Rectangle{Min: origin, Max: origin.Add(cellSize)}))
+	}
+	return p, nil
+}
+
+// LoadSVGSheet loads a piece set drawn in one SVG, laid out like a sprite
+// sheet. The pieces share the parsed image and are rasterized one by one.
+func LoadSVGSheet(filename string, columns, rows int, order string) (Piece, error) {
+	svg, err := util.OpenSVG(filename)
+	if err != nil {
+		return Piece{}, fmt.Errorf("can't load SVG sheet %q: %w", filename, err)
+	}
+	return SVGSheetPieces(svg, columns, rows, order)
+}
+
+func SVGSheetPieces(sheet *util.SVG, columns, rows int, order string) (Piece, error) {
+	cells, err := spriteCells(columns, rows, order)
+	if err != nil {
+		return Piece{}, err
+	}
+
+	var p Piece
+	for piece, cell := range cells {
+		p.SetSVG(piece, sheet.Cell(cell.X, cell.Y, columns, rows))
+	}
+	return p, nil
+}
+
+// spriteCells places the pieces of a sprite order on the grid, row by row.
+func spriteCells(columns, rows int, order string) (map[rules.Piece]image.Point, error) {
+	if columns < 1 || rows < 1 {
+		return nil, fmt.Errorf("invalid sprite grid %dx%d", columns, rows)
+	}
+	if len(order) > columns*rows {
+		return nil, fmt.Errorf("sprite order %q doesn't fit a %dx%d grid", order, columns, rows)
+	}
+
+	cells := make(map[rules.Piece]image.Point)
+	for i := 0; i < len(order); i++ {
+		if order[i] == '.' {
+			continue
+		}
+		piece := rules.PieceFromLetter(order[i])
+		if piece == rules.NoPiece {
+			return nil, fmt.Errorf("sprite order %q has unknown piece %q", order, order[i])
+		}
+		cells[piece] = image.Pt(i%columns, i/columns)
+	}
+
+	var missing []rules.Piece
+	for piece := rules.WhiteKing; piece <= rules.BlackPawn; piece++ {
+		if _, ok := cells[piece]; !ok {
+			missing = append(missing, piece)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, &MissingPiecesError{Pieces: missing}
+	}
+	return cells, nil
+}
+
+// crop copies a cell of a sheet into an image of its own, with its origin at
+// zero like the images loaded from files.
+func crop(img image.Image, rect image.Rectangle) image.Image {
+	dst := image.NewRGBA(image.Rectangle{Max: rect.Size()})
+	draw.Draw(dst, dst.Bounds(), img, rect.Min, draw.Src)
+	return dst
+}
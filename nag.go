@@ -0,0 +1,165 @@
+package chessboard
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type NAG int8
+
+const (
+	NoNAG NAG = iota
+	GoodMove
+	Mistake
+	BrilliantMove
+	Blunder
+	InterestingMove
+	DubiousMove
+)
+
+var nagSymbols = map[NAG]string{
+	GoodMove:        "!",
+	Mistake:         "?",
+	BrilliantMove:   "!!",
+	Blunder:         "??",
+	InterestingMove: "!?",
+	DubiousMove:     "?!",
+}
+
+func ParseNAG(s string) (NAG, error) {
+	if strings.HasPrefix(s, "$") {
+		n, err := strconv.Atoi(s[1:])
+		if err != nil || n < 0 {
+			return NoNAG, fmt.Errorf("invalid NAG %q", s)
+		}
+		if n > int(DubiousMove) {
+			return NoNAG, fmt.Errorf("unsupported NAG %q", s)
+		}
+		return NAG(n), nil
+	}
+
+	for nag, symbol := range nagSymbols {
+		if s == symbol {
+			return nag, nil
+		}
+	}
+	return NoNAG, fmt.Errorf("invalid NAG %q", s)
+}
+
+func (n NAG) String() string {
+	return fmt.Sprintf("$%d", n)
+}
+
+func (n NAG) Symbol() string {
+	return nagSymbols[n]
+}
+
+func (n NAG) Color() color.NRGBA {
+	switch n {
+	case BrilliantMove:
+		return color.NRGBA{R: 0x26, G: 0xC2, B: 0xA3, A: 0xFF}
+	case GoodMove:
+		return color.NRGBA{R: 0x5C, G: 0x8B, B: 0xB0, A: 0xFF}
+	case InterestingMove:
+		return color.NRGBA{R: 0xEA, G: 0x45, B: 0xD8, A: 0xFF}
+	case DubiousMove:
+		return color.NRGBA{R: 0x56, G: 0xB4, B: 0xE9, A: 0xFF}
+	case Mistake:
+		return color.NRGBA{R: 0xE6, G: 0x9F, B: 0x00, A: 0xFF}
+	case Blunder:
+		return color.NRGBA{R: 0xDF, G: 0x53, B: 0x53, A: 0xFF}
+	default:
+		return color.NRGBA{}
+	}
+}
+
+// MoveNAGs returns the move assessment of every ply in the PGN main line,
+// taken either from a $1-$6 NAG or from a symbol suffix like "e4!?".
+// notnil/chess drops both while decoding, so the move text is scanned here.
+func MoveNAGs(pgn string) ([]NAG, error) {
+	var nags []NAG
+	for _, token := range mainLineTokens(pgn) {
+		if strings.HasPrefix(token, "$") {
+			if len(nags) == 0 {
+				return nil, fmt.Errorf("NAG %q before the first move", token)
+			}
+			if nag, err := ParseNAG(token); err == nil && nags[len(nags)-1] == NoNAG {
+				nags[len(nags)-1] = nag
+			}
+			continue
+		}
+
+		switch token {
+		case "*", "1-0", "0-1", "1/2-1/2":
+			continue
+		}
+
+		move := strings.TrimLeftFunc(token, func(r rune) bool {
+			return unicode.IsDigit(r) || r == '.'
+		})
+		if move == "" {
+			continue
+		}
+
+		suffix := strings.TrimLeft(move, "abcdefghKQRBNPOx0-12345678=+#")
+		nag, _ := ParseNAG(suffix)
+		nags = append(nags, nag)
+	}
+	return nags, nil
+}
+
+func mainLineTokens(pgn string) []string {
+	var moveText strings.Builder
+	for _, line := range strings.Split(pgn, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "[") {
+			moveText.WriteString(line)
+			moveText.WriteString("\n")
+		}
+	}
+
+	var (
+		tokens    []string
+		token     strings.Builder
+		depth     int
+		inComment bool
+		inLine    bool
+	)
+	flush := func() {
+		if token.Len() > 0 {
+			tokens = append(tokens, token.String())
+			token.Reset()
+		}
+	}
+	for _, r := range moveText.String() {
+		switch {
+		case inComment:
+			inComment = r != '}'
+		case inLine:
+			inLine = r != '\n'
+		case r == '{':
+			flush()
+			inComment = true
+		case r == ';':
+			flush()
+			inLine = true
+		case r == '(':
+			flush()
+			depth++
+		case r == ')':
+			depth--
+		case depth > 0:
+		case unicode.IsSpace(r):
+			flush()
+		case r == '$':
+			flush()
+			token.WriteRune(r)
+		default:
+			token.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
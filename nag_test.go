@@ -0,0 +1,70 @@
+package chessboard
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseNAG(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    NAG
+		wantErr bool
+	}{
+		{"$0", NoNAG, false},
+		{"$1", GoodMove, false},
+		{"$4", Blunder, false},
+		{"$6", DubiousMove, false},
+		{"!", GoodMove, false},
+		{"?", Mistake, false},
+		{"!!", BrilliantMove, false},
+		{"??", Blunder, false},
+		{"!?", InterestingMove, false},
+		{"?!", DubiousMove, false},
+		{"$7", NoNAG, true},
+		{"$-1", NoNAG, true},
+		{"$x", NoNAG, true},
+		{"!!!", NoNAG, true},
+		{"", NoNAG, true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseNAG(test.s)
+		if got != test.want || (err != nil) != test.wantErr {
+			t.Errorf("ParseNAG(%q) = %s, %v, want %s, error %t", test.s, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestMoveNAGs(t *testing.T) {
+	tests := []struct {
+		name    string
+		pgn     string
+		want    []NAG
+		wantErr bool
+	}{
+		{"suffixes", "1. e4! e5?! 2. Nf3!! Nc6?? *",
+			[]NAG{GoodMove, DubiousMove, BrilliantMove, Blunder}, false},
+		{"numeric", "1. e4 $1 e5 $6 2. Nf3 Nc6 $4 1-0",
+			[]NAG{GoodMove, DubiousMove, NoNAG, Blunder}, false},
+		{"suffix wins over a NAG", "1. e4! $2 *", []NAG{GoodMove}, false},
+		{"no spaces", "1.e4!? e5 2.O-O-O? e8=Q+! *",
+			[]NAG{InterestingMove, NoNAG, Mistake, GoodMove}, false},
+		{"tags, comments and variations", `[Event "Is this good?!"]
+
+1. e4 { why not? } (1. d4 $2 d5!) e5 ; the rest!
+2. Qh5?? 1/2-1/2`, []NAG{NoNAG, NoNAG, Blunder}, false},
+		{"NAG first", "$1 1. e4 *", nil, true},
+	}
+
+	for _, test := range tests {
+		got, err := MoveNAGs(test.pgn)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: error %v, want error %t", test.name, err, test.wantErr)
+			continue
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("%s: got %s, want %s", test.name, got, test.want)
+		}
+	}
+}
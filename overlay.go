@@ -0,0 +1,54 @@
+package chessboard
+
+import (
+	"image"
+
+	"gioui.org/layout"
+	"github.com/failosof/chessboard/rules"
+	"github.com/failosof/chessboard/union"
+)
+
+type Layer int8
+
+const (
+	AboveBoardLayer Layer = iota
+	BelowPiecesLayer
+	AbovePiecesLayer
+	AboveAnnotationsLayer
+)
+
+func (l Layer) String() string {
+	switch l {
+	case AboveBoardLayer:
+		return "above board"
+	case BelowPiecesLayer:
+		return "below pieces"
+	case AbovePiecesLayer:
+		return "above pieces"
+	case AboveAnnotationsLayer:
+		return "above annotations"
+	default:
+		return "unknown"
+	}
+}
+
+// BoardState is what overlays draw with. SquareOrigins and Pieces are
+// indexed by square and account for the orientation, they're copies the
+// overlay may keep. Redraw is set when the board was resized, flipped or the
+// position changed, so overlays can rebuild their cached ops like the widget
+// does.
+type BoardState struct {
+	SquareOrigins []union.Point
+	SquareSize    union.Size
+	BoardSize     image.Point
+	Flipped       bool
+	Pieces        []rules.Piece
+	Rules         rules.Rules
+	Redraw        bool
+}
+
+// Overlay draws custom content into the board at every layer. Layout is called
+// while the widget is locked, so it must not call back into the widget.
+type Overlay interface {
+	Layout(gtx layout.Context, layer Layer, state BoardState)
+}
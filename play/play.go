@@ -0,0 +1,413 @@
+// Package play plays a game against a local engine on a board widget, with
+// difficulty levels, the clock, takebacks, resignation and draw offers.
+package play
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/failosof/chessboard"
+	"github.com/failosof/chessboard/engine"
+	"github.com/failosof/chessboard/rules"
+)
+
+// Level limits how well and how long the engine thinks. Elo limits strength
+// through UCI_Elo, when it's zero Skill is used as the Skill Level, from 0 to
+// 20. Depth and MoveTime limit each search, zero doesn't limit.
+type Level struct {
+	Skill    int
+	Elo      int
+	Depth    int
+	MoveTime time.Duration
+}
+
+// Levels go from a beginner to full strength.
+var Levels = []Level{
+	{Skill: 0, Depth: 1, MoveTime: 50 * time.Millisecond},
+	{Skill: 3, Depth: 2, MoveTime: 100 * time.Millisecond},
+	{Skill: 6, Depth: 3, MoveTime: 150 * time.Millisecond},
+	{Skill: 9, Depth: 5, MoveTime: 200 * time.Millisecond},
+	{Skill: 11, Depth: 8, MoveTime: 300 * time.Millisecond},
+	{Skill: 14, Depth: 13, MoveTime: 400 * time.Millisecond},
+	{Skill: 17, Depth: 18, MoveTime: 500 * time.Millisecond},
+	{Skill: 20, Depth: 22, MoveTime: time.Second},
+}
+
+// DrawMargin is how many centipawns the engine wants to be ahead by to
+// decline a draw.
+const DrawMargin = 50
+
+// Game plays the engine on a widget. The app hands it the board events, the
+// engine answers the moves made on the board from its own goroutine.
+type Game struct {
+	// OnUpdate is called from the engine's goroutine after it moved, to have
+	// the window redrawn. The game isn't locked then.
+	OnUpdate func()
+
+	engine engine.Engine
+	board  *chessboard.Widget
+	clock  *chessboard.Clock
+
+	human    rules.Color
+	level    Level
+	start    string
+	chess960 bool // castling goes king onto rook in the engine's moves too
+	moves    []rules.Move
+	game     *rules.Game  // the game as played, for reading the engine's moves
+	score    engine.Score // the engine's last evaluation, for draw offers
+	search   int          // counts searches so a stale one drops its move
+	cancel   context.CancelFunc
+
+	mu sync.Mutex
+}
+
+// NewGame plays on the board, the clock may be nil. The engine stays the
+// caller's to close.
+func NewGame(e engine.Engine, board *chessboard.Widget, clock *chessboard.Clock) *Game {
+	return &Game{engine: e, board: board, clock: clock}
+}
+
+// Start sets up a new game from the FEN, or from the starting position when
+// it's empty. The board is flipped when the human plays Black, and the engine
+// starts thinking when it's its move.
+func (g *Game) Start(fen string, human rules.Color, level Level) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.stop()
+	if fen == "" {
+		fen = rules.Standard.StartingFEN()
+	}
+	if err := g.engine.NewGame(); err != nil {
+		return fmt.Errorf("can't start engine game: %w", err)
+	}
+	if err := setStrength(g.engine, level); err != nil {
+		return err
+	}
+	chess960 := isChess960(fen)
+	if err := g.engine.SetOption("UCI_Chess960", strconv.FormatBool(chess960)); err != nil {
+		return fmt.Errorf("can't set engine variant: %w", err)
+	}
+
+	g.human, g.level, g.start, g.chess960, g.moves = human, level, fen, chess960, nil
+	g.score = engine.Score{}
+	if err := g.rebuild(); err != nil {
+		return err
+	}
+	g.board.SetFlipped(human == rules.Black)
+	g.board.SetPlayer(human)
+
+	if g.game.Turn() != human {
+		g.think()
+	}
+	return nil
+}
+
+func (g *Game) Human() rules.Color {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.human
+}
+
+// Thinking tells whether the engine is searching its move.
+func (g *Game) Thinking() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.cancel != nil
+}
+
+// HandleEvent answers the moves made on the board.
+func (g *Game) HandleEvent(e chessboard.Event) {
+	if e.Type != chessboard.MoveEvent {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.game == nil || g.over() {
+		return
+	}
+
+	if g.game.Turn() != g.human || g.game.Move(e.Move) != nil {
+		slog.Error("can't play move against engine", "move", e.Move)
+		if err := g.rebuild(); err != nil {
+			slog.Error("can't set up game against engine", "err", err)
+		}
+		return
+	}
+	g.moves = append(g.moves, e.Move)
+	g.played(g.human)
+}
+
+// Takeback takes back the last move of the human and the engine's answer,
+// or stops the engine when it's still thinking about it. Finished games
+// stay finished.
+func (g *Game) Takeback() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.game == nil {
+		return nil
+	}
+	if g.over() {
+		return rules.ErrGameOver
+	}
+	g.stop()
+
+	plies := 2
+	if g.game.Turn() != g.human {
+		plies = 1
+	}
+	if len(g.moves) < plies {
+		return rules.ErrNothingToTakeback
+	}
+	g.moves = g.moves[:len(g.moves)-plies]
+	if err := g.rebuild(); err != nil {
+		return err
+	}
+
+	if g.clock != nil && g.clock.Running() != rules.NoColor && g.clock.Running() != g.human {
+		g.clock.Press(g.human.Other(), time.Now())
+	}
+	return nil
+}
+
+func (g *Game) Resign() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.game == nil || g.over() {
+		return nil
+	}
+	g.stop()
+	g.game.Resign(g.human)
+	g.stopClock()
+	return g.board.Resign(g.human)
+}
+
+// OfferDraw ends the game in a draw when the engine doesn't think it's
+// ahead, and tells whether it did.
+func (g *Game) OfferDraw() (bool, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.game == nil || g.over() || len(g.moves) < 2 || g.score.Centipawns() >= DrawMargin {
+		return false, nil
+	}
+	g.stop()
+	g.game.AgreeDraw()
+	g.stopClock()
+	return true, g.board.AgreeDraw()
+}
+
+// CheckClock ends the game when a flag fell, the app calls it every frame.
+func (g *Game) CheckClock(now time.Time) {
+	if g.clock == nil {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.game == nil || g.over() {
+		return
+	}
+	if flagged := g.clock.Flagged(now); flagged != rules.NoColor {
+		g.stop()
+		g.game.Timeout(flagged)
+		g.clock.Stop(now)
+		g.board.Timeout(flagged)
+	}
+}
+
+// Close stops the engine's search.
+func (g *Game) Close() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.stop()
+}
+
+// played presses the clock after a move and has the engine answer the
+// human's.
+func (g *Game) played(color rules.Color) {
+	if g.over() {
+		g.stopClock()
+		return
+	}
+	if g.clock != nil {
+		g.clock.Press(color, time.Now())
+	}
+	if color == g.human {
+		g.think()
+	}
+}
+
+func (g *Game) think() {
+	ctx, cancel := context.WithCancel(context.Background())
+	g.search++
+	g.cancel = cancel
+	search := g.search
+
+	moves := make([]string, 0, len(g.moves))
+	position, err := rules.NewGameFromFEN(g.start)
+	if err != nil {
+		cancel()
+		g.cancel = nil
+		slog.Error("can't set up engine position", "err", err)
+		return
+	}
+	for _, move := range g.moves {
+		moves = append(moves, rules.UCI(position, move, g.chess960))
+		position.Move(move)
+	}
+	limits := g.limits()
+
+	go func() {
+		defer cancel()
+
+		err := g.engine.SetPosition(g.start, moves)
+		var result engine.Result
+		if err == nil {
+			result, err = g.engine.Go(ctx, limits, nil)
+		}
+
+		g.mu.Lock()
+		if search != g.search {
+			g.mu.Unlock()
+			return
+		}
+		g.cancel = nil
+		if err := g.answer(result, err); err != nil {
+			slog.Error("can't play engine move", "move", result.BestMove, "err", err)
+		}
+		g.mu.Unlock()
+
+		// unlocked, so the app may call back into the game
+		if g.OnUpdate != nil {
+			g.OnUpdate()
+		}
+	}()
+}
+
+func (g *Game) answer(result engine.Result, err error) error {
+	color := g.human.Other()
+	if errors.Is(err, engine.ErrResigned) {
+		g.game.Resign(color)
+		g.stopClock()
+		return g.board.Resign(color)
+	}
+	if err != nil {
+		return err
+	}
+
+	move, err := rules.ParseUCI(g.game, result.BestMove)
+	if err != nil {
+		return err
+	}
+	if err := g.game.Move(move); err != nil {
+		return err
+	}
+	if err := g.board.Play(move); err != nil {
+		return err
+	}
+	g.moves = append(g.moves, move)
+	g.score = result.Info.Score
+	g.played(color)
+	return nil
+}
+
+// limits are the level's, with the clock's times so the engine doesn't
+// lose on time.
+func (g *Game) limits() engine.Limits {
+	limits := engine.Limits{Depth: g.level.Depth, MoveTime: g.level.MoveTime}
+	if g.clock == nil {
+		return limits
+	}
+
+	now := time.Now()
+	limits.WhiteTime = g.clock.Remaining(rules.White, now)
+	limits.BlackTime = g.clock.Remaining(rules.Black, now)
+	if own := g.clock.Remaining(g.human.Other(), now); limits.MoveTime == 0 || limits.MoveTime > own/20 {
+		limits.MoveTime = max(own/20, 10*time.Millisecond)
+	}
+	return limits
+}
+
+// stop drops the engine's search.
+func (g *Game) stop() {
+	g.search++
+	if g.cancel != nil {
+		g.cancel()
+		g.cancel = nil
+	}
+}
+
+func (g *Game) stopClock() {
+	if g.clock != nil {
+		g.clock.Stop(time.Now())
+	}
+}
+
+func (g *Game) over() bool {
+	outcome, _ := g.game.Outcome()
+	return outcome != rules.NoOutcome
+}
+
+// rebuild sets the board to the game's moves.
+func (g *Game) rebuild() error {
+	game, err := replay(g.start, g.moves)
+	if err != nil {
+		return err
+	}
+	board, err := replay(g.start, g.moves)
+	if err != nil {
+		return err
+	}
+	g.game = game
+	g.board.SetRules(board)
+	return nil
+}
+
+func replay(fen string, moves []rules.Move) (*rules.Game, error) {
+	game, err := rules.NewGameFromFEN(fen)
+	if err != nil {
+		return nil, fmt.Errorf("can't set up %s: %w", fen, err)
+	}
+	for _, move := range moves {
+		if err := game.Move(move); err != nil {
+			return nil, fmt.Errorf("can't replay %s: %w", move, err)
+		}
+	}
+	return game, nil
+}
+
+// isChess960 tells a Chess960 start other than the standard one, the engine
+// can't tell them apart by itself.
+func isChess960(fen string) bool {
+	position, err := rules.ParseFEN(fen)
+	if err != nil {
+		return false
+	}
+	index := rules.Chess960Index(position)
+	return index >= 0 && index != standardIndex
+}
+
+// standardIndex is the standard setup's Scharnagl number.
+const standardIndex = 518
+
+func setStrength(e engine.Engine, level Level) error {
+	options := [][2]string{{"UCI_LimitStrength", "false"}, {"Skill Level", strconv.Itoa(level.Skill)}}
+	if level.Elo > 0 {
+		options = [][2]string{{"UCI_LimitStrength", "true"}, {"UCI_Elo", strconv.Itoa(level.Elo)}}
+	}
+	for _, option := range options {
+		if err := e.SetOption(option[0], option[1]); err != nil {
+			return fmt.Errorf("can't set engine strength: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package play
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gioui.org/widget/material"
+	"github.com/failosof/chessboard"
+	"github.com/failosof/chessboard/engine"
+	"github.com/failosof/chessboard/engine/enginetest"
+	"github.com/failosof/chessboard/rules"
+)
+
+// newFakeEngine answers every search with the move.
+func newFakeEngine(t *testing.T, bestmove string) (engine.Engine, *enginetest.Engine) {
+	t.Helper()
+
+	fake := enginetest.New(enginetest.Replies(
+		[]string{"uci", "id name Fake", "uciok"},
+		[]string{"isready", "readyok"},
+		[]string{"go", "bestmove " + bestmove},
+	))
+	t.Cleanup(func() { fake.Close() })
+	e, err := engine.NewUCI(fake.Stdout, fake.Stdin)
+	if err != nil {
+		t.Fatalf("NewUCI: %v", err)
+	}
+	return e, fake
+}
+
+func newTestGame(t *testing.T) (*Game, *chessboard.Widget) {
+	t.Helper()
+
+	e, _ := newFakeEngine(t, "e7e5")
+	board := chessboard.NewWidget(material.NewTheme(), chessboard.Config{})
+	g := NewGame(e, board, nil)
+	t.Cleanup(g.Close)
+	if err := g.Start("", rules.White, Levels[0]); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	// e2e4 and the engine's answer
+	move := rules.Move{From: rules.NewSquare(4, 1), To: rules.NewSquare(4, 3)}
+	if err := board.Rules().Move(move); err != nil {
+		t.Fatalf("can't play e2e4: %v", err)
+	}
+	g.HandleEvent(chessboard.Event{Type: chessboard.MoveEvent, Move: move})
+	deadline := time.Now().Add(5 * time.Second)
+	for g.Thinking() {
+		if time.Now().After(deadline) {
+			t.Fatal("the engine didn't answer")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return g, board
+}
+
+func placement(board *chessboard.Widget) string {
+	placement, _, _ := strings.Cut(board.FEN(), " ")
+	return placement
+}
+
+func TestTakeback(t *testing.T) {
+	g, board := newTestGame(t)
+	if got, want := placement(board), "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR"; got != want {
+		t.Fatalf("board %s, want %s", got, want)
+	}
+
+	if err := g.Takeback(); err != nil {
+		t.Fatalf("Takeback: %v", err)
+	}
+	if got, want := placement(board), "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR"; got != want {
+		t.Errorf("board %s after the takeback, want %s", got, want)
+	}
+	if err := g.Takeback(); !errors.Is(err, rules.ErrNothingToTakeback) {
+		t.Errorf("Takeback at the start = %v, want %v", err, rules.ErrNothingToTakeback)
+	}
+}
+
+func TestTakebackAfterTheEnd(t *testing.T) {
+	g, board := newTestGame(t)
+	if err := g.Resign(); err != nil {
+		t.Fatalf("Resign: %v", err)
+	}
+
+	if err := g.Takeback(); !errors.Is(err, rules.ErrGameOver) {
+		t.Errorf("Takeback after resigning = %v, want %v", err, rules.ErrGameOver)
+	}
+	if outcome, method := board.Outcome(); outcome != rules.BlackWon || method != rules.Resignation {
+		t.Errorf("outcome %s by %s, want 0-1 by resignation", outcome, method)
+	}
+}
+
+func TestOnUpdate(t *testing.T) {
+	e, _ := newFakeEngine(t, "e2e4")
+	board := chessboard.NewWidget(material.NewTheme(), chessboard.Config{})
+	g := NewGame(e, board, nil)
+	t.Cleanup(g.Close)
+
+	// the app may ask the game about itself when it's told to redraw
+	updated := make(chan rules.Color, 1)
+	g.OnUpdate = func() {
+		g.Thinking()
+		updated <- g.Human()
+	}
+	if err := g.Start("", rules.Black, Levels[0]); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	select {
+	case human := <-updated:
+		if human != rules.Black {
+			t.Errorf("Human() = %s, want black", human)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnUpdate wasn't called, or blocked")
+	}
+	if got, want := placement(board), "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR"; got != want {
+		t.Errorf("board %s, want %s", got, want)
+	}
+}
+
+func TestChess960(t *testing.T) {
+	tests := []struct {
+		fen  string
+		want string
+	}{
+		{"", "setoption name UCI_Chess960 value false"},
+		{"bqnbrkrn/pppppppp/8/8/8/8/PPPPPPPP/BQNBRKRN w GEge - 0 1", "setoption name UCI_Chess960 value true"},
+	}
+
+	for _, test := range tests {
+		e, fake := newFakeEngine(t, "a2a3")
+		board := chessboard.NewWidget(material.NewTheme(), chessboard.Config{})
+		g := NewGame(e, board, nil)
+		if err := g.Start(test.fen, rules.White, Levels[0]); err != nil {
+			t.Fatalf("Start(%q): %v", test.fen, err)
+		}
+		g.Close()
+
+		if commands := fake.Commands(); !slices.Contains(commands, test.want) {
+			t.Errorf("Start(%q) sent %q, want %q", test.fen, commands, test.want)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package chessboard
+
+import (
+	"cmp"
+	"image"
+	"image/color"
+	"log/slog"
+	"strconv"
+
+	"gioui.org/f32"
+	"gioui.org/font"
+	"gioui.org/io/event"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/text"
+	"gioui.org/widget/material"
+	"github.com/failosof/chessboard/rules"
+	"github.com/failosof/chessboard/util"
+)
+
+var pocketTypes = []rules.PieceType{rules.Queen, rules.Rook, rules.Bishop, rules.Knight, rules.Pawn}
+
+// PocketsStyle leaves a strip for the pieces in hand above and below the
+// board, one square high. Inset is the room the board keeps around its
+// squares, like the one taken by outside coordinates. Files and Ranks default
+// to 8.
+type PocketsStyle struct {
+	Inset int
+	Files int
+	Ranks int
+	Board layout.Widget
+}
+
+func (s PocketsStyle) Layout(gtx layout.Context) layout.Dimensions {
+	files, ranks := cmp.Or(s.Files, 8), cmp.Or(s.Ranks, 8)
+	square := min((gtx.Constraints.Max.X-2*s.Inset)/files, (gtx.Constraints.Max.Y-2*s.Inset)/(ranks+2))
+	board := image.Pt(square*files+2*s.Inset, square*ranks+2*s.Inset)
+
+	stack := op.Offset(image.Pt(0, square)).Push(gtx.Ops)
+	gtx.Constraints = layout.Exact(board)
+	s.Board(gtx)
+	stack.Pop()
+
+	return layout.Dimensions{Size: image.Pt(board.X, board.Y+2*square)}
+}
+
+type pocketSlot struct {
+	piece rules.Piece
+}
+
+func (w *Widget) dropper() (rules.Dropper, bool) {
+	dropper, ok := w.game.(rules.Dropper)
+	return dropper, ok && dropper.Drops()
+}
+
+// layoutPockets draws the pockets outside the board, in board coordinates, so
+// pieces dragged out of them land on the same squares as any other piece.
+func (w *Widget) layoutPockets(gtx layout.Context) {
+	dropper, ok := w.dropper()
+	if !ok {
+		return
+	}
+
+	top, bottom := rules.Black, rules.White
+	if w.flipped {
+		top, bottom = bottom, top
+	}
+
+	w.pocketEventTargets = w.pocketEventTargets[:0]
+	for _, color := range []rules.Color{top, bottom} {
+		y := -w.pocketInset - w.squareSize.Int
+		if color == bottom {
+			y = w.curBoardSize.Y + w.pocketInset
+		}
+
+		pocket := dropper.Pocket(color)
+		for i, t := range pocketTypes {
+			count := pocket.Count(t)
+			if count == 0 {
+				continue
+			}
+
+			piece := rules.NewPiece(t, color)
+			slot := util.Rect(image.Pt(i*w.squareSize.Int, y), w.squareSize.Pt)
+			if !(w.dropping && w.selectedPiece == piece) || count > 1 {
+				w.config.Piece.Draw(gtx.Ops, piece, slot.Min, w.squareSize)
+			}
+			w.drawBadge(gtx, slot, strconv.Itoa(count), w.config.Color.Pocket)
+
+			tag := pocketSlot{piece: piece}
+			area := clip.Rect(slot).Push(gtx.Ops)
+			event.Op(gtx.Ops, tag)
+			area.Pop()
+			w.pocketEventTargets = append(w.pocketEventTargets, pointer.Filter{
+				Target: tag,
+				Kinds:  pointer.Move | pointer.Press | pointer.Drag | pointer.Release,
+			})
+		}
+	}
+
+	for {
+		ev, ok := gtx.Event(w.pocketEventTargets...)
+		if !ok {
+			break
+		}
+
+		e, ok := ev.(pointer.Event)
+		if !ok {
+			continue
+		}
+
+		switch e.Kind {
+		case pointer.Move:
+			pointer.CursorGrab.Add(gtx.Ops)
+		case pointer.Press:
+			if e.Buttons == pointer.ButtonPrimary {
+				w.processPocketPress(gtx, e)
+			}
+		case pointer.Drag:
+			if w.dropping && w.dragID == e.PointerID {
+				pointer.CursorGrabbing.Add(gtx.Ops)
+				w.draggingPos = w.dragOrigin(e.Position)
+				gtx.Execute(op.InvalidateCmd{})
+			}
+		case pointer.Release:
+			if w.dropping {
+				square := util.PointToSquare(e.Position, w.squareSize.Float, w.files, w.ranks, w.flipped)
+				if square != rules.NoSquare {
+					w.drop(gtx, square)
+				}
+			}
+		}
+	}
+}
+
+func (w *Widget) processPocketPress(gtx layout.Context, e pointer.Event) {
+	square := util.PointToSquare(e.Position, w.squareSize.Float, w.files, w.ranks, w.flipped)
+	if square != rules.NoSquare || (w.config.ShowGameOver && w.gameOverShown()) {
+		return
+	}
+
+	slot, ok := w.pocketSlotAt(e.Position)
+	if !ok || !w.canMove(slot.piece.Color()) {
+		return
+	}
+
+	w.unselectPiece(gtx)
+	w.dropping = true
+	w.selectedPiece = slot.piece
+	w.dragID = e.PointerID
+	w.draggingPos = w.dragOrigin(e.Position)
+	pointer.CursorGrabbing.Add(gtx.Ops)
+	gtx.Execute(pointer.GrabCmd{Tag: slot, ID: e.PointerID})
+	gtx.Execute(op.InvalidateCmd{})
+}
+
+// pocketSlotAt finds the pocket piece under a point, since pocket events
+// don't tell which slot they came from.
+func (w *Widget) pocketSlotAt(pos f32.Point) (pocketSlot, bool) {
+	i := util.Floor(pos.X / w.squareSize.Float)
+	if i < 0 || i >= len(pocketTypes) {
+		return pocketSlot{}, false
+	}
+
+	top, bottom := rules.Black, rules.White
+	if w.flipped {
+		top, bottom = bottom, top
+	}
+
+	color := rules.NoColor
+	switch y := util.Round(pos.Y); {
+	case y < 0:
+		color = top
+	case y >= w.curBoardSize.Y:
+		color = bottom
+	}
+	if color == rules.NoColor {
+		return pocketSlot{}, false
+	}
+
+	dropper, ok := w.dropper()
+	if !ok || dropper.Pocket(color).Count(pocketTypes[i]) == 0 {
+		return pocketSlot{}, false
+	}
+	return pocketSlot{piece: rules.NewPiece(pocketTypes[i], color)}, true
+}
+
+func (w *Widget) drop(gtx layout.Context, square rules.Square) {
+	dropper, ok := w.dropper()
+	if !ok {
+		return
+	}
+
+	for _, move := range dropper.LegalDrops(w.selectedPiece) {
+		if move.To == square {
+			if err := w.game.Move(move); err != nil {
+				slog.Error("can't make drop", "err", err)
+				break
+			}
+			w.emit(MoveEvent, move)
+			break
+		}
+	}
+	w.unselectPiece(gtx)
+}
+
+// drawBadge puts a small round label into the corner of a square.
+func (w *Widget) drawBadge(gtx layout.Context, slot image.Rectangle, value string, fill color.NRGBA) {
+	diameter := util.Round(w.squareSize.Float * 0.35)
+	badge := util.Rect(slot.Max.Sub(image.Pt(diameter, diameter)), image.Pt(diameter, diameter))
+	util.DrawEllipse(gtx.Ops, badge, fill)
+
+	gtx.Constraints = layout.Exact(badge.Size())
+	defer op.Offset(badge.Min).Push(gtx.Ops).Pop()
+	layout.Center.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		gtx.Constraints.Min = image.Point{}
+		label := material.Label(w.th, gtx.Metric.PxToSp(diameter*3/5), value)
+		label.Color = util.WhiteColor
+		label.Font.Weight = font.Bold
+		label.Alignment = text.Middle
+		label.MaxLines = 1
+		return label.Layout(gtx)
+	})
+}
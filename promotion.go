@@ -8,23 +8,23 @@ import (
 	"gioui.org/io/pointer"
 	"gioui.org/layout"
 	"gioui.org/op/clip"
+	"github.com/failosof/chessboard/rules"
 	"github.com/failosof/chessboard/union"
 	"github.com/failosof/chessboard/util"
-	"github.com/notnil/chess"
 )
 
 var (
-	whiteCandidates = []chess.Piece{chess.WhiteQueen, chess.WhiteRook, chess.WhiteBishop, chess.WhiteKnight}
-	blackCandidates = []chess.Piece{chess.BlackPawn, chess.BlackRook, chess.BlackBishop, chess.BlackKnight}
+	whiteCandidates = []rules.Piece{rules.WhiteQueen, rules.WhiteRook, rules.WhiteBishop, rules.WhiteKnight}
+	blackCandidates = []rules.Piece{rules.BlackQueen, rules.BlackRook, rules.BlackBishop, rules.BlackKnight}
 )
 
 type Promotion struct {
 	Position         union.Point
 	SquareSize       union.Size
-	Color            chess.Color
+	Color            rules.Color
 	Background       color.NRGBA
 	Piece            Piece
-	HoveredCandidate chess.Piece
+	HoveredCandidate rules.Piece
 	Flipped          bool
 }
 
@@ -36,16 +36,15 @@ func (p Promotion) Layout(gtx layout.Context) layout.Dimensions {
 	util.DrawPane(gtx.Ops, selection, p.Background)
 
 	candidates := whiteCandidates
-	if p.Color == chess.Black {
+	if p.Color == rules.Black {
 		candidates = blackCandidates
 	}
 
 	piecePos := p.Position.Pt
 	pieceEventTargets := make([]event.Filter, len(candidates))
 	for i, piece := range candidates {
-		factor := p.SquareSize.F32.Div(p.Piece.Sizes[piece].Float)
-		util.DrawImage(gtx.Ops, p.Piece.Images[piece], piecePos, factor)
-		pieceClip := clip.Rect(image.Rectangle{Min: piecePos, Max: piecePos.Add(factor.Round())}).Push(gtx.Ops)
+		p.Piece.Draw(gtx.Ops, piece, piecePos, p.SquareSize)
+		pieceClip := clip.Rect(util.Rect(piecePos, p.SquareSize.Pt)).Push(gtx.Ops)
 		event.Op(gtx.Ops, piece)
 		pieceClip.Pop()
 		piecePos.Y += p.SquareSize.Int
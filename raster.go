@@ -0,0 +1,101 @@
+package chessboard
+
+import (
+	"fmt"
+	"image"
+	"slices"
+	"sync"
+
+	"gioui.org/op/paint"
+
+	"github.com/failosof/chessboard/util"
+)
+
+// maxRasterSizes bounds how many square sizes the pieces are kept scaled
+// at, enough to go back and forth between a few window sizes or to show
+// boards of a few sizes at once.
+const maxRasterSizes = 4
+
+type rasterKey struct {
+	source any // *util.SVG or image.Image
+	size   int
+}
+
+// rasterCache keeps every piece image scaled to the square size, as an op
+// that is uploaded to the GPU once. It's shared by all widgets, so boards
+// with the same piece set and size scale each piece once. Images go away
+// when their size wasn't used lately or their set was replaced.
+type rasterCache struct {
+	mu     sync.Mutex
+	sizes  []int // most recently used first
+	images map[rasterKey]paint.ImageOp
+}
+
+var rasters = rasterCache{images: make(map[rasterKey]paint.ImageOp)}
+
+func (c *rasterCache) get(source any, size int) (paint.ImageOp, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := rasterKey{source: source, size: size}
+	imageOp, ok := c.images[key]
+	if !ok {
+		img, err := rasterize(source, size)
+		if err != nil {
+			return paint.ImageOp{}, err
+		}
+		imageOp = paint.NewImageOp(img)
+		c.images[key] = imageOp
+	}
+	c.use(size)
+	return imageOp, nil
+}
+
+// replace drops the images of a piece set that was swapped for another one.
+// Boards that still show the old set scale it again.
+func (c *rasterCache) replace(old, new Piece) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dropped := make(map[any]bool)
+	for _, source := range old.sources() {
+		dropped[source] = true
+	}
+	for _, source := range new.sources() {
+		delete(dropped, source)
+	}
+	for key := range c.images {
+		if dropped[key.source] {
+			delete(c.images, key)
+		}
+	}
+}
+
+func rasterize(source any, size int) (image.Image, error) {
+	switch source := source.(type) {
+	case *util.SVG:
+		return source.Rasterize(size), nil
+	case image.Image:
+		return util.ResizeToFit(source, size), nil
+	default:
+		return nil, fmt.Errorf("can't rasterize %T", source)
+	}
+}
+
+func (c *rasterCache) use(size int) {
+	if i := slices.Index(c.sizes, size); i >= 0 {
+		c.sizes = slices.Delete(c.sizes, i, i+1)
+	}
+	c.sizes = slices.Insert(c.sizes, 0, size)
+	if len(c.sizes) <= maxRasterSizes {
+		return
+	}
+
+	evicted := c.sizes[maxRasterSizes]
+	c.sizes = c.sizes[:maxRasterSizes]
+	for key := range c.images {
+		if key.size == evicted {
+			delete(c.images, key)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package chessboard
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"gioui.org/op/paint"
+	"github.com/failosof/chessboard/rules"
+)
+
+func solidImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, color.White)
+		}
+	}
+	return img
+}
+
+func TestRasterCacheReplace(t *testing.T) {
+	cache := rasterCache{images: make(map[rasterKey]paint.ImageOp)}
+
+	var old, new, shared Piece
+	shared.Set(rules.WhiteKing, solidImage(8, 8))
+	old.Set(rules.WhiteKing, shared.Images[rules.WhiteKing])
+	old.Set(rules.BlackKing, solidImage(8, 8))
+	new.Set(rules.WhiteKing, shared.Images[rules.WhiteKing])
+	new.Set(rules.BlackKing, solidImage(8, 8))
+
+	for _, source := range old.sources() {
+		if _, err := cache.get(source, 16); err != nil {
+			t.Fatalf("get: %v", err)
+		}
+	}
+	cache.replace(old, new)
+
+	if _, ok := cache.images[rasterKey{source: old.Images[rules.WhiteKing], size: 16}]; !ok {
+		t.Error("replace dropped an image both sets share")
+	}
+	if _, ok := cache.images[rasterKey{source: old.Images[rules.BlackKing], size: 16}]; ok {
+		t.Error("replace kept an image of the old set")
+	}
+}
+
+func TestRasterCacheSizes(t *testing.T) {
+	cache := rasterCache{images: make(map[rasterKey]paint.ImageOp)}
+	img := solidImage(8, 8)
+	for size := 10; size < 10+maxRasterSizes+2; size++ {
+		if _, err := cache.get(img, size); err != nil {
+			t.Fatalf("get: %v", err)
+		}
+	}
+	if len(cache.images) != maxRasterSizes {
+		t.Errorf("cache keeps %d sizes, want %d", len(cache.images), maxRasterSizes)
+	}
+}
+
+func TestRasterizeUnknownSource(t *testing.T) {
+	if _, err := rasterize("not an image", 16); err == nil {
+		t.Error("rasterize didn't fail for a string")
+	}
+}
+
+func TestRasterizeKeepsAspectRatio(t *testing.T) {
+	img, err := rasterize(solidImage(20, 10), 40)
+	if err != nil {
+		t.Fatalf("rasterize: %v", err)
+	}
+
+	if got := img.Bounds().Size(); got != image.Pt(40, 40) {
+		t.Fatalf("size = %v, want 40x40", got)
+	}
+	for _, test := range []struct {
+		x, y   int
+		opaque bool
+	}{
+		{20, 20, true},
+		{0, 12, true},
+		{20, 5, false},
+		{20, 34, false},
+	} {
+		if _, _, _, a := img.At(test.x, test.y).RGBA(); (a > 0) != test.opaque {
+			t.Errorf("pixel %d,%d has alpha %d", test.x, test.y, a)
+		}
+	}
+}
@@ -0,0 +1,386 @@
+// Package relay follows a tournament broadcast through the PGN files that a
+// DGT relay keeps rewriting, and moves the boards showing its games along.
+package relay
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/failosof/chessboard/rules"
+	"github.com/notnil/chess"
+)
+
+// Key identifies a game of the broadcast by its tags. Empty fields of a
+// followed key match any value, so a board can follow Key{Board: "1"}.
+type Key struct {
+	White string
+	Black string
+	Round string
+	Board string
+}
+
+func KeyOf(game *chess.Game) Key {
+	tag := func(key string) string {
+		if pair := game.GetTagPair(key); pair != nil {
+			return pair.Value
+		}
+		return ""
+	}
+	return Key{White: tag("White"), Black: tag("Black"), Round: tag("Round"), Board: tag("Board")}
+}
+
+func (k Key) Matches(game Key) bool {
+	match := func(want, got string) bool {
+		return want == "" || want == got
+	}
+	return match(k.White, game.White) && match(k.Black, game.Black) &&
+		match(k.Round, game.Round) && match(k.Board, game.Board)
+}
+
+func (k Key) String() string {
+	return fmt.Sprintf("%s - %s, round %s, board %s", k.White, k.Black, k.Round, k.Board)
+}
+
+// Board shows a followed game, *chessboard.Widget and *chessboard.MiniBoard
+// are boards.
+type Board interface {
+	SetRules(r rules.Rules)
+	Play(move rules.Move) error
+	Resign(color rules.Color) error
+	AgreeDraw() error
+}
+
+type followed struct {
+	key    Key
+	board  Board
+	start  string
+	moves  []rules.Move
+	result string
+
+	// mu keeps polls from applying games to the board at the same time,
+	// without holding the follower's lock while the board is updated
+	mu sync.Mutex
+}
+
+type update struct {
+	fb   *followed
+	key  Key
+	game *chess.Game
+}
+
+type stamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// DefaultInterval is how often followers without an interval poll.
+const DefaultInterval = time.Second
+
+// Follower polls a PGN file, or all PGN files of a directory. New moves are
+// played on the boards so they are animated, when moves were replaced the
+// board is set to the corrected game instead.
+type Follower struct {
+	Path string
+	// Interval is DefaultInterval when it isn't positive.
+	Interval time.Duration
+	// OnUpdate is called from the polling goroutine after a board changed,
+	// to have the window redrawn.
+	OnUpdate func(key Key)
+
+	boards []*followed
+	keys   []Key
+	stamps map[string]stamp
+
+	mu sync.Mutex
+}
+
+func NewFollower(path string, interval time.Duration) *Follower {
+	return &Follower{Path: path, Interval: interval}
+}
+
+// Follow shows the first game of the broadcast matching the key on the
+// board.
+func (f *Follower) Follow(key Key, board Board) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.boards = append(f.boards, &followed{key: key, board: board})
+	f.stamps = nil // apply the games to the new board on the next poll
+}
+
+func (f *Follower) Unfollow(board Board) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.boards = slices.DeleteFunc(f.boards, func(fb *followed) bool {
+		return fb.board == board
+	})
+}
+
+// Keys returns the games found by the last poll, in the order of the files.
+func (f *Follower) Keys() []Key {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.keys)
+}
+
+// Run polls until the context is done. Failed polls are logged and retried,
+// since the relay may be caught in the middle of rewriting a file.
+func (f *Follower) Run(ctx context.Context) error {
+	interval := f.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := f.Poll(); err != nil {
+			slog.Warn("can't poll relay", "path", f.Path, "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll reads the PGN files once, unless none of them changed since the last
+// poll.
+func (f *Follower) Poll() error {
+	files, err := f.files()
+	if err != nil {
+		return err
+	}
+
+	stamps := make(map[string]stamp, len(files))
+	for _, name := range files {
+		info, err := os.Stat(name)
+		if err != nil {
+			return fmt.Errorf("can't stat %s: %w", name, err)
+		}
+		stamps[name] = stamp{size: info.Size(), modTime: info.ModTime()}
+	}
+
+	f.mu.Lock()
+	unchanged := f.stamps != nil && maps.Equal(f.stamps, stamps)
+	f.mu.Unlock()
+	if unchanged {
+		return nil
+	}
+
+	var games []*chess.Game
+	for _, name := range files {
+		fileGames, err := readGames(name)
+		if err != nil {
+			return err
+		}
+		games = append(games, fileGames...)
+	}
+
+	f.mu.Lock()
+	f.stamps = stamps
+	f.keys = f.keys[:0]
+	var updates []update
+	matched := make(map[*followed]bool, len(f.boards))
+	for _, game := range games {
+		key := KeyOf(game)
+		f.keys = append(f.keys, key)
+		for _, fb := range f.boards {
+			if matched[fb] || !fb.key.Matches(key) {
+				continue
+			}
+			matched[fb] = true
+			updates = append(updates, update{fb: fb, key: key, game: game})
+		}
+	}
+	f.mu.Unlock()
+
+	for _, u := range updates {
+		changed, err := u.fb.apply(u.game)
+		if err != nil {
+			slog.Warn("can't follow relayed game", "game", u.key, "err", err)
+		} else if changed && f.OnUpdate != nil {
+			f.OnUpdate(u.key)
+		}
+	}
+	return nil
+}
+
+func (f *Follower) files() ([]string, error) {
+	info, err := os.Stat(f.Path)
+	if err != nil {
+		return nil, fmt.Errorf("can't stat relay: %w", err)
+	}
+	if !info.IsDir() {
+		return []string{f.Path}, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(f.Path, "*.pgn"))
+	if err != nil {
+		return nil, fmt.Errorf("can't list relay: %w", err)
+	}
+	slices.Sort(files)
+	return files, nil
+}
+
+// apply catches the board up with the game. A game whose moves only grew is
+// played on, and ended on the board when a result came with them. Anything
+// else sets the board to the rebuilt game.
+func (fb *followed) apply(game *chess.Game) (bool, error) {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+
+	start := game.Positions()[0].String()
+	moves := gameMoves(game)
+	result := game.Outcome().String()
+
+	ongoing := fb.result == chess.NoOutcome.String()
+	continued := start == fb.start && (result == fb.result || ongoing) &&
+		len(moves) >= len(fb.moves) && slices.Equal(moves[:len(fb.moves)], fb.moves)
+	if continued {
+		if len(moves) == len(fb.moves) && result == fb.result {
+			return false, nil
+		}
+		for _, move := range moves[len(fb.moves):] {
+			if err := fb.board.Play(move); err != nil {
+				continued = false
+				break
+			}
+			fb.moves = append(fb.moves, move)
+		}
+	}
+	if continued && result != fb.result && game.Method() == chess.NoMethod {
+		continued = end(fb.board, result) == nil
+	}
+
+	if !continued {
+		r, err := rebuild(start, moves, result)
+		if err != nil {
+			return false, err
+		}
+		fb.board.SetRules(r)
+	}
+
+	fb.start, fb.moves, fb.result = start, moves, result
+	return true, nil
+}
+
+// end shows a result the moves didn't decide, the same way rebuild does.
+func end(board Board, result string) error {
+	switch result {
+	case "1-0":
+		return board.Resign(rules.Black)
+	case "0-1":
+		return board.Resign(rules.White)
+	case "1/2-1/2":
+		return board.AgreeDraw()
+	}
+	return nil
+}
+
+func gameMoves(game *chess.Game) []rules.Move {
+	positions := game.Positions()
+	moves := make([]rules.Move, 0, len(game.Moves()))
+	for i, move := range game.Moves() {
+		moves = append(moves, rules.FromChessMove(move, positions[i].Board()))
+	}
+	return moves
+}
+
+// rebuild replays a game. PGN results don't say how a game was decided, so
+// decisive results that aren't mates show as resignations.
+func rebuild(start string, moves []rules.Move, result string) (*rules.Game, error) {
+	game, err := rules.NewGameFromFEN(start)
+	if err != nil {
+		return nil, err
+	}
+	for _, move := range moves {
+		if err := game.Move(move); err != nil {
+			return nil, fmt.Errorf("can't replay %s: %w", move, err)
+		}
+	}
+
+	if outcome, _ := game.Outcome(); outcome == rules.NoOutcome {
+		switch result {
+		case "1-0":
+			game.Resign(rules.Black)
+		case "0-1":
+			game.Resign(rules.White)
+		case "1/2-1/2":
+			game.AgreeDraw()
+		}
+	}
+	return game, nil
+}
+
+func readGames(name string) ([]*chess.Game, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("can't open %s: %w", name, err)
+	}
+	defer file.Close()
+
+	texts, err := splitGames(file)
+	if err != nil {
+		return nil, fmt.Errorf("can't read %s: %w", name, err)
+	}
+
+	games := make([]*chess.Game, 0, len(texts))
+	for i, text := range texts {
+		pgn, err := chess.PGN(strings.NewReader(text))
+		if err != nil {
+			return nil, fmt.Errorf("can't parse game %d of %s: %w", i+1, name, err)
+		}
+		games = append(games, chess.NewGame(pgn))
+	}
+	return games, nil
+}
+
+// tagLineRe matches tag pairs, not comment commands like [%clk 1:30:00] that
+// start a line of movetext.
+var tagLineRe = regexp.MustCompile(`^\[\w+\s+"`)
+
+// splitGames cuts a PGN file into its games, each starting with the first
+// tag after some movetext. Games without moves yet, as a relay publishes
+// them before a round starts, are kept.
+func splitGames(r io.Reader) ([]string, error) {
+	var games []string
+	var game strings.Builder
+	inTags, hasTags := false, false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		isTag := tagLineRe.MatchString(line)
+		if isTag && !inTags && hasTags {
+			games = append(games, game.String())
+			game.Reset()
+		}
+		if line != "" {
+			inTags = isTag
+			hasTags = hasTags || isTag
+		}
+		game.WriteString(line)
+		game.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(game.String()) != "" {
+		games = append(games, game.String())
+	}
+	return games, nil
+}
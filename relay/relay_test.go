@@ -0,0 +1,228 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/failosof/chessboard/rules"
+)
+
+// board records what the follower did to it.
+type board struct {
+	game  rules.Rules
+	calls []string
+
+	mu sync.Mutex
+}
+
+func (b *board) SetRules(r rules.Rules) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.game = r
+	b.calls = append(b.calls, "set")
+}
+
+func (b *board) Play(move rules.Move) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.game.Move(move); err != nil {
+		return err
+	}
+	b.calls = append(b.calls, "play "+move.String())
+	return nil
+}
+
+func (b *board) Resign(color rules.Color) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.game.(rules.Ender).Resign(color)
+	b.calls = append(b.calls, "resign "+color.String())
+	return nil
+}
+
+func (b *board) AgreeDraw() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.game.(rules.Ender).AgreeDraw()
+	b.calls = append(b.calls, "draw")
+	return nil
+}
+
+// takeCalls returns the calls since the last time.
+func (b *board) takeCalls() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	calls := b.calls
+	b.calls = nil
+	return calls
+}
+
+func (b *board) outcome() rules.Outcome {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	outcome, _ := b.game.(rules.Outcomer).Outcome()
+	return outcome
+}
+
+// publish writes a fixture over the relayed file, like the relay rewriting
+// it, with a later modification time so the poll sees the change.
+func publish(t *testing.T, path, fixture string, step int) {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatalf("can't read fixture: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("can't publish %s: %v", fixture, err)
+	}
+	modTime := time.Date(2024, 1, 1, 12, step, 0, 0, time.UTC)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("can't touch %s: %v", fixture, err)
+	}
+}
+
+func TestFollower(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "round.pgn")
+	follower := NewFollower(path, time.Second)
+
+	var first, second board
+	follower.Follow(Key{Board: "1"}, &first)
+	follower.Follow(Key{White: "Carol"}, &second)
+
+	var updated []Key
+	follower.OnUpdate = func(key Key) {
+		// the follower mustn't be locked while it calls back
+		follower.Keys()
+		updated = append(updated, key)
+	}
+
+	steps := []struct {
+		fixture string
+		first   []string
+		second  []string
+	}{
+		{"round-1.pgn", []string{"set"}, []string{"set"}},
+		{"round-1.pgn", nil, nil},
+		{"round-2.pgn", []string{"play b8c6", "play f1b5"}, []string{"play f2f3"}},
+		// Bb5 was corrected to Bc4
+		{"round-3.pgn", []string{"set"}, []string{"play e7e5"}},
+		// the final moves are animated, then the game is ended
+		{"round-4.pgn", []string{"play c6d4", "play f3d4", "resign b"}, []string{"play g2g4", "play d8h4"}},
+		// the result was corrected
+		{"round-5.pgn", []string{"set"}, nil},
+	}
+
+	for i, step := range steps {
+		if i == 0 || step.fixture != steps[i-1].fixture {
+			publish(t, path, step.fixture, i)
+		}
+		updated = nil
+		if err := follower.Poll(); err != nil {
+			t.Fatalf("step %d: Poll: %v", i, err)
+		}
+
+		if got := first.takeCalls(); !slices.Equal(got, step.first) {
+			t.Errorf("step %d: board 1 got %q, want %q", i, got, step.first)
+		}
+		if got := second.takeCalls(); !slices.Equal(got, step.second) {
+			t.Errorf("step %d: board 2 got %q, want %q", i, got, step.second)
+		}
+		want := 0
+		for _, calls := range [][]string{step.first, step.second} {
+			if calls != nil {
+				want++
+			}
+		}
+		if len(updated) != want {
+			t.Errorf("step %d: updated %v, want %d boards", i, updated, want)
+		}
+	}
+
+	if outcome := first.outcome(); outcome != rules.Draw {
+		t.Errorf("board 1 outcome %v, want a draw", outcome)
+	}
+	if outcome := second.outcome(); outcome != rules.BlackWon {
+		t.Errorf("board 2 outcome %v, want Black to win", outcome)
+	}
+
+	want := []Key{
+		{White: "Alice", Black: "Bob", Round: "1", Board: "1"},
+		{White: "Carol", Black: "Dave", Round: "1", Board: "2"},
+	}
+	if got := follower.Keys(); !slices.Equal(got, want) {
+		t.Errorf("keys %v, want %v", got, want)
+	}
+}
+
+func TestFollowerDirectory(t *testing.T) {
+	dir := t.TempDir()
+	for i, name := range []string{"b.pgn", "a.pgn"} {
+		game := fmt.Sprintf("[White \"Player %d\"]\n[Black \"Other\"]\n\n1. e4 *\n", i)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(game), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	follower := NewFollower(dir, time.Second)
+	if err := follower.Poll(); err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+	keys := follower.Keys()
+	if len(keys) != 2 || keys[0].White != "Player 1" || keys[1].White != "Player 0" {
+		t.Errorf("keys %v, want the games of a.pgn first", keys)
+	}
+}
+
+func TestSplitGames(t *testing.T) {
+	pgn := `[White "A"]
+[Black "B"]
+
+*
+
+[White "C"]
+[Black "D"]
+
+1. e4 { a long think
+[%clk 1:30:00] } e5
+[%emt 0:00:05]
+2. Nf3 *
+[White "E"]
+[Black "F"]
+`
+	games, err := splitGames(strings.NewReader(pgn))
+	if err != nil {
+		t.Fatalf("splitGames: %v", err)
+	}
+	if len(games) != 3 {
+		t.Fatalf("got %d games, want 3: %q", len(games), games)
+	}
+	if !strings.Contains(games[1], "2. Nf3") || strings.Contains(games[1], `"E"`) {
+		t.Errorf("second game is %q", games[1])
+	}
+}
+
+func TestRunWithoutInterval(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "round.pgn"), []byte("[White \"A\"]\n\n1. e4 *\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	follower := NewFollower(dir, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := follower.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Run = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if keys := follower.Keys(); len(keys) != 1 {
+		t.Errorf("keys %v after running, want the one game", keys)
+	}
+}
@@ -0,0 +1,84 @@
+package review
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/failosof/chessboard"
+	"github.com/failosof/chessboard/engine"
+	"github.com/notnil/chess"
+)
+
+// PGN writes the game back with the review applied: classified moves get their
+// NAG, and a comment naming the better move with a [%cal] arrow for it.
+func (r *Review) PGN(game *chess.Game) string {
+	var pgn strings.Builder
+	for _, tag := range game.TagPairs() {
+		fmt.Fprintf(&pgn, "[%s \"%s\"]\n", tag.Key, tag.Value)
+	}
+	if len(game.TagPairs()) > 0 {
+		pgn.WriteString("\n")
+	}
+
+	notation := chess.AlgebraicNotation{}
+	positions := game.Positions()
+	comments := game.Comments()
+	colors := chessboard.DefaultColors()
+
+	for ply, move := range game.Moves() {
+		position := positions[ply]
+		if position.Turn() == chess.White {
+			fmt.Fprintf(&pgn, "%s. ", moveNumber(position))
+		} else if ply == 0 {
+			fmt.Fprintf(&pgn, "%s... ", moveNumber(position))
+		}
+		pgn.WriteString(notation.Encode(position, move))
+
+		var texts []string
+		if ply < len(comments) {
+			texts = append(texts, comments[ply]...)
+		}
+
+		if ply < len(r.Moves) {
+			reviewed := r.Moves[ply]
+			if nag := reviewed.Class.NAG(); nag != chessboard.NoNAG {
+				fmt.Fprintf(&pgn, " %s", nag)
+
+				text := reviewed.Class.String() + "."
+				if reviewed.BestMove != nil {
+					text += fmt.Sprintf(" %s was best.", notation.Encode(position, reviewed.BestMove))
+				}
+				text += " " + formatEval(reviewed.After)
+				if annotations := r.Annotations(nil, ply, colors); len(annotations) > 0 {
+					text += " " + chessboard.CommentFromAnnotations(annotations, colors)
+				}
+				texts = append(texts, text)
+			} else {
+				texts = append(texts, formatEval(reviewed.After))
+			}
+		}
+
+		for _, text := range texts {
+			fmt.Fprintf(&pgn, " { %s }", text)
+		}
+		pgn.WriteString(" ")
+	}
+
+	pgn.WriteString(game.Outcome().String())
+	return pgn.String()
+}
+
+func formatEval(score engine.Score) string {
+	if score.IsMate() {
+		return fmt.Sprintf("[%%eval #%d]", score.Mate)
+	}
+	return fmt.Sprintf("[%%eval %.2f]", float64(score.CP)/100)
+}
+
+func moveNumber(position *chess.Position) string {
+	fields := strings.Fields(position.String())
+	if len(fields) < 6 {
+		return "1"
+	}
+	return fields[5]
+}
@@ -0,0 +1,231 @@
+package review
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"gioui.org/widget/material"
+	"github.com/failosof/chessboard"
+	"github.com/failosof/chessboard/engine"
+	"github.com/failosof/chessboard/rules"
+	"github.com/notnil/chess"
+)
+
+type Class int8
+
+const (
+	Unclassified Class = iota
+	Best
+	Excellent
+	Inaccuracy
+	Mistake
+	Blunder
+)
+
+func (c Class) String() string {
+	switch c {
+	case Best:
+		return "Best move"
+	case Excellent:
+		return "Excellent"
+	case Inaccuracy:
+		return "Inaccuracy"
+	case Mistake:
+		return "Mistake"
+	case Blunder:
+		return "Blunder"
+	default:
+		return ""
+	}
+}
+
+func (c Class) NAG() chessboard.NAG {
+	switch c {
+	case Inaccuracy:
+		return chessboard.DubiousMove
+	case Mistake:
+		return chessboard.Mistake
+	case Blunder:
+		return chessboard.Blunder
+	default:
+		return chessboard.NoNAG
+	}
+}
+
+// Thresholds are the centipawn losses starting from which a move is
+// classified as an inaccuracy, a mistake or a blunder.
+type Thresholds struct {
+	Inaccuracy int
+	Mistake    int
+	Blunder    int
+}
+
+var DefaultThresholds = Thresholds{
+	Inaccuracy: 50,
+	Mistake:    100,
+	Blunder:    300,
+}
+
+func (t Thresholds) Classify(loss int) Class {
+	switch {
+	case loss >= t.Blunder:
+		return Blunder
+	case loss >= t.Mistake:
+		return Mistake
+	case loss >= t.Inaccuracy:
+		return Inaccuracy
+	default:
+		return Excellent
+	}
+}
+
+// Options left zero take the DefaultOptions value, a negative Clamp doesn't
+// clamp at all.
+type Options struct {
+	Limits     engine.Limits
+	Thresholds Thresholds
+	// Evaluations are clamped to this many centipawns before computing the
+	// loss, so missing a faster mate in a won position isn't a blunder.
+	Clamp int
+}
+
+var DefaultOptions = Options{
+	Limits:     engine.Limits{Depth: 16},
+	Thresholds: DefaultThresholds,
+	Clamp:      1000,
+}
+
+func (o Options) withDefaults() Options {
+	if o.Limits == (engine.Limits{}) {
+		o.Limits = DefaultOptions.Limits
+	}
+	if o.Thresholds == (Thresholds{}) {
+		o.Thresholds = DefaultOptions.Thresholds
+	}
+	if o.Clamp == 0 {
+		o.Clamp = DefaultOptions.Clamp
+	}
+	return o
+}
+
+// Move is the review of a single ply. Scores are from White's point of view.
+type Move struct {
+	Ply      int
+	Move     *chess.Move
+	BestMove *chess.Move
+	Before   engine.Score
+	After    engine.Score
+	Loss     int
+	Class    Class
+}
+
+type Review struct {
+	Moves []Move
+}
+
+func Run(ctx context.Context, e engine.Engine, game *chess.Game, options Options) (*Review, error) {
+	options = options.withDefaults()
+	positions := game.Positions()
+	moves := game.Moves()
+
+	if err := e.NewGame(); err != nil {
+		return nil, fmt.Errorf("can't start new game: %w", err)
+	}
+
+	scores := make([]engine.Score, len(positions))
+	bestMoves := make([]*chess.Move, len(positions))
+	for ply, position := range positions {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		switch position.Status() {
+		case chess.Checkmate:
+			scores[ply] = whitePOV(engine.Mated, position.Turn())
+			continue
+		case chess.Stalemate:
+			continue
+		}
+
+		if err := engine.SetPositionAt(e, game, ply); err != nil {
+			return nil, fmt.Errorf("can't set position at ply %d: %w", ply, err)
+		}
+		result, err := e.Go(ctx, options.Limits, nil)
+		if err != nil {
+			return nil, fmt.Errorf("can't analyse ply %d: %w", ply, err)
+		}
+
+		scores[ply] = whitePOV(result.Info.Score, position.Turn())
+		bestMoves[ply], err = chess.UCINotation{}.Decode(position, result.BestMove)
+		if err != nil {
+			slog.Warn("engine suggested invalid move", "ply", ply, "move", result.BestMove, "err", err)
+		}
+	}
+
+	review := &Review{Moves: make([]Move, len(moves))}
+	for ply, move := range moves {
+		turn := positions[ply].Turn()
+		before := clamp(moverPOV(scores[ply], turn).Centipawns(), options.Clamp)
+		after := clamp(moverPOV(scores[ply+1], turn).Centipawns(), options.Clamp)
+
+		reviewed := Move{
+			Ply:      ply,
+			Move:     move,
+			BestMove: bestMoves[ply],
+			Before:   scores[ply],
+			After:    scores[ply+1],
+			Loss:     max(before-after, 0),
+		}
+		if reviewed.BestMove != nil && reviewed.BestMove.String() == move.String() {
+			reviewed.Class = Best
+		} else {
+			reviewed.Class = options.Thresholds.Classify(reviewed.Loss)
+		}
+		review.Moves[ply] = reviewed
+	}
+
+	return review, nil
+}
+
+// Annotations returns what the board shows for the given ply: a badge labelled
+// with th on the destination square and an arrow for the better move when
+// there was one.
+func (r *Review) Annotations(th *material.Theme, ply int, colors chessboard.Color) []chessboard.Annotation {
+	if ply < 0 || ply >= len(r.Moves) {
+		return nil
+	}
+
+	move := r.Moves[ply]
+	var annotations []chessboard.Annotation
+	if nag := move.Class.NAG(); nag != chessboard.NoNAG {
+		annotations = append(annotations, chessboard.NewBadge(th, rules.FromChessSquare(move.Move.S2()), nag))
+		if move.BestMove != nil {
+			annotations = append(annotations, chessboard.Annotation{
+				Type:  chessboard.ArrowAnno,
+				Start: rules.FromChessSquare(move.BestMove.S1()),
+				End:   rules.FromChessSquare(move.BestMove.S2()),
+				Color: colors.Primary,
+			})
+		}
+	}
+	return annotations
+}
+
+func whitePOV(score engine.Score, turn chess.Color) engine.Score {
+	if turn == chess.Black {
+		return score.Negate()
+	}
+	return score
+}
+
+func moverPOV(score engine.Score, turn chess.Color) engine.Score {
+	return whitePOV(score, turn)
+}
+
+func clamp(cp, limit int) int {
+	if limit <= 0 {
+		return cp
+	}
+	return min(max(cp, -limit), limit)
+}
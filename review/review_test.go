@@ -0,0 +1,152 @@
+package review
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/failosof/chessboard/engine"
+	"github.com/failosof/chessboard/engine/enginetest"
+	"github.com/notnil/chess"
+)
+
+// foolsMate is reviewed by an engine that answers each position in turn,
+// scores are from the side to move's point of view.
+var foolsMate = [][]string{
+	{"info depth 10 score cp 30 pv e2e4", "bestmove e2e4"},
+	{"info depth 10 score cp 20 pv e7e5", "bestmove e7e5"},
+	{"info depth 10 score cp -40 pv d2d4", "bestmove d2d4"},
+	{"info depth 10 score mate 1 pv d8h4", "bestmove d8h4"},
+}
+
+func newFakeEngine(t *testing.T, answers [][]string) (engine.Engine, *enginetest.Engine) {
+	t.Helper()
+
+	searches := 0
+	fake := enginetest.New(func(cmd string) []string {
+		switch {
+		case cmd == "uci":
+			return []string{"id name Fake", "uciok"}
+		case cmd == "isready":
+			return []string{"readyok"}
+		case strings.HasPrefix(cmd, "go") && searches < len(answers):
+			searches++
+			return answers[searches-1]
+		}
+		return nil
+	})
+	t.Cleanup(func() { fake.Close() })
+
+	e, err := engine.NewUCI(fake.Stdout, fake.Stdin)
+	if err != nil {
+		t.Fatalf("NewUCI: %v", err)
+	}
+	return e, fake
+}
+
+func foolsMateGame(t *testing.T) *chess.Game {
+	t.Helper()
+
+	game := chess.NewGame()
+	for _, move := range []string{"f3", "e5", "g4", "Qh4"} {
+		if err := game.MoveStr(move); err != nil {
+			t.Fatalf("can't play %s: %v", move, err)
+		}
+	}
+	return game
+}
+
+func TestRun(t *testing.T) {
+	game := foolsMateGame(t)
+	e, _ := newFakeEngine(t, foolsMate)
+	review, err := Run(context.Background(), e, game, DefaultOptions)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	want := []struct {
+		class Class
+		loss  int
+		best  string
+	}{
+		{Inaccuracy, 50, "e2e4"},
+		{Best, 0, "e7e5"},
+		{Blunder, 960, "d2d4"},
+		{Best, 0, "d8h4"},
+	}
+	if len(review.Moves) != len(want) {
+		t.Fatalf("got %d reviewed moves, want %d", len(review.Moves), len(want))
+	}
+	for ply, move := range review.Moves {
+		if move.Class != want[ply].class || move.Loss != want[ply].loss || move.BestMove.String() != want[ply].best {
+			t.Errorf("ply %d: %s loss %d best %s, want %s loss %d best %s", ply,
+				move.Class, move.Loss, move.BestMove, want[ply].class, want[ply].loss, want[ply].best)
+		}
+	}
+	if after := review.Moves[3].After; after != engine.Mated {
+		t.Errorf("score after mate = %v, want White mated", after)
+	}
+}
+
+func TestRunDefaults(t *testing.T) {
+	game := foolsMateGame(t)
+	e, fake := newFakeEngine(t, foolsMate)
+	review, err := Run(context.Background(), e, game, Options{})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	for _, cmd := range fake.Commands() {
+		if strings.HasPrefix(cmd, "go") && cmd != "go depth 16" {
+			t.Errorf("searched with %q, want the default depth", cmd)
+		}
+	}
+	// the default thresholds and clamp, not a blunder for every loss
+	if got := review.Moves[0]; got.Class != Inaccuracy || got.Loss != 50 {
+		t.Errorf("first move: %s loss %d, want %s loss 50", got.Class, got.Loss, Inaccuracy)
+	}
+	if got := review.Moves[2]; got.Class != Blunder || got.Loss != 960 {
+		t.Errorf("third move: %s loss %d, want %s loss 960", got.Class, got.Loss, Blunder)
+	}
+}
+
+func TestPGN(t *testing.T) {
+	game := foolsMateGame(t)
+	e, _ := newFakeEngine(t, foolsMate)
+	review, err := Run(context.Background(), e, game, DefaultOptions)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	pgn := review.PGN(game)
+	for _, want := range []string{
+		"1. f3 $6 { Inaccuracy. e4 was best. [%eval -0.20] [%cal Ge2e4] }",
+		"2. g4 $4 { Blunder. d4 was best. [%eval #-1] [%cal Gd2d4] }",
+		"Qh4# { [%eval #0] }",
+		"0-1",
+	} {
+		if !strings.Contains(pgn, want) {
+			t.Errorf("PGN doesn't contain %q:\n%s", want, pgn)
+		}
+	}
+}
+
+func TestThresholds(t *testing.T) {
+	tests := []struct {
+		loss int
+		want Class
+	}{
+		{0, Excellent},
+		{49, Excellent},
+		{50, Inaccuracy},
+		{100, Mistake},
+		{299, Mistake},
+		{300, Blunder},
+	}
+
+	for _, test := range tests {
+		if got := DefaultThresholds.Classify(test.loss); got != test.want {
+			t.Errorf("Classify(%d) = %s, want %s", test.loss, got, test.want)
+		}
+	}
+}
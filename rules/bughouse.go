@@ -0,0 +1,207 @@
+package rules
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Bughouse links two crazyhouse boards played by two teams. The first team
+// plays white on the first board and black on the second one. Whatever is
+// captured on one board goes to the pocket of the capturer's partner, and the
+// match is over as soon as either board is.
+type Bughouse struct {
+	boards  [2]*Game
+	outcome Outcome
+	method  Method
+	decided int // board the match was decided on
+
+	mu sync.Mutex
+}
+
+func NewBughouse() *Bughouse {
+	b := &Bughouse{outcome: NoOutcome, decided: -1}
+	for i := range b.boards {
+		b.boards[i] = NewCrazyhouseGame()
+		b.boards[i].Position().linked = true
+	}
+	return b
+}
+
+// Board returns the rules of one board, for a widget to play it.
+func (b *Bughouse) Board(i int) *BughouseBoard {
+	return &BughouseBoard{match: b, index: i}
+}
+
+// Outcome returns the result as seen from the given board.
+func (b *Bughouse) Outcome(board int) (Outcome, Method) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.outcomeOn(board)
+}
+
+// Timeout ends the match when a player's clock runs out, which loses it for
+// the whole team.
+func (b *Bughouse) Timeout(board int, color Color) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.outcome == NoOutcome {
+		b.outcome, b.method, b.decided = WinFor(color.Other()), Timeout, board
+	}
+}
+
+func (b *Bughouse) Resign(board int, color Color) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.outcome == NoOutcome {
+		b.outcome, b.method, b.decided = WinFor(color.Other()), Resignation, board
+	}
+}
+
+// Team returns the team of the player of the given color on a board.
+func Team(board int, color Color) int {
+	if (board == 0) == (color == White) {
+		return 0
+	}
+	return 1
+}
+
+func (b *Bughouse) outcomeOn(board int) (Outcome, Method) {
+	if b.outcome == NoOutcome || b.decided == board || b.outcome == Draw {
+		return b.outcome, b.method
+	}
+	// partners play opposite colors, so the result is mirrored on the other board
+	winner := WhiteWon
+	if b.outcome == WhiteWon {
+		winner = BlackWon
+	}
+	return winner, b.method
+}
+
+func (b *Bughouse) move(board int, move Move) error {
+	if b.outcome != NoOutcome {
+		return ErrGameOver
+	}
+
+	game := b.boards[board]
+	captured := game.Position().Captured(move)
+	if err := game.Move(move); err != nil {
+		return fmt.Errorf("can't move on board %d: %w", board+1, err)
+	}
+
+	partner := b.boards[1-board]
+	if captured != NoPiece {
+		partner.give(captured)
+	}
+
+	for i, g := range b.boards {
+		if outcome, method := g.Outcome(); outcome != NoOutcome {
+			b.outcome, b.method, b.decided = outcome, method, i
+			break
+		}
+	}
+	return nil
+}
+
+type BughouseBoard struct {
+	match *Bughouse
+	index int
+}
+
+func (b *BughouseBoard) Match() *Bughouse {
+	return b.match
+}
+
+func (b *BughouseBoard) Index() int {
+	return b.index
+}
+
+func (b *BughouseBoard) Piece(square Square) Piece {
+	b.match.mu.Lock()
+	defer b.match.mu.Unlock()
+	return b.game().Piece(square)
+}
+
+func (b *BughouseBoard) Turn() Color {
+	b.match.mu.Lock()
+	defer b.match.mu.Unlock()
+	return b.game().Turn()
+}
+
+func (b *BughouseBoard) LegalMoves(from Square) []Move {
+	b.match.mu.Lock()
+	defer b.match.mu.Unlock()
+
+	if b.match.outcome != NoOutcome {
+		return nil
+	}
+	return b.game().LegalMoves(from)
+}
+
+func (b *BughouseBoard) Move(move Move) error {
+	b.match.mu.Lock()
+	defer b.match.mu.Unlock()
+	return b.match.move(b.index, move)
+}
+
+func (b *BughouseBoard) LastMove() (Move, bool) {
+	b.match.mu.Lock()
+	defer b.match.mu.Unlock()
+	return b.game().LastMove()
+}
+
+func (b *BughouseBoard) Hash() uint64 {
+	b.match.mu.Lock()
+	defer b.match.mu.Unlock()
+
+	// the outcome is part of the hash, so a board redraws when the match
+	// ends on the other one
+	hash := b.game().Hash()
+	if b.match.outcome != NoOutcome {
+		hash = ^hash
+	}
+	return hash
+}
+
+func (b *BughouseBoard) InCheck() bool {
+	b.match.mu.Lock()
+	defer b.match.mu.Unlock()
+	return b.game().InCheck()
+}
+
+func (b *BughouseBoard) Outcome() (Outcome, Method) {
+	b.match.mu.Lock()
+	defer b.match.mu.Unlock()
+	return b.match.outcomeOn(b.index)
+}
+
+func (b *BughouseBoard) FEN() string {
+	b.match.mu.Lock()
+	defer b.match.mu.Unlock()
+	return b.game().FEN()
+}
+
+func (b *BughouseBoard) Drops() bool {
+	return true
+}
+
+func (b *BughouseBoard) Pocket(color Color) Pocket {
+	b.match.mu.Lock()
+	defer b.match.mu.Unlock()
+	return b.game().Pocket(color)
+}
+
+func (b *BughouseBoard) LegalDrops(piece Piece) []Move {
+	b.match.mu.Lock()
+	defer b.match.mu.Unlock()
+
+	if b.match.outcome != NoOutcome {
+		return nil
+	}
+	return b.game().LegalDrops(piece)
+}
+
+func (b *BughouseBoard) game() *Game {
+	return b.match.boards[b.index]
+}
@@ -0,0 +1,178 @@
+package rules
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/notnil/chess"
+)
+
+// ChessGame adapts a notnil/chess game to Rules. Castling moves are
+// translated into the king onto rook form, and takebacks keep the game's
+// notation, tags and comments.
+type ChessGame struct {
+	game    *chess.Game
+	history MoveStack
+}
+
+func FromChess(game *chess.Game) *ChessGame {
+	return &ChessGame{game: game}
+}
+
+func (g *ChessGame) Game() *chess.Game {
+	return g.game
+}
+
+func (g *ChessGame) Piece(square Square) Piece {
+	if square < 0 || square.File() > 7 || square.Rank() > 7 {
+		return NoPiece
+	}
+	return Piece(g.game.Position().Board().Piece(ToChessSquare(square)))
+}
+
+func (g *ChessGame) Turn() Color {
+	return Color(g.game.Position().Turn())
+}
+
+// LegalMoves is empty once the game ended, notnil/chess would go on
+// generating moves after a resignation or an agreed draw.
+func (g *ChessGame) LegalMoves(from Square) []Move {
+	if g.Piece(from) == NoPiece || g.game.Outcome() != chess.NoOutcome {
+		return nil
+	}
+
+	board := g.game.Position().Board()
+	var moves []Move
+	for _, move := range g.game.ValidMoves() {
+		if move.S1() == ToChessSquare(from) {
+			moves = append(moves, FromChessMove(move, board))
+		}
+	}
+	return moves
+}
+
+func (g *ChessGame) Move(move Move) error {
+	if g.game.Outcome() != chess.NoOutcome {
+		return ErrGameOver
+	}
+
+	board := g.game.Position().Board()
+	for _, valid := range g.game.ValidMoves() {
+		if FromChessMove(valid, board) == move {
+			if err := g.game.Move(valid); err != nil {
+				return err
+			}
+			g.history.Clear()
+			return nil
+		}
+	}
+	return fmt.Errorf("%w %s", ErrIllegalMove, move)
+}
+
+func (g *ChessGame) LastMove() (Move, bool) {
+	moves := g.game.Moves()
+	positions := g.game.Positions()
+	if len(moves) == 0 || len(positions) < 2 {
+		return NoMove, false
+	}
+	return FromChessMove(moves[len(moves)-1], positions[len(positions)-2].Board()), true
+}
+
+func (g *ChessGame) InCheck() bool {
+	position, err := ParseFEN(g.game.FEN())
+	return err == nil && position.InCheck()
+}
+
+func (g *ChessGame) Outcome() (Outcome, Method) {
+	return Outcome(g.game.Outcome()), methodFromChess(g.history.Method(g.game))
+}
+
+func (g *ChessGame) Resign(color Color) {
+	g.game.Resign(chess.Color(color))
+}
+
+func (g *ChessGame) AgreeDraw() {
+	g.game.Draw(chess.DrawOffer)
+}
+
+func (g *ChessGame) Takeback() (Move, error) {
+	move, err := g.history.Takeback(g.game)
+	if err != nil {
+		return NoMove, err
+	}
+	return FromChessMove(move, g.game.Position().Board()), nil
+}
+
+func (g *ChessGame) Redo() (Move, error) {
+	if _, err := g.history.Redo(g.game); err != nil {
+		return NoMove, err
+	}
+	move, _ := g.LastMove()
+	return move, nil
+}
+
+func (g *ChessGame) CanRedo() bool {
+	return g.history.CanRedo()
+}
+
+func (g *ChessGame) Hash() uint64 {
+	hash := g.game.Position().Hash()
+	return binary.LittleEndian.Uint64(hash[:8])
+}
+
+func (g *ChessGame) FEN() string {
+	return g.game.FEN()
+}
+
+func ToChessSquare(square Square) chess.Square {
+	return chess.NewSquare(chess.File(square.File()), chess.Rank(square.Rank()))
+}
+
+func FromChessSquare(square chess.Square) Square {
+	if square == chess.NoSquare {
+		return NoSquare
+	}
+	return NewSquare(int(square.File()), int(square.Rank()))
+}
+
+// FromChessMove needs the board the move was made on to tell castling apart,
+// since moves decoded from PGN don't always carry the castling tags.
+func FromChessMove(move *chess.Move, board *chess.Board) Move {
+	m := Move{
+		From:  FromChessSquare(move.S1()),
+		To:    FromChessSquare(move.S2()),
+		Promo: PieceType(move.Promo()),
+	}
+
+	if board.Piece(move.S1()).Type() == chess.King {
+		switch m.To.File() - m.From.File() {
+		case 2:
+			m.To = NewSquare(7, m.From.Rank())
+		case -2:
+			m.To = NewSquare(0, m.From.Rank())
+		}
+	}
+
+	return m
+}
+
+func methodFromChess(method chess.Method) Method {
+	switch method {
+	case chess.Checkmate:
+		return Checkmate
+	case chess.Stalemate:
+		return Stalemate
+	case chess.ThreefoldRepetition, chess.FivefoldRepetition:
+		return Repetition
+	case chess.FiftyMoveRule, chess.SeventyFiveMoveRule:
+		return FiftyMoveRule
+	case chess.InsufficientMaterial:
+		return InsufficientMaterial
+	case chess.Resignation:
+		return Resignation
+	case chess.DrawOffer:
+		return Agreement
+	default:
+		return NoMethod
+	}
+}
@@ -0,0 +1,82 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+)
+
+const Chess960Positions = 960
+
+var knightPlacements = [10][2]int{
+	{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2},
+	{1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4},
+}
+
+// Chess960BackRank returns the white back rank of a Chess960 start position
+// using the Scharnagl numbering, in which 518 is the standard setup.
+func Chess960BackRank(index int) (string, error) {
+	if index < 0 || index >= Chess960Positions {
+		return "", fmt.Errorf("chess960 index %d out of range", index)
+	}
+
+	var rank [8]byte
+	n := index
+	rank[n%4*2+1] = 'B'
+	n /= 4
+	rank[n%4*2] = 'B'
+	n /= 4
+
+	place := func(piece byte, nth int) {
+		for file := range rank {
+			if rank[file] == 0 {
+				if nth == 0 {
+					rank[file] = piece
+					return
+				}
+				nth--
+			}
+		}
+	}
+
+	place('Q', n%6)
+	n /= 6
+
+	knights := knightPlacements[n]
+	place('N', knights[1])
+	place('N', knights[0])
+
+	place('R', 0)
+	place('K', 0)
+	place('R', 0)
+
+	return string(rank[:]), nil
+}
+
+func Chess960(index int) (*Position, error) {
+	backRank, err := Chess960BackRank(index)
+	if err != nil {
+		return nil, err
+	}
+
+	fen := fmt.Sprintf("%s/pppppppp/8/8/8/8/PPPPPPPP/%s w KQkq - 0 1", strings.ToLower(backRank), backRank)
+	return ParseFEN(fen)
+}
+
+// Chess960Index returns the Scharnagl number of a position's back rank setup,
+// or -1 if it isn't a Chess960 start.
+func Chess960Index(p *Position) int {
+	if p.files != 8 || p.ranks != 8 {
+		return -1
+	}
+
+	var backRank strings.Builder
+	for file := 0; file < 8; file++ {
+		backRank.WriteString(p.board[NewSquare(file, 0)].String())
+	}
+	for index := 0; index < Chess960Positions; index++ {
+		if candidate, _ := Chess960BackRank(index); candidate == backRank.String() {
+			return index
+		}
+	}
+	return -1
+}
@@ -0,0 +1,127 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChess960BackRank(t *testing.T) {
+	tests := []struct {
+		index int
+		want  string
+	}{
+		{0, "BBQNNRKR"},
+		{1, "BQNBNRKR"},
+		{518, "RNBQKBNR"},
+		{959, "RKRNNQBB"},
+	}
+
+	for _, test := range tests {
+		got, err := Chess960BackRank(test.index)
+		if err != nil || got != test.want {
+			t.Errorf("Chess960BackRank(%d) = %q, %v, want %q", test.index, got, err, test.want)
+		}
+	}
+
+	for _, index := range []int{-1, 960} {
+		if _, err := Chess960BackRank(index); err == nil {
+			t.Errorf("Chess960BackRank(%d) didn't fail", index)
+		}
+	}
+}
+
+func TestChess960Positions(t *testing.T) {
+	seen := make(map[string]bool)
+	for index := range Chess960Positions {
+		position, err := Chess960(index)
+		if err != nil {
+			t.Fatalf("Chess960(%d): %v", index, err)
+		}
+
+		backRank, _ := Chess960BackRank(index)
+		if seen[backRank] {
+			t.Errorf("position %d repeats %s", index, backRank)
+		}
+		seen[backRank] = true
+
+		king := strings.IndexByte(backRank, 'K')
+		if strings.IndexByte(backRank, 'R') > king || strings.LastIndexByte(backRank, 'R') < king {
+			t.Errorf("position %d %s doesn't have the king between the rooks", index, backRank)
+		}
+		if bishops := strings.IndexByte(backRank, 'B') + strings.LastIndexByte(backRank, 'B'); bishops%2 == 0 {
+			t.Errorf("position %d %s has its bishops on the same color", index, backRank)
+		}
+		if got := Chess960Index(position); got != index {
+			t.Errorf("Chess960Index(Chess960(%d)) = %d", index, got)
+		}
+		if got := len(position.LegalMoves()); got < 18 || got > 21 {
+			t.Errorf("position %d %s has %d moves", index, backRank, got)
+		}
+	}
+}
+
+func TestChess960Castling(t *testing.T) {
+	tests := []struct {
+		name    string
+		fen     string
+		move    Move
+		want    string
+		illegal bool
+	}{
+		{
+			name: "king and rook swap",
+			fen:  "4k3/8/8/8/8/8/8/5KR1 w G - 0 1",
+			move: Move{From: NewSquare(5, 0), To: NewSquare(6, 0)},
+			want: "4k3/8/8/8/8/8/8/5RK1 b - - 1 1",
+		},
+		{
+			name: "king stays, rook jumps over",
+			fen:  "4k3/8/8/8/8/8/8/1R4KR w H - 0 1",
+			move: Move{From: NewSquare(6, 0), To: NewSquare(7, 0)},
+			want: "4k3/8/8/8/8/8/8/1R3RK1 b - - 1 1",
+		},
+		{
+			name: "queen side from b1",
+			fen:  "4k3/8/8/8/8/8/8/RK6 w A - 0 1",
+			move: Move{From: NewSquare(1, 0), To: NewSquare(0, 0)},
+			want: "4k3/8/8/8/8/8/8/2KR4 b - - 1 1",
+		},
+		{
+			name:    "onto an attacked square",
+			fen:     "2r1k3/8/8/8/8/8/8/RK6 w A - 0 1",
+			move:    Move{From: NewSquare(1, 0), To: NewSquare(0, 0)},
+			illegal: true,
+		},
+		{
+			name:    "through an attacked square",
+			fen:     "3k1r2/8/8/8/8/8/8/4K2R w H - 0 1",
+			move:    Move{From: NewSquare(4, 0), To: NewSquare(7, 0)},
+			illegal: true,
+		},
+		{
+			name:    "blocked by the other rook",
+			fen:     "4k3/8/8/8/8/8/8/4KRR1 w G - 0 1",
+			move:    Move{From: NewSquare(4, 0), To: NewSquare(6, 0)},
+			illegal: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			position, err := ParseFEN(test.fen)
+			if err != nil {
+				t.Fatalf("ParseFEN: %v", err)
+			}
+
+			if legal := position.IsLegal(test.move); legal == test.illegal {
+				t.Fatalf("IsLegal(%s) = %t", test.move, legal)
+			}
+			if test.illegal {
+				return
+			}
+			if got := position.Update(test.move).String(); got != test.want {
+				t.Errorf("after %s got %s, want %s", test.move, got, test.want)
+			}
+		})
+	}
+}
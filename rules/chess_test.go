@@ -0,0 +1,68 @@
+package rules
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+func TestChessGameCastling(t *testing.T) {
+	fen, err := chess.FEN("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	game := FromChess(chess.NewGame(fen))
+
+	e1, h1 := NewSquare(4, 0), NewSquare(7, 0)
+	castling := Move{From: e1, To: h1}
+	found := false
+	for _, move := range game.LegalMoves(e1) {
+		found = found || move == castling
+	}
+	if !found {
+		t.Fatalf("castling %s isn't among the king moves %v", castling, game.LegalMoves(e1))
+	}
+
+	if err := game.Move(castling); err != nil {
+		t.Fatalf("Move(%s): %v", castling, err)
+	}
+	if got, want := game.FEN(), "r3k2r/8/8/8/8/8/8/R4RK1 b kq - 1 1"; got != want {
+		t.Errorf("FEN = %q, want %q", got, want)
+	}
+	if last, _ := game.LastMove(); last != castling {
+		t.Errorf("LastMove = %s, want %s", last, castling)
+	}
+
+	move, err := game.Takeback()
+	if err != nil || move != castling {
+		t.Fatalf("Takeback = %s, %v, want %s", move, err, castling)
+	}
+	if move, err := game.Redo(); err != nil || move != castling {
+		t.Errorf("Redo = %s, %v, want %s", move, err, castling)
+	}
+}
+
+func TestChessGameEnd(t *testing.T) {
+	game := FromChess(chess.NewGame())
+	game.Resign(White)
+	if outcome, method := game.Outcome(); outcome != WinFor(Black) || method != Resignation {
+		t.Errorf("after White resigned got %s by %s", outcome, method)
+	}
+
+	if moves := game.LegalMoves(NewSquare(4, 1)); len(moves) != 0 {
+		t.Errorf("moves %v after the resignation", moves)
+	}
+	if err := game.Move(Move{From: NewSquare(4, 1), To: NewSquare(4, 3)}); !errors.Is(err, ErrGameOver) {
+		t.Errorf("Move after the resignation = %v, want %v", err, ErrGameOver)
+	}
+
+	game = FromChess(chess.NewGame())
+	game.AgreeDraw()
+	if outcome, method := game.Outcome(); outcome != Draw || method != Agreement {
+		t.Errorf("after a draw agreement got %s by %s", outcome, method)
+	}
+	if err := game.Move(Move{From: NewSquare(4, 1), To: NewSquare(4, 3)}); !errors.Is(err, ErrGameOver) {
+		t.Errorf("Move after the draw = %v, want %v", err, ErrGameOver)
+	}
+}
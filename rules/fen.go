@@ -0,0 +1,280 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type CastlingStyle int8
+
+const (
+	// XFEN writes KQkq for the outermost rooks and file letters for the rest,
+	// which is plain FEN for standard chess.
+	XFEN CastlingStyle = iota
+	// ShredderFEN always writes the file letters of the castling rooks.
+	ShredderFEN
+)
+
+// ParseFEN reads standard FEN, X-FEN and Shredder-FEN, including boards that
+// aren't 8x8 as long as every rank has the same width.
+func ParseFEN(fen string) (*Position, error) {
+	fields := strings.Fields(fen)
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("invalid FEN %q: expected at least 4 fields", fen)
+	}
+
+	placement, pockets, drops := strings.Cut(fields[0], "[")
+	rows := strings.Split(placement, "/")
+	files := 0
+	grid := make([][]Piece, len(rows))
+	promoted := make([][]int, len(rows))
+	for i, row := range rows {
+		empty := 0
+		for j := 0; j < len(row); j++ {
+			c := row[j]
+			if '0' <= c && c <= '9' {
+				empty = empty*10 + int(c-'0')
+				continue
+			}
+			if c == '~' && len(grid[i]) > 0 {
+				promoted[i] = append(promoted[i], len(grid[i])-1)
+				continue
+			}
+			for ; empty > 0; empty-- {
+				grid[i] = append(grid[i], NoPiece)
+			}
+			piece := PieceFromLetter(c)
+			if piece == NoPiece {
+				return nil, fmt.Errorf("invalid FEN %q: unknown piece %q", fen, c)
+			}
+			grid[i] = append(grid[i], piece)
+		}
+		for ; empty > 0; empty-- {
+			grid[i] = append(grid[i], NoPiece)
+		}
+
+		if i == 0 {
+			files = len(grid[i])
+		} else if len(grid[i]) != files {
+			return nil, fmt.Errorf("invalid FEN %q: rank %d has %d files instead of %d", fen, len(rows)-i, len(grid[i]), files)
+		}
+	}
+	if files < 1 || files > 16 || len(rows) > 16 {
+		return nil, fmt.Errorf("invalid FEN %q: unsupported board size %dx%d", fen, files, len(rows))
+	}
+
+	p := newPosition(files, len(rows))
+	for i, row := range grid {
+		for file, piece := range row {
+			p.board[NewSquare(file, p.ranks-1-i)] = piece
+		}
+		for _, file := range promoted[i] {
+			p.promoted[NewSquare(file, p.ranks-1-i)] = true
+		}
+	}
+
+	if drops {
+		p.drops = true
+		for _, c := range []byte(strings.TrimSuffix(pockets, "]")) {
+			piece := PieceFromLetter(c)
+			if piece == NoPiece || piece.Type() == King {
+				return nil, fmt.Errorf("invalid FEN %q: unknown pocket piece %q", fen, c)
+			}
+			p.pockets[piece.Color()][piece.Type()]++
+		}
+	}
+
+	switch fields[1] {
+	case "w":
+		p.turn = White
+	case "b":
+		p.turn = Black
+	default:
+		return nil, fmt.Errorf("invalid FEN %q: unknown side to move %q", fen, fields[1])
+	}
+
+	if err := p.parseCastling(fields[2]); err != nil {
+		return nil, fmt.Errorf("invalid FEN %q: %w", fen, err)
+	}
+
+	if fields[3] != "-" {
+		square, err := ParseSquare(fields[3])
+		if err != nil || !p.Contains(square) {
+			return nil, fmt.Errorf("invalid FEN %q: invalid en passant square %q", fen, fields[3])
+		}
+		p.enPassant = square
+	}
+
+	// Three-check positions carry the checks each side has left, as in 3+3
+	if len(fields) > 4 && strings.Contains(fields[4], "+") {
+		white, black, _ := strings.Cut(fields[4], "+")
+		whiteLeft, err1 := strconv.Atoi(white)
+		blackLeft, err2 := strconv.Atoi(black)
+		if err1 != nil || err2 != nil || whiteLeft < 0 || whiteLeft > checkLimit || blackLeft < 0 || blackLeft > checkLimit {
+			return nil, fmt.Errorf("invalid FEN %q: invalid remaining checks %q", fen, fields[4])
+		}
+		p.checks = [3]int{White: checkLimit - whiteLeft, Black: checkLimit - blackLeft}
+		fields = append(fields[:4], fields[5:]...)
+	}
+
+	if len(fields) > 4 {
+		halfMoves, err := strconv.Atoi(fields[4])
+		if err != nil {
+			return nil, fmt.Errorf("invalid FEN %q: invalid half move clock %q", fen, fields[4])
+		}
+		p.halfMoves = halfMoves
+	}
+	if len(fields) > 5 {
+		fullMoves, err := strconv.Atoi(fields[5])
+		if err != nil {
+			return nil, fmt.Errorf("invalid FEN %q: invalid move number %q", fen, fields[5])
+		}
+		p.fullMoves = fullMoves
+	}
+
+	return p, nil
+}
+
+func (p *Position) parseCastling(field string) error {
+	if field == "-" {
+		return nil
+	}
+
+	for i := 0; i < len(field); i++ {
+		c := field[i]
+		color, rank := White, 0
+		if 'a' <= c && c <= 'z' {
+			color, rank = Black, p.ranks-1
+			c -= 'a' - 'A'
+		}
+
+		king := p.KingSquare(color)
+		if king == NoSquare || king.Rank() != rank {
+			return fmt.Errorf("castling right %q without a king on the back rank", field[i])
+		}
+
+		rook := NoSquare
+		switch {
+		case c == 'K':
+			rook = p.outermostRook(color, king, 1)
+		case c == 'Q':
+			rook = p.outermostRook(color, king, -1)
+		case 'A' <= c && int(c-'A') < p.files:
+			rook = NewSquare(int(c-'A'), rank)
+			if p.board[rook] != NewPiece(Rook, color) {
+				rook = NoSquare
+			}
+		}
+		if rook == NoSquare {
+			return fmt.Errorf("castling right %q without a matching rook", field[i])
+		}
+
+		side := KingSide
+		if rook.File() < king.File() {
+			side = QueenSide
+		}
+		p.castling[color][side] = rook
+	}
+
+	return nil
+}
+
+func (p *Position) outermostRook(color Color, king Square, dir int) Square {
+	rook := NewPiece(Rook, color)
+	found := NoSquare
+	for file := king.File() + dir; 0 <= file && file < p.files; file += dir {
+		if square := NewSquare(file, king.Rank()); p.board[square] == rook {
+			found = square
+		}
+	}
+	return found
+}
+
+func (p *Position) String() string {
+	return p.FEN(XFEN)
+}
+
+func (p *Position) FEN(style CastlingStyle) string {
+	return fmt.Sprintf("%s %s %s %s%s %d %d",
+		p.placement(), p.turn, p.castlingString(style), p.enPassant, p.checksString(), p.halfMoves, p.fullMoves)
+}
+
+func (p *Position) checksString() string {
+	if p.variant != ThreeCheck {
+		return ""
+	}
+	return fmt.Sprintf(" %d+%d", checkLimit-p.checks[White], checkLimit-p.checks[Black])
+}
+
+func (p *Position) placement() string {
+	var sb strings.Builder
+	for rank := p.ranks - 1; rank >= 0; rank-- {
+		empty := 0
+		for file := 0; file < p.files; file++ {
+			piece := p.board[NewSquare(file, rank)]
+			if piece == NoPiece {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				sb.WriteString(strconv.Itoa(empty))
+				empty = 0
+			}
+			sb.WriteString(piece.String())
+			if p.promoted[NewSquare(file, rank)] {
+				sb.WriteByte('~')
+			}
+		}
+		if empty > 0 {
+			sb.WriteString(strconv.Itoa(empty))
+		}
+		if rank > 0 {
+			sb.WriteByte('/')
+		}
+	}
+
+	if p.drops {
+		sb.WriteByte('[')
+		for _, color := range []Color{White, Black} {
+			for t := Queen; t <= Pawn; t++ {
+				sb.WriteString(strings.Repeat(NewPiece(t, color).String(), p.pockets[color][t]))
+			}
+		}
+		sb.WriteByte(']')
+	}
+	return sb.String()
+}
+
+func (p *Position) castlingString(style CastlingStyle) string {
+	var sb strings.Builder
+	for _, color := range []Color{White, Black} {
+		king := p.KingSquare(color)
+		for _, side := range []Side{KingSide, QueenSide} {
+			rook := p.castling[color][side]
+			if rook == NoSquare {
+				continue
+			}
+
+			letter := byte('A' + rook.File())
+			if style != ShredderFEN {
+				dir := 1
+				if side == QueenSide {
+					dir = -1
+				}
+				if king != NoSquare && p.outermostRook(color, king, dir) == rook {
+					letter = "KQ"[side]
+				}
+			}
+			if color == Black {
+				letter += 'a' - 'A'
+			}
+			sb.WriteByte(letter)
+		}
+	}
+
+	if sb.Len() == 0 {
+		return "-"
+	}
+	return sb.String()
+}
@@ -0,0 +1,70 @@
+package rules
+
+import "testing"
+
+func TestFENRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		xfen     string
+		shredder string
+	}{
+		{
+			name:     "start",
+			xfen:     "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			shredder: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HAha - 0 1",
+		},
+		{
+			name:     "en passant and partial rights",
+			xfen:     "rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w Kq f6 0 3",
+			shredder: "rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w Ha f6 0 3",
+		},
+		{
+			name:     "chess960 outermost rooks",
+			xfen:     "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w Kk - 2 9",
+			shredder: "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w Hh - 2 9",
+		},
+		{
+			name:     "chess960 inner rook",
+			xfen:     "r2k1rr1/pppppppp/8/8/8/8/PPPPPPPP/R2K1RR1 w FQfq - 0 1",
+			shredder: "r2k1rr1/pppppppp/8/8/8/8/PPPPPPPP/R2K1RR1 w FAfa - 0 1",
+		},
+		{
+			name:     "no rights",
+			xfen:     "4k3/8/8/8/8/8/8/4K3 b - - 12 40",
+			shredder: "4k3/8/8/8/8/8/8/4K3 b - - 12 40",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, fen := range []string{test.xfen, test.shredder} {
+				position, err := ParseFEN(fen)
+				if err != nil {
+					t.Fatalf("ParseFEN(%q): %v", fen, err)
+				}
+				if got := position.FEN(XFEN); got != test.xfen {
+					t.Errorf("X-FEN of %q = %q, want %q", fen, got, test.xfen)
+				}
+				if got := position.FEN(ShredderFEN); got != test.shredder {
+					t.Errorf("Shredder-FEN of %q = %q, want %q", fen, got, test.shredder)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFENErrors(t *testing.T) {
+	for _, fen := range []string{
+		"",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w",
+		"rnbqkbnr/pppppppp/9/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR x KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBN1 w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq z9 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - x 1",
+	} {
+		if _, err := ParseFEN(fen); err == nil {
+			t.Errorf("ParseFEN(%q) didn't fail", fen)
+		}
+	}
+}
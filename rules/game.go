@@ -0,0 +1,393 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+)
+
+type Outcome string
+
+const (
+	NoOutcome Outcome = "*"
+	WhiteWon  Outcome = "1-0"
+	BlackWon  Outcome = "0-1"
+	Draw      Outcome = "1/2-1/2"
+)
+
+func (o Outcome) String() string {
+	return string(o)
+}
+
+func WinFor(color Color) Outcome {
+	switch color {
+	case White:
+		return WhiteWon
+	case Black:
+		return BlackWon
+	default:
+		return Draw
+	}
+}
+
+type Method int8
+
+const (
+	NoMethod Method = iota
+	Checkmate
+	Stalemate
+	Repetition
+	FiftyMoveRule
+	InsufficientMaterial
+	Resignation
+	Agreement
+	Timeout
+	KingInCenter
+	ThreeChecks
+	Explosion
+	AllPiecesCaptured
+	RaceFinished
+	Aborted
+)
+
+func (m Method) String() string {
+	switch m {
+	case Checkmate:
+		return "checkmate"
+	case Stalemate:
+		return "stalemate"
+	case Repetition:
+		return "repetition"
+	case FiftyMoveRule:
+		return "50-move rule"
+	case InsufficientMaterial:
+		return "insufficient material"
+	case Resignation:
+		return "resignation"
+	case Agreement:
+		return "agreement"
+	case Timeout:
+		return "timeout"
+	case KingInCenter:
+		return "king in the center"
+	case ThreeChecks:
+		return "three checks"
+	case Explosion:
+		return "explosion"
+	case AllPiecesCaptured:
+		return "all pieces captured"
+	case RaceFinished:
+		return "race finished"
+	case Aborted:
+		return "aborted"
+	default:
+		return ""
+	}
+}
+
+var (
+	ErrGameOver          = errors.New("game is over")
+	ErrNothingToTakeback = errors.New("no moves to take back")
+	ErrNothingToRedo     = errors.New("no moves to redo")
+	ErrIllegalMove       = errors.New("illegal move")
+)
+
+type Game struct {
+	positions []*Position
+	moves     []Move
+	undone    []Move
+	outcome   Outcome
+	method    Method
+}
+
+func NewGame(start *Position) *Game {
+	g := &Game{positions: []*Position{start}, outcome: NoOutcome}
+	g.updateOutcome()
+	return g
+}
+
+func NewGameFromFEN(fen string) (*Game, error) {
+	start, err := ParseFEN(fen)
+	if err != nil {
+		return nil, err
+	}
+	return NewGame(start), nil
+}
+
+func NewChess960Game(index int) (*Game, error) {
+	start, err := Chess960(index)
+	if err != nil {
+		return nil, err
+	}
+	return NewGame(start), nil
+}
+
+func NewCrazyhouseGame() *Game {
+	start, err := ParseFEN("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1")
+	if err != nil {
+		panic(err)
+	}
+	return NewGame(start)
+}
+
+// NewCapablancaGame starts a game of Capablanca chess on a 10x8 board, with an
+// archbishop and a chancellor next to the king and queen.
+func NewCapablancaGame() *Game {
+	start, err := ParseFEN(CapablancaFEN)
+	if err != nil {
+		panic(err)
+	}
+	return NewGame(start)
+}
+
+func (g *Game) Position() *Position {
+	return g.positions[len(g.positions)-1]
+}
+
+func (g *Game) Positions() []*Position {
+	return append([]*Position(nil), g.positions...)
+}
+
+func (g *Game) Moves() []Move {
+	return append([]Move(nil), g.moves...)
+}
+
+func (g *Game) LastMove() (Move, bool) {
+	if len(g.moves) == 0 {
+		return NoMove, false
+	}
+	return g.moves[len(g.moves)-1], true
+}
+
+func (g *Game) Outcome() (Outcome, Method) {
+	return g.outcome, g.method
+}
+
+func (g *Game) Move(move Move) error {
+	if g.outcome != NoOutcome {
+		return ErrGameOver
+	}
+
+	position := g.Position()
+	if !position.IsLegal(move) {
+		return fmt.Errorf("%w %s in %s", ErrIllegalMove, move, position)
+	}
+
+	g.apply(move)
+	g.undone = g.undone[:0]
+	return nil
+}
+
+func (g *Game) apply(move Move) {
+	g.moves = append(g.moves, move)
+	g.positions = append(g.positions, g.Position().Update(move))
+	g.updateOutcome()
+}
+
+func (g *Game) Takeback() (Move, error) {
+	if len(g.moves) == 0 {
+		return NoMove, ErrNothingToTakeback
+	}
+
+	move := g.moves[len(g.moves)-1]
+	g.moves = g.moves[:len(g.moves)-1]
+	g.positions = g.positions[:len(g.positions)-1]
+	g.outcome, g.method = NoOutcome, NoMethod
+	g.updateOutcome()
+	g.undone = append(g.undone, move)
+	return move, nil
+}
+
+func (g *Game) Redo() (Move, error) {
+	if len(g.undone) == 0 {
+		return NoMove, ErrNothingToRedo
+	}
+
+	move := g.undone[len(g.undone)-1]
+	g.undone = g.undone[:len(g.undone)-1]
+	g.apply(move)
+	return move, nil
+}
+
+func (g *Game) CanRedo() bool {
+	return len(g.undone) > 0
+}
+
+func (g *Game) Files() int {
+	return g.Position().Files()
+}
+
+func (g *Game) Ranks() int {
+	return g.Position().Ranks()
+}
+
+func (g *Game) Piece(square Square) Piece {
+	return g.Position().Piece(square)
+}
+
+func (g *Game) Turn() Color {
+	return g.Position().Turn()
+}
+
+func (g *Game) LegalMoves(from Square) []Move {
+	if g.outcome != NoOutcome {
+		return nil
+	}
+	return g.Position().LegalMovesFrom(from)
+}
+
+func (g *Game) Drops() bool {
+	return g.Position().Drops()
+}
+
+func (g *Game) Pocket(color Color) Pocket {
+	return g.Position().Pocket(color)
+}
+
+func (g *Game) LegalDrops(piece Piece) []Move {
+	if g.outcome != NoOutcome || piece.Color() != g.Turn() {
+		return nil
+	}
+
+	var drops []Move
+	for _, move := range g.Position().dropMoves() {
+		if move.Drop == piece.Type() {
+			drops = append(drops, move)
+		}
+	}
+	return drops
+}
+
+func (g *Game) Variant() Variant {
+	return g.Position().Variant()
+}
+
+func (g *Game) Goal() []Square {
+	return g.Position().Goal()
+}
+
+func (g *Game) Checks(color Color) int {
+	return g.Position().Checks(color)
+}
+
+func (g *Game) CheckLimit() int {
+	if g.Variant() != ThreeCheck {
+		return 0
+	}
+	return checkLimit
+}
+
+func (g *Game) Explosion(move Move) []Square {
+	return g.Position().Explosion(move)
+}
+
+func (g *Game) ForcedCaptures() []Move {
+	if g.outcome != NoOutcome {
+		return nil
+	}
+	return g.Position().ForcedCaptures()
+}
+
+func (g *Game) InCheck() bool {
+	return g.Position().InCheck()
+}
+
+func (g *Game) Hash() uint64 {
+	return g.Position().Hash64()
+}
+
+func (g *Game) FEN() string {
+	return g.Position().String()
+}
+
+func (g *Game) Resign(color Color) {
+	if g.outcome == NoOutcome {
+		g.outcome, g.method = WinFor(color.Other()), Resignation
+	}
+}
+
+func (g *Game) AgreeDraw() {
+	if g.outcome == NoOutcome {
+		g.outcome, g.method = Draw, Agreement
+	}
+}
+
+func (g *Game) Timeout(color Color) {
+	if g.outcome == NoOutcome {
+		g.outcome, g.method = WinFor(color.Other()), Timeout
+	}
+}
+
+// give puts a piece into a pocket, as when a partner captures it on another
+// board.
+func (g *Game) give(piece Piece) {
+	next := *g.Position()
+	next.pockets[piece.Color()][piece.Type()]++
+	g.positions[len(g.positions)-1] = &next
+}
+
+func (g *Game) updateOutcome() {
+	position := g.Position()
+	if position.linked {
+		// a side without moves can wait for pieces from the partner board
+		if position.InCheck() && len(position.LegalMoves()) == 0 {
+			g.outcome, g.method = WinFor(position.Turn().Other()), Checkmate
+		}
+		return
+	}
+
+	if outcome, method := position.variantOutcome(); outcome != NoOutcome {
+		g.outcome, g.method = outcome, method
+		return
+	}
+
+	if len(position.LegalMoves()) == 0 {
+		switch {
+		case position.Variant() == Antichess && !position.hasPieces(position.Turn()):
+			g.outcome, g.method = WinFor(position.Turn()), AllPiecesCaptured
+		case position.Variant() == Antichess:
+			g.outcome, g.method = WinFor(position.Turn()), Stalemate
+		case position.InCheck():
+			g.outcome, g.method = WinFor(position.Turn().Other()), Checkmate
+		default:
+			g.outcome, g.method = Draw, Stalemate
+		}
+		return
+	}
+
+	switch {
+	case position.HalfMoveClock() >= 100:
+		g.outcome, g.method = Draw, FiftyMoveRule
+	case g.repetitions() >= 3:
+		g.outcome, g.method = Draw, Repetition
+	case position.Variant() == Standard && !position.hasMatingMaterial():
+		g.outcome, g.method = Draw, InsufficientMaterial
+	}
+}
+
+func (g *Game) repetitions() int {
+	current := g.Position().key()
+	count := 0
+	for _, position := range g.positions {
+		if position.key() == current {
+			count++
+		}
+	}
+	return count
+}
+
+func (p *Position) hasMatingMaterial() bool {
+	if p.drops {
+		return true
+	}
+
+	minors := 0
+	for _, square := range p.Squares() {
+		switch p.board[square].Type() {
+		case Pawn, Rook, Queen, Archbishop, Chancellor:
+			return true
+		case Bishop, Knight:
+			minors++
+		}
+	}
+	return minors > 1
+}
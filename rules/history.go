@@ -0,0 +1,159 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+type undoneMove struct {
+	move     *chess.Move
+	comments []string
+	outcome  chess.Outcome
+	method   chess.Method
+	from     [16]byte
+}
+
+// MoveStack takes moves back and replays them in place, so the game keeps
+// its notation, tags, comments and outcome. notnil/chess can't pop moves,
+// so the game is rebuilt from its own PGN on every step.
+type MoveStack struct {
+	undone []undoneMove
+	drawn  drawnGame
+}
+
+// drawnGame keeps the method of an automatic draw a redo ended in, as
+// notnil/chess only finds those when moves are played, not decoded, and has
+// no way to set them.
+type drawnGame struct {
+	hash   [16]byte
+	method chess.Method
+}
+
+// Method is the game's method, or the automatic draw a redo restored.
+func (s *MoveStack) Method(game *chess.Game) chess.Method {
+	method := game.Method()
+	if method == chess.NoMethod && game.Outcome() == chess.Draw && game.Position().Hash() == s.drawn.hash {
+		return s.drawn.method
+	}
+	return method
+}
+
+func (s *MoveStack) Takeback(game *chess.Game) (*chess.Move, error) {
+	moves := game.Moves()
+	if len(moves) == 0 {
+		return nil, ErrNothingToTakeback
+	}
+
+	last := len(moves) - 1
+	comments := game.Comments()
+	undone := undoneMove{
+		move:    moves[last],
+		outcome: game.Outcome(),
+		method:  s.Method(game),
+		from:    game.Positions()[last].Hash(),
+	}
+	if last < len(comments) {
+		undone.comments = comments[last]
+		comments = comments[:last]
+	}
+
+	if err := s.rebuild(game, moves[:last], comments, chess.NoOutcome, chess.NoMethod); err != nil {
+		return nil, err
+	}
+
+	s.undone = append(s.undone, undone)
+	return undone.move, nil
+}
+
+func (s *MoveStack) Redo(game *chess.Game) (*chess.Move, error) {
+	if len(s.undone) == 0 {
+		return nil, ErrNothingToRedo
+	}
+
+	undone := s.undone[len(s.undone)-1]
+	if game.Position().Hash() != undone.from {
+		s.Clear()
+		return nil, ErrNothingToRedo
+	}
+
+	moves := append(game.Moves(), undone.move)
+	comments := append(game.Comments(), undone.comments)
+	if err := s.rebuild(game, moves, comments, undone.outcome, undone.method); err != nil {
+		return nil, err
+	}
+
+	s.undone = s.undone[:len(s.undone)-1]
+	return undone.move, nil
+}
+
+func (s *MoveStack) CanRedo() bool {
+	return len(s.undone) > 0
+}
+
+func (s *MoveStack) Clear() {
+	clear(s.undone)
+	s.undone = s.undone[:0]
+}
+
+// rebuild decodes the moves and comments from PGN and sets the tags and the
+// method afterwards, since notnil/chess reads neither quoted tag values nor
+// methods back.
+func (s *MoveStack) rebuild(game *chess.Game, moves []*chess.Move, comments [][]string, outcome chess.Outcome, method chess.Method) error {
+	var pgn strings.Builder
+
+	tags := game.TagPairs()
+	if initial := game.Positions()[0].String(); initial != chess.StartingPosition().String() {
+		fmt.Fprintf(&pgn, "[FEN \"%s\"]\n", initial)
+	}
+	pgn.WriteString("\n")
+
+	for i, move := range moves {
+		pgn.WriteString(move.String())
+		if i < len(comments) {
+			for _, comment := range comments[i] {
+				fmt.Fprintf(&pgn, " { %s }", commentBraces.Replace(comment))
+			}
+		}
+		pgn.WriteString(" ")
+	}
+
+	// methods that aren't derived from the position are reapplied after decoding
+	deferred := method == chess.Resignation || method == chess.DrawOffer ||
+		method == chess.ThreefoldRepetition || method == chess.FiftyMoveRule
+	if deferred {
+		pgn.WriteString(chess.NoOutcome.String())
+	} else {
+		pgn.WriteString(outcome.String())
+	}
+
+	if err := game.UnmarshalText([]byte(pgn.String())); err != nil {
+		return fmt.Errorf("can't rebuild game: %w", err)
+	}
+	game.RemoveTagPair("FEN")
+	for _, tag := range tags {
+		game.AddTagPair(tag.Key, tag.Value)
+	}
+
+	switch method {
+	case chess.Resignation:
+		if outcome == chess.WhiteWon {
+			game.Resign(chess.Black)
+		} else {
+			game.Resign(chess.White)
+		}
+	case chess.DrawOffer, chess.ThreefoldRepetition, chess.FiftyMoveRule:
+		if err := game.Draw(method); err != nil {
+			return fmt.Errorf("can't restore draw: %w", err)
+		}
+	case chess.FivefoldRepetition, chess.SeventyFiveMoveRule, chess.InsufficientMaterial:
+		s.drawn = drawnGame{hash: game.Position().Hash(), method: method}
+	}
+
+	return nil
+}
+
+// commentBraces keeps comments from closing early, notnil/chess has no
+// escape for braces.
+var commentBraces = strings.NewReplacer("{", "(", "}", ")")
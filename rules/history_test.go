@@ -0,0 +1,138 @@
+package rules
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+func TestMoveStackKeepsGame(t *testing.T) {
+	game := chess.NewGame()
+	pgn := `[Event "Club \ championship"]
+[FEN "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1"]
+
+1. O-O { castles (early) } O-O-O { [%clk 0:01:00] } *`
+	if err := game.UnmarshalText([]byte(pgn)); err != nil {
+		t.Fatalf("can't read the game: %v", err)
+	}
+	game.AddTagPair("White", `Conan "The" O'Brien`)
+	game.AddTagPair("Annotator", `{curly} \ "quoted"`)
+
+	tags := func() []string {
+		var tags []string
+		for _, tag := range game.TagPairs() {
+			tags = append(tags, tag.Key+"="+tag.Value)
+		}
+		return tags
+	}
+	wantTags, wantComments, wantFEN := tags(), game.Comments(), game.FEN()
+
+	var s MoveStack
+	for range 2 {
+		if _, err := s.Takeback(game); err != nil {
+			t.Fatalf("Takeback: %v", err)
+		}
+	}
+	if got := game.FEN(); got != "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1" {
+		t.Errorf("FEN after the takebacks = %s", got)
+	}
+	if got := tags(); !slices.Equal(got, wantTags) {
+		t.Errorf("tags after the takebacks = %q, want %q", got, wantTags)
+	}
+
+	for range 2 {
+		if _, err := s.Redo(game); err != nil {
+			t.Fatalf("Redo: %v", err)
+		}
+	}
+	if got := game.FEN(); got != wantFEN {
+		t.Errorf("FEN after the redos = %s, want %s", got, wantFEN)
+	}
+	if got := tags(); !slices.Equal(got, wantTags) {
+		t.Errorf("tags after the redos = %q, want %q", got, wantTags)
+	}
+	if got := game.Comments(); !slices.EqualFunc(got, wantComments, slices.Equal) {
+		t.Errorf("comments after the redos = %q, want %q", got, wantComments)
+	}
+}
+
+func TestMoveStackKeepsOutcome(t *testing.T) {
+	shuffle := []string{"g1f3", "g8f6", "f3g1", "f6g8"}
+	tests := []struct {
+		name   string
+		fen    string
+		moves  []string
+		end    func(game *chess.Game) error
+		method chess.Method
+	}{
+		{"checkmate", "", []string{"f2f3", "e7e5", "g2g4", "d8h4"}, nil, chess.Checkmate},
+		{"stalemate", "7k/8/6K1/8/8/8/8/5Q2 w - - 0 1", []string{"f1f7"}, nil, chess.Stalemate},
+		{"resignation", "", []string{"e2e4"}, func(game *chess.Game) error {
+			game.Resign(chess.Black)
+			return nil
+		}, chess.Resignation},
+		{"agreement", "", []string{"e2e4"}, func(game *chess.Game) error {
+			return game.Draw(chess.DrawOffer)
+		}, chess.DrawOffer},
+		{"threefold repetition", "", slices.Concat(shuffle, shuffle), func(game *chess.Game) error {
+			return game.Draw(chess.ThreefoldRepetition)
+		}, chess.ThreefoldRepetition},
+		{"fifty moves", "4k3/8/8/8/8/8/8/R3K3 w - - 99 60", []string{"a1a2"}, func(game *chess.Game) error {
+			return game.Draw(chess.FiftyMoveRule)
+		}, chess.FiftyMoveRule},
+		{"fivefold repetition", "", slices.Concat(shuffle, shuffle, shuffle, shuffle), nil, chess.FivefoldRepetition},
+		{"seventy-five moves", "4k3/8/8/8/8/8/8/R3K3 w - - 149 80", []string{"a1a2"}, nil, chess.SeventyFiveMoveRule},
+		{"insufficient material", "4k3/8/8/8/8/8/4q3/4K3 w - - 0 1", []string{"e1e2"}, nil, chess.InsufficientMaterial},
+	}
+
+	for _, test := range tests {
+		game := chess.NewGame(chess.UseNotation(chess.UCINotation{}))
+		if test.fen != "" {
+			fen, err := chess.FEN(test.fen)
+			if err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+			game = chess.NewGame(fen, chess.UseNotation(chess.UCINotation{}))
+		}
+		for _, move := range test.moves {
+			if err := game.MoveStr(move); err != nil {
+				t.Fatalf("%s: can't play %s: %v", test.name, move, err)
+			}
+		}
+		if test.end != nil {
+			if err := test.end(game); err != nil {
+				t.Fatalf("%s: can't end the game: %v", test.name, err)
+			}
+		}
+		outcome := game.Outcome()
+		if game.Method() != test.method {
+			t.Fatalf("%s: the game ended by %s", test.name, game.Method())
+		}
+
+		var s MoveStack
+		if _, err := s.Takeback(game); err != nil {
+			t.Fatalf("%s: Takeback: %v", test.name, err)
+		}
+		if game.Outcome() != chess.NoOutcome {
+			t.Errorf("%s: outcome %s after the takeback", test.name, game.Outcome())
+		}
+		if _, err := s.Redo(game); err != nil {
+			t.Fatalf("%s: Redo: %v", test.name, err)
+		}
+		if game.Outcome() != outcome || s.Method(game) != test.method {
+			t.Errorf("%s: %s by %s after the redo, want %s by %s", test.name, game.Outcome(), s.Method(game), outcome, test.method)
+		}
+
+		// and again, from the restored game
+		if _, err := s.Takeback(game); err != nil {
+			t.Fatalf("%s: Takeback: %v", test.name, err)
+		}
+		if _, err := s.Redo(game); err != nil {
+			t.Fatalf("%s: Redo: %v", test.name, err)
+		}
+		if game.Outcome() != outcome || s.Method(game) != test.method {
+			t.Errorf("%s: %s by %s after the second redo, want %s by %s", test.name, game.Outcome(), s.Method(game), outcome, test.method)
+		}
+	}
+}
@@ -0,0 +1,578 @@
+package rules
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"slices"
+)
+
+type offset struct {
+	file, rank int
+}
+
+var (
+	orthogonal  = []offset{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	diagonal    = []offset{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+	allAround   = append(append([]offset(nil), orthogonal...), diagonal...)
+	knightJumps = []offset{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+	promoTypes  = []PieceType{Queen, Rook, Bishop, Knight}
+	fairyPromos = []PieceType{Queen, Chancellor, Archbishop, Rook, Bishop, Knight}
+
+	antichessPromos = []PieceType{Queen, Rook, Bishop, Knight, King}
+)
+
+type Position struct {
+	files     int
+	ranks     int
+	board     [256]Piece
+	turn      Color
+	castling  [3][2]Square // rook squares by color and side
+	enPassant Square
+	halfMoves int
+	fullMoves int
+	drops     bool
+	linked    bool // captures go to a partner board instead of the own pocket
+	pockets   [3]Pocket
+	promoted  [256]bool // promoted pieces go back to the pocket as pawns
+	variant   Variant
+	checks    [3]int
+}
+
+func newPosition(files, ranks int) *Position {
+	return &Position{
+		files:     files,
+		ranks:     ranks,
+		turn:      White,
+		castling:  [3][2]Square{{NoSquare, NoSquare}, {NoSquare, NoSquare}, {NoSquare, NoSquare}},
+		enPassant: NoSquare,
+		fullMoves: 1,
+	}
+}
+
+// Start positions of variants played on other boards, for NewGameFromFEN.
+const (
+	CapablancaFEN = "rnabqkbcnr/pppppppppp/10/10/10/10/PPPPPPPPPP/RNABQKBCNR w KQkq - 0 1"
+	GrandFEN      = "r8r/1nbqkcabn1/pppppppppp/10/10/10/10/PPPPPPPPPP/1NBQKCABN1/R8R w - - 0 1"
+	GardnerFEN    = "rnbqk/ppppp/5/PPPPP/RNBQK w - - 0 1"
+)
+
+func StartingPosition() *Position {
+	pos, err := ParseFEN("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	if err != nil {
+		panic(err)
+	}
+	return pos
+}
+
+func (p *Position) Files() int {
+	return p.files
+}
+
+func (p *Position) Ranks() int {
+	return p.ranks
+}
+
+func (p *Position) Turn() Color {
+	return p.turn
+}
+
+func (p *Position) EnPassant() Square {
+	return p.enPassant
+}
+
+func (p *Position) HalfMoveClock() int {
+	return p.halfMoves
+}
+
+func (p *Position) FullMoves() int {
+	return p.fullMoves
+}
+
+// Drops tells whether captured pieces go to a pocket and can be dropped, as
+// in crazyhouse.
+func (p *Position) Drops() bool {
+	return p.drops
+}
+
+func (p *Position) Pocket(color Color) Pocket {
+	return p.pockets[color]
+}
+
+func (p *Position) CastlingRook(color Color, side Side) Square {
+	return p.castling[color][side]
+}
+
+func (p *Position) Contains(square Square) bool {
+	return square >= 0 && square.File() < p.files && square.Rank() < p.ranks
+}
+
+func (p *Position) Piece(square Square) Piece {
+	if !p.Contains(square) {
+		return NoPiece
+	}
+	return p.board[square]
+}
+
+func (p *Position) Squares() []Square {
+	squares := make([]Square, 0, p.files*p.ranks)
+	for rank := 0; rank < p.ranks; rank++ {
+		for file := 0; file < p.files; file++ {
+			squares = append(squares, NewSquare(file, rank))
+		}
+	}
+	return squares
+}
+
+func (p *Position) KingSquare(color Color) Square {
+	king := NewPiece(King, color)
+	for _, square := range p.Squares() {
+		if p.board[square] == king {
+			return square
+		}
+	}
+	return NoSquare
+}
+
+// InCheck knows that there is no check in Antichess, and none between
+// touching kings in Atomic, as a king can't capture there.
+func (p *Position) InCheck() bool {
+	if p.variant == Antichess || (p.variant == Atomic && p.kingsTouch()) {
+		return false
+	}
+	king := p.KingSquare(p.turn)
+	return king != NoSquare && p.IsAttacked(king, p.turn.Other())
+}
+
+func (p *Position) IsAttacked(square Square, by Color) bool {
+	attacker := func(from Square, o offset, types ...PieceType) bool {
+		piece := p.Piece(p.step(from, o))
+		if piece == NoPiece || piece.Color() != by {
+			return false
+		}
+		for _, t := range types {
+			if piece.Type() == t {
+				return true
+			}
+		}
+		return false
+	}
+
+	pawnRank := -1
+	if by == Black {
+		pawnRank = 1
+	}
+	if attacker(square, offset{-1, pawnRank}, Pawn) || attacker(square, offset{1, pawnRank}, Pawn) {
+		return true
+	}
+
+	for _, o := range knightJumps {
+		if attacker(square, o, Knight, Archbishop, Chancellor) {
+			return true
+		}
+	}
+
+	for _, o := range allAround {
+		if attacker(square, o, King) {
+			return true
+		}
+
+		sliders := []PieceType{Rook, Queen, Chancellor}
+		if o.file != 0 && o.rank != 0 {
+			sliders = []PieceType{Bishop, Queen, Archbishop}
+		}
+		to := p.step(square, o)
+		for to != NoSquare && p.board[to] == NoPiece {
+			to = p.step(to, o)
+		}
+		if to != NoSquare && attacker(to, offset{}, sliders...) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *Position) LegalMoves() []Move {
+	var moves []Move
+	for _, move := range p.pseudoMoves() {
+		if p.isLegal(move) {
+			moves = append(moves, move)
+		}
+	}
+	moves = append(moves, p.castlingMoves()...)
+	moves = append(moves, p.dropMoves()...)
+
+	if p.variant == Antichess {
+		captures := slices.DeleteFunc(slices.Clone(moves), func(move Move) bool {
+			return !p.IsCapture(move)
+		})
+		if len(captures) > 0 {
+			return captures
+		}
+	}
+	return moves
+}
+
+func (p *Position) LegalMovesFrom(from Square) []Move {
+	var moves []Move
+	for _, move := range p.LegalMoves() {
+		if move.From == from {
+			moves = append(moves, move)
+		}
+	}
+	return moves
+}
+
+func (p *Position) IsLegal(move Move) bool {
+	for _, legal := range p.LegalMoves() {
+		if legal == move {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Position) IsCastling(move Move) bool {
+	if move.Drop != NoPieceType {
+		return false
+	}
+	piece := p.Piece(move.From)
+	target := p.Piece(move.To)
+	return piece.Type() == King && target.Type() == Rook && piece.Color() == target.Color()
+}
+
+func (p *Position) IsCapture(move Move) bool {
+	if move.Drop != NoPieceType {
+		return false
+	}
+	target := p.Piece(move.To)
+	if target != NoPiece && target.Color() != p.Piece(move.From).Color() {
+		return true
+	}
+	return p.Piece(move.From).Type() == Pawn && move.To == p.enPassant
+}
+
+// Captured returns the piece a move takes, the way it goes to a pocket:
+// promoted pieces turn back into pawns.
+func (p *Position) Captured(move Move) Piece {
+	if !p.IsCapture(move) {
+		return NoPiece
+	}
+
+	square := p.capturedSquare(move)
+	if p.promoted[square] {
+		return NewPiece(Pawn, p.board[square].Color())
+	}
+	return p.board[square]
+}
+
+func (p *Position) capturedSquare(move Move) Square {
+	if p.Piece(move.From).Type() == Pawn && move.To == p.enPassant {
+		return NewSquare(move.To.File(), move.From.Rank())
+	}
+	return move.To
+}
+
+func (p *Position) Update(move Move) *Position {
+	next := *p
+	piece := p.Piece(move.From)
+
+	next.enPassant = NoSquare
+	next.halfMoves++
+	if p.IsCapture(move) || piece.Type() == Pawn {
+		next.halfMoves = 0
+	}
+	if p.turn == Black {
+		next.fullMoves++
+	}
+	next.turn = p.turn.Other()
+
+	if move.Drop != NoPieceType {
+		next.board[move.To] = NewPiece(move.Drop, p.turn)
+		next.pockets[p.turn][move.Drop]--
+		return &next
+	}
+
+	if p.IsCastling(move) {
+		kingTo, rookTo := CastlingTargets(move.From, move.To, p.files)
+		rook := p.board[move.To]
+		next.board[move.From] = NoPiece
+		next.board[move.To] = NoPiece
+		next.board[kingTo] = piece
+		next.board[rookTo] = rook
+		next.castling[piece.Color()] = [2]Square{NoSquare, NoSquare}
+		for _, square := range []Square{move.From, move.To, kingTo, rookTo} {
+			next.promoted[square] = false
+		}
+		return &next
+	}
+
+	if captured := p.Captured(move); captured != NoPiece && p.drops {
+		if !p.linked {
+			next.pockets[p.turn][captured.Type()]++
+		}
+		next.promoted[p.capturedSquare(move)] = false
+	}
+
+	if piece.Type() == Pawn {
+		if move.To == p.enPassant {
+			next.board[NewSquare(move.To.File(), move.From.Rank())] = NoPiece
+		}
+		if diff := move.To.Rank() - move.From.Rank(); (diff == 2 || diff == -2) && move.From.Rank() == p.doubleStepRank() {
+			next.enPassant = NewSquare(move.From.File(), move.From.Rank()+diff/2)
+		}
+	}
+
+	next.board[move.From] = NoPiece
+	next.board[move.To] = piece
+	next.promoted[move.From] = false
+	next.promoted[move.To] = p.promoted[move.From]
+	if move.Promo != NoPieceType {
+		next.board[move.To] = NewPiece(move.Promo, piece.Color())
+		next.promoted[move.To] = p.drops
+	}
+
+	for _, square := range p.Explosion(move) {
+		next.board[square] = NoPiece
+	}
+
+	if piece.Type() == King {
+		next.castling[piece.Color()] = [2]Square{NoSquare, NoSquare}
+	}
+	for _, color := range []Color{White, Black} {
+		for side, rook := range next.castling[color] {
+			if rook != NoSquare && next.board[rook] != NewPiece(Rook, color) {
+				next.castling[color][side] = NoSquare
+			}
+		}
+	}
+
+	if p.variant == ThreeCheck && next.InCheck() {
+		next.checks[p.turn]++
+	}
+
+	return &next
+}
+
+func (p *Position) Hash() [16]byte {
+	return md5.Sum([]byte(p.key()))
+}
+
+func (p *Position) Hash64() uint64 {
+	hash := p.Hash()
+	return binary.LittleEndian.Uint64(hash[:8])
+}
+
+// key identifies the position for repetition purposes, so the move clocks are
+// left out.
+func (p *Position) key() string {
+	return fmt.Sprintf("%s %s %s %s%s", p.placement(), p.turn, p.castlingString(ShredderFEN), p.enPassant, p.checksString())
+}
+
+func (p *Position) dropMoves() []Move {
+	if !p.drops {
+		return nil
+	}
+
+	var moves []Move
+	for t := Queen; t <= Pawn; t++ {
+		if p.pockets[p.turn][t] == 0 {
+			continue
+		}
+		for _, to := range p.Squares() {
+			if p.board[to] != NoPiece || (t == Pawn && (to.Rank() == 0 || to.Rank() == p.ranks-1)) {
+				continue
+			}
+			if move := (Move{From: NoSquare, To: to, Drop: t}); p.isLegal(move) {
+				moves = append(moves, move)
+			}
+		}
+	}
+	return moves
+}
+
+func (p *Position) pseudoMoves() []Move {
+	var moves []Move
+	for _, from := range p.Squares() {
+		piece := p.board[from]
+		if piece == NoPiece || piece.Color() != p.turn {
+			continue
+		}
+
+		switch piece.Type() {
+		case Pawn:
+			moves = p.appendPawnMoves(moves, from)
+		case Knight:
+			moves = p.appendLeaperMoves(moves, from, knightJumps)
+		case King:
+			moves = p.appendLeaperMoves(moves, from, allAround)
+		case Bishop:
+			moves = p.appendSliderMoves(moves, from, diagonal)
+		case Rook:
+			moves = p.appendSliderMoves(moves, from, orthogonal)
+		case Queen:
+			moves = p.appendSliderMoves(moves, from, allAround)
+		case Archbishop:
+			moves = p.appendSliderMoves(moves, from, diagonal)
+			moves = p.appendLeaperMoves(moves, from, knightJumps)
+		case Chancellor:
+			moves = p.appendSliderMoves(moves, from, orthogonal)
+			moves = p.appendLeaperMoves(moves, from, knightJumps)
+		}
+	}
+	return moves
+}
+
+func (p *Position) step(from Square, o offset) Square {
+	file, rank := from.File()+o.file, from.Rank()+o.rank
+	if file < 0 || rank < 0 || file >= p.files || rank >= p.ranks {
+		return NoSquare
+	}
+	return NewSquare(file, rank)
+}
+
+func (p *Position) canLand(to Square) bool {
+	return to != NoSquare && (p.board[to] == NoPiece || p.board[to].Color() != p.turn)
+}
+
+func (p *Position) appendLeaperMoves(moves []Move, from Square, offsets []offset) []Move {
+	for _, o := range offsets {
+		if to := p.step(from, o); p.canLand(to) {
+			moves = append(moves, Move{From: from, To: to})
+		}
+	}
+	return moves
+}
+
+func (p *Position) appendSliderMoves(moves []Move, from Square, offsets []offset) []Move {
+	for _, o := range offsets {
+		for to := p.step(from, o); p.canLand(to); to = p.step(to, o) {
+			moves = append(moves, Move{From: from, To: to})
+			if p.board[to] != NoPiece {
+				break
+			}
+		}
+	}
+	return moves
+}
+
+// doubleStepRank is the rank the pawns of the side to move can make a double
+// step from: none on minichess boards and the third rank on Grand chess'
+// 10x10.
+func (p *Position) doubleStepRank() int {
+	rank := 1
+	switch {
+	case p.ranks < 8:
+		return -1
+	case p.ranks >= 10:
+		rank = 2
+	}
+	if p.turn == Black {
+		return p.ranks - 1 - rank
+	}
+	return rank
+}
+
+func (p *Position) appendPawnMoves(moves []Move, from Square) []Move {
+	forward, startRank, lastRank := 1, p.doubleStepRank(), p.ranks-1
+	if p.turn == Black {
+		forward, lastRank = -1, 0
+	}
+	// the horde's pawns on the first rank can make a double step as well
+	firstRank := p.variant == Horde && p.turn == White && from.Rank() == 0
+
+	appendPawnMove := func(to Square) {
+		if to.Rank() == lastRank {
+			for _, promo := range p.promotions() {
+				moves = append(moves, Move{From: from, To: to, Promo: promo})
+			}
+		} else {
+			moves = append(moves, Move{From: from, To: to})
+		}
+	}
+
+	if to := p.step(from, offset{0, forward}); to != NoSquare && p.board[to] == NoPiece {
+		appendPawnMove(to)
+		if from.Rank() == startRank || firstRank {
+			if to := p.step(to, offset{0, forward}); to != NoSquare && p.board[to] == NoPiece {
+				appendPawnMove(to)
+			}
+		}
+	}
+
+	for _, side := range []int{-1, 1} {
+		to := p.step(from, offset{side, forward})
+		if to == NoSquare {
+			continue
+		}
+		if target := p.board[to]; (target != NoPiece && target.Color() != p.turn) || to == p.enPassant {
+			appendPawnMove(to)
+		}
+	}
+
+	return moves
+}
+
+// promotions includes the fairy pieces on the ten file boards they're
+// played on, whether or not any are left, and the king in Antichess.
+func (p *Position) promotions() []PieceType {
+	switch {
+	case p.variant == Antichess:
+		return antichessPromos
+	case p.files == 10:
+		return fairyPromos
+	default:
+		return promoTypes
+	}
+}
+
+func (p *Position) isLegal(move Move) bool {
+	return p.isVariantLegal(move, p.Update(move))
+}
+
+func (p *Position) castlingMoves() []Move {
+	king := p.KingSquare(p.turn)
+	if king == NoSquare || p.InCheck() {
+		return nil
+	}
+
+	var moves []Move
+	for _, rook := range p.castling[p.turn] {
+		if rook == NoSquare || p.board[rook] != NewPiece(Rook, p.turn) || rook.Rank() != king.Rank() {
+			continue
+		}
+
+		kingTo, rookTo := CastlingTargets(king, rook, p.files)
+		lo := min(king.File(), rook.File(), kingTo.File(), rookTo.File())
+		hi := max(king.File(), rook.File(), kingTo.File(), rookTo.File())
+
+		free := true
+		for file := lo; file <= hi && free; file++ {
+			square := NewSquare(file, king.Rank())
+			free = square == king || square == rook || p.board[square] == NoPiece
+		}
+		if !free {
+			continue
+		}
+
+		empty := *p
+		empty.board[king] = NoPiece
+		empty.board[rook] = NoPiece
+		dir := 1
+		if kingTo.File() < king.File() {
+			dir = -1
+		}
+		safe := true
+		for file := king.File(); safe; file += dir {
+			safe = !empty.IsAttacked(NewSquare(file, king.Rank()), p.turn.Other())
+			if file == kingTo.File() {
+				break
+			}
+		}
+		if safe {
+			moves = append(moves, Move{From: king, To: rook})
+		}
+	}
+	return moves
+}
@@ -0,0 +1,93 @@
+package rules
+
+import (
+	"slices"
+	"testing"
+)
+
+func perft(p *Position, depth int) int {
+	moves := p.LegalMoves()
+	if depth == 1 {
+		return len(moves)
+	}
+
+	nodes := 0
+	for _, move := range moves {
+		nodes += perft(p.Update(move), depth-1)
+	}
+	return nodes
+}
+
+// The counts are the usual perft results, see
+// https://www.chessprogramming.org/Perft_Results and the Chess960 ones at
+// https://www.chessprogramming.org/Chess960_Perft_Results.
+var perftTests = []struct {
+	name  string
+	fen   string
+	nodes []int
+}{
+	{"start", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", []int{20, 400, 8902, 197281}},
+	{"kiwipete", "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", []int{48, 2039, 97862}},
+	{"position 3", "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", []int{14, 191, 2812, 43238}},
+	{"position 4", "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", []int{6, 264, 9467}},
+	{"position 5", "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8", []int{44, 1486, 62379}},
+	{"chess960 1", "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", []int{21, 528, 12189}},
+	{"chess960 2", "2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9", []int{21, 807, 18002}},
+	{"chess960 3", "b1q1rrkb/pppppppp/3nn3/8/P7/1PPP4/4PPPP/BQNNRKRB w GE - 1 9", []int{20, 479, 10471}},
+	{"chess960 4", "1nbbnrkr/p1p1ppp1/3p4/1p3P1p/3Pq2P/8/PPP1P1P1/QNBBNRKR w HFhf - 0 9", []int{28, 1120, 31058}},
+	// the boards of other sizes are counted by hand as far as that goes, and
+	// by this generator past that for Gardner's
+	{"capablanca", CapablancaFEN, []int{28, 784, 25228}},
+	{"grand", GrandFEN, []int{65, 4225, 259514}},
+	{"gardner", GardnerFEN, []int{7, 53, 506, 4775}},
+}
+
+func TestPerft(t *testing.T) {
+	for _, test := range perftTests {
+		t.Run(test.name, func(t *testing.T) {
+			position, err := ParseFEN(test.fen)
+			if err != nil {
+				t.Fatalf("ParseFEN: %v", err)
+			}
+
+			for depth, want := range test.nodes {
+				if testing.Short() && want > 20000 {
+					break
+				}
+				if got := perft(position, depth+1); got != want {
+					t.Errorf("perft(%d) = %d, want %d", depth+1, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPromotions(t *testing.T) {
+	tests := []struct {
+		fen  string
+		from Square
+		want []PieceType
+	}{
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", NewSquare(0, 6), []PieceType{Queen, Rook, Bishop, Knight}},
+		// Capablanca keeps its fairy promotions once they're captured
+		{"4k5/P9/10/10/10/10/10/4K5 w - - 0 1", NewSquare(0, 6), []PieceType{Queen, Chancellor, Archbishop, Rook, Bishop, Knight}},
+		{"4k/P4/5/5/K4 w - - 0 1", NewSquare(0, 3), []PieceType{Queen, Rook, Bishop, Knight}},
+	}
+
+	for _, test := range tests {
+		position, err := ParseFEN(test.fen)
+		if err != nil {
+			t.Fatalf("ParseFEN(%q): %v", test.fen, err)
+		}
+
+		var promos []PieceType
+		for _, move := range position.LegalMoves() {
+			if move.From == test.from {
+				promos = append(promos, move.Promo)
+			}
+		}
+		if !slices.Equal(promos, test.want) {
+			t.Errorf("%s: promotions %v, want %v", test.fen, promos, test.want)
+		}
+	}
+}
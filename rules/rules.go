@@ -0,0 +1,106 @@
+package rules
+
+// Rules is all a board needs to show and play a game, so any move generator
+// can drive it. Castling moves go from the king's square to the castling
+// rook's square.
+type Rules interface {
+	Piece(square Square) Piece
+	Turn() Color
+	LegalMoves(from Square) []Move
+	Move(move Move) error
+	LastMove() (Move, bool)
+	Hash() uint64
+}
+
+// The interfaces below are optional, a board makes use of them when the rules
+// implement them.
+
+type Checker interface {
+	InCheck() bool
+}
+
+type Outcomer interface {
+	Outcome() (Outcome, Method)
+}
+
+type Undoer interface {
+	Takeback() (Move, error)
+	Redo() (Move, error)
+	CanRedo() bool
+}
+
+type FENer interface {
+	FEN() string
+}
+
+// Geometry is implemented by rules played on boards other than 8x8.
+type Geometry interface {
+	Files() int
+	Ranks() int
+}
+
+// Dropper is implemented by rules of drop variants like crazyhouse, where
+// captured pieces go to a pocket and can be put back onto the board.
+type Dropper interface {
+	Drops() bool
+	Pocket(color Color) Pocket
+	LegalDrops(piece Piece) []Move
+}
+
+// Goaler is implemented by variants won by bringing the king onto some
+// squares, like King of the Hill and Racing Kings.
+type Goaler interface {
+	Goal() []Square
+}
+
+// CheckCounter is implemented by variants won by giving a number of checks,
+// a limit of zero means the checks aren't counted.
+type CheckCounter interface {
+	Checks(color Color) int
+	CheckLimit() int
+}
+
+// Exploder is implemented by variants where captures blow up the pieces
+// around them, like Atomic.
+type Exploder interface {
+	Explosion(move Move) []Square
+}
+
+// CaptureForcer is implemented by variants where taking is compulsory, like
+// Antichess.
+type CaptureForcer interface {
+	ForcedCaptures() []Move
+}
+
+// Ender is implemented by rules whose games can also end off the board.
+type Ender interface {
+	Resign(color Color)
+	AgreeDraw()
+}
+
+var (
+	_ Rules         = (*Game)(nil)
+	_ Checker       = (*Game)(nil)
+	_ Outcomer      = (*Game)(nil)
+	_ Undoer        = (*Game)(nil)
+	_ FENer         = (*Game)(nil)
+	_ Dropper       = (*Game)(nil)
+	_ Geometry      = (*Game)(nil)
+	_ Goaler        = (*Game)(nil)
+	_ CheckCounter  = (*Game)(nil)
+	_ Exploder      = (*Game)(nil)
+	_ CaptureForcer = (*Game)(nil)
+	_ Ender         = (*Game)(nil)
+
+	_ Rules    = (*BughouseBoard)(nil)
+	_ Checker  = (*BughouseBoard)(nil)
+	_ Outcomer = (*BughouseBoard)(nil)
+	_ FENer    = (*BughouseBoard)(nil)
+	_ Dropper  = (*BughouseBoard)(nil)
+
+	_ Rules    = (*ChessGame)(nil)
+	_ Checker  = (*ChessGame)(nil)
+	_ Outcomer = (*ChessGame)(nil)
+	_ Undoer   = (*ChessGame)(nil)
+	_ FENer    = (*ChessGame)(nil)
+)
@@ -0,0 +1,282 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type Color int8
+
+const (
+	NoColor Color = iota
+	White
+	Black
+)
+
+func (c Color) Other() Color {
+	switch c {
+	case White:
+		return Black
+	case Black:
+		return White
+	default:
+		return NoColor
+	}
+}
+
+func (c Color) String() string {
+	switch c {
+	case White:
+		return "w"
+	case Black:
+		return "b"
+	default:
+		return "-"
+	}
+}
+
+type PieceType int8
+
+const (
+	NoPieceType PieceType = iota
+	King
+	Queen
+	Rook
+	Bishop
+	Knight
+	Pawn
+	Archbishop // bishop and knight
+	Chancellor // rook and knight
+)
+
+var pieceTypeLetters = []byte{' ', 'k', 'q', 'r', 'b', 'n', 'p', 'a', 'c'}
+
+func PieceTypeFromLetter(letter byte) PieceType {
+	if 'A' <= letter && letter <= 'Z' {
+		letter += 'a' - 'A'
+	}
+	for t, l := range pieceTypeLetters {
+		if l == letter && t != int(NoPieceType) {
+			return PieceType(t)
+		}
+	}
+	return NoPieceType
+}
+
+func (t PieceType) String() string {
+	if t <= NoPieceType || int(t) >= len(pieceTypeLetters) {
+		return ""
+	}
+	return string(pieceTypeLetters[t])
+}
+
+// Piece values of the standard pieces match notnil/chess, so images indexed
+// by chess.Piece can be indexed by Piece as well. Fairy pieces come after
+// them, white and black in turns.
+type Piece int8
+
+const (
+	NoPiece Piece = iota
+	WhiteKing
+	WhiteQueen
+	WhiteRook
+	WhiteBishop
+	WhiteKnight
+	WhitePawn
+	BlackKing
+	BlackQueen
+	BlackRook
+	BlackBishop
+	BlackKnight
+	BlackPawn
+	WhiteArchbishop
+	BlackArchbishop
+	WhiteChancellor
+	BlackChancellor
+)
+
+const (
+	standardTypeCount = int(Pawn)
+	pieceTypeCount    = int(Chancellor)
+
+	// PieceCount is the size of a slice indexed by Piece.
+	PieceCount = int(BlackChancellor) + 1
+)
+
+func NewPiece(t PieceType, c Color) Piece {
+	switch {
+	case t == NoPieceType || int(t) > pieceTypeCount || c == NoColor:
+		return NoPiece
+	case int(t) > standardTypeCount:
+		piece := 2*standardTypeCount + 2*(int(t)-standardTypeCount-1) + 1
+		if c == Black {
+			piece++
+		}
+		return Piece(piece)
+	case c == White:
+		return Piece(t)
+	default:
+		return Piece(int(t) + standardTypeCount)
+	}
+}
+
+func PieceFromLetter(letter byte) Piece {
+	color := Black
+	if 'A' <= letter && letter <= 'Z' {
+		color = White
+	}
+	return NewPiece(PieceTypeFromLetter(letter), color)
+}
+
+func (p Piece) Type() PieceType {
+	switch {
+	case p <= NoPiece || int(p) >= PieceCount:
+		return NoPieceType
+	case int(p) > 2*standardTypeCount:
+		return PieceType(standardTypeCount + (int(p)-2*standardTypeCount+1)/2)
+	default:
+		return PieceType((int(p)-1)%standardTypeCount + 1)
+	}
+}
+
+func (p Piece) Color() Color {
+	switch {
+	case p <= NoPiece || int(p) >= PieceCount:
+		return NoColor
+	case int(p) > 2*standardTypeCount:
+		return Color(2 - int(p)%2)
+	case int(p) <= standardTypeCount:
+		return White
+	default:
+		return Black
+	}
+}
+
+// String returns the FEN letter of the piece.
+func (p Piece) String() string {
+	s := p.Type().String()
+	if p.Color() == White && len(s) > 0 {
+		return string(s[0] - 'a' + 'A')
+	}
+	return s
+}
+
+// Square packs the file into the low and the rank into the high four bits,
+// which leaves room for boards up to 16x16.
+type Square int16
+
+const NoSquare Square = -1
+
+func NewSquare(file, rank int) Square {
+	return Square(rank<<4 | file)
+}
+
+func ParseSquare(s string) (Square, error) {
+	if len(s) < 2 || s[0] < 'a' || s[0] > 'p' {
+		return NoSquare, fmt.Errorf("invalid square %q", s)
+	}
+	rank, err := strconv.Atoi(s[1:])
+	if err != nil || rank < 1 || rank > 16 {
+		return NoSquare, fmt.Errorf("invalid square %q", s)
+	}
+	return NewSquare(int(s[0]-'a'), rank-1), nil
+}
+
+func (s Square) File() int {
+	return int(s & 15)
+}
+
+func (s Square) Rank() int {
+	return int(s >> 4)
+}
+
+func (s Square) String() string {
+	if s == NoSquare {
+		return "-"
+	}
+	return fmt.Sprintf("%c%d", 'a'+s.File(), s.Rank()+1)
+}
+
+// Move is a move in UCI terms, except castling which always goes from the
+// king's square to the castling rook's square, so it is the same for standard
+// chess and Chess960. Drops have no From square and are written as N@f3.
+type Move struct {
+	From  Square
+	To    Square
+	Promo PieceType
+	Drop  PieceType
+}
+
+var NoMove = Move{From: NoSquare, To: NoSquare}
+
+func ParseMove(s string) (Move, error) {
+	if len(s) > 2 && s[1] == '@' {
+		drop := PieceTypeFromLetter(s[0])
+		to, err := ParseSquare(s[2:])
+		if drop == NoPieceType || drop == King || err != nil {
+			return NoMove, fmt.Errorf("invalid drop %q", s)
+		}
+		return Move{From: NoSquare, To: to, Drop: drop}, nil
+	}
+
+	if len(s) < 4 {
+		return NoMove, fmt.Errorf("invalid move %q", s)
+	}
+
+	split := 2
+	for split < len(s) && '0' <= s[split] && s[split] <= '9' {
+		split++
+	}
+	from, err := ParseSquare(s[:split])
+	if err != nil {
+		return NoMove, fmt.Errorf("invalid move %q: %w", s, err)
+	}
+
+	rest := s[split:]
+	promo := NoPieceType
+	if last := rest[len(rest)-1]; 'a' <= last && last <= 'z' && len(rest) > 2 {
+		if _, err := strconv.Atoi(rest[len(rest)-2 : len(rest)-1]); err == nil {
+			promo = PieceTypeFromLetter(last)
+			rest = rest[:len(rest)-1]
+		}
+	}
+	to, err := ParseSquare(rest)
+	if err != nil {
+		return NoMove, fmt.Errorf("invalid move %q: %w", s, err)
+	}
+
+	return Move{From: from, To: to, Promo: promo}, nil
+}
+
+func (m Move) String() string {
+	if m.Drop != NoPieceType {
+		return NewPiece(m.Drop, White).String() + "@" + m.To.String()
+	}
+	return m.From.String() + m.To.String() + m.Promo.String()
+}
+
+// Pocket counts the pieces of each type a side can drop.
+type Pocket [pieceTypeCount + 1]int
+
+func (p Pocket) Count(t PieceType) int {
+	if t <= NoPieceType || int(t) >= len(p) {
+		return 0
+	}
+	return p[t]
+}
+
+type Side int8
+
+const (
+	KingSide Side = iota
+	QueenSide
+)
+
+// CastlingTargets returns where the king and the rook end up after castling,
+// which is the same in standard chess and Chess960.
+func CastlingTargets(king, rook Square, files int) (kingTo, rookTo Square) {
+	if rook.File() > king.File() {
+		return NewSquare(files-2, king.Rank()), NewSquare(files-3, king.Rank())
+	}
+	return NewSquare(2, king.Rank()), NewSquare(3, king.Rank())
+}
@@ -0,0 +1,54 @@
+package rules
+
+// Engines and servers write castling as the king going two squares in
+// standard chess and onto its rook in Chess960, the functions below translate
+// between that and Move. Both need the position before the move.
+
+// ParseUCI reads a move in either castling notation.
+func ParseUCI(r Rules, s string) (Move, error) {
+	move, err := ParseMove(s)
+	if err != nil || move.Drop != NoPieceType {
+		return move, err
+	}
+
+	king := r.Piece(move.From)
+	if king.Type() != King || move.From.Rank() != move.To.Rank() {
+		return move, nil
+	}
+
+	legal := r.LegalMoves(move.From)
+	for _, m := range legal {
+		if m == move {
+			return move, nil
+		}
+	}
+	for _, m := range legal {
+		if r.Piece(m.To) != NewPiece(Rook, king.Color()) {
+			continue
+		}
+		if kingTo, _ := CastlingTargets(m.From, m.To, files(r)); kingTo == move.To {
+			return m, nil
+		}
+	}
+	return move, nil
+}
+
+// UCI writes a move with castling as the king going two squares, unless the
+// game is Chess960.
+func UCI(r Rules, move Move, chess960 bool) string {
+	king := r.Piece(move.From)
+	if chess960 || move.Drop != NoPieceType || king.Type() != King ||
+		r.Piece(move.To) != NewPiece(Rook, king.Color()) {
+		return move.String()
+	}
+
+	kingTo, _ := CastlingTargets(move.From, move.To, files(r))
+	return Move{From: move.From, To: kingTo}.String()
+}
+
+func files(r Rules) int {
+	if geometry, ok := r.(Geometry); ok {
+		return geometry.Files()
+	}
+	return 8
+}
@@ -0,0 +1,248 @@
+package rules
+
+import "fmt"
+
+type Variant int8
+
+const (
+	Standard Variant = iota
+	KingOfTheHill
+	ThreeCheck
+	Atomic
+	Antichess
+	Horde
+	RacingKings
+)
+
+var Variants = []Variant{Standard, KingOfTheHill, ThreeCheck, Atomic, Antichess, Horde, RacingKings}
+
+func (v Variant) String() string {
+	switch v {
+	case Standard:
+		return "Standard"
+	case KingOfTheHill:
+		return "King of the Hill"
+	case ThreeCheck:
+		return "Three-check"
+	case Atomic:
+		return "Atomic"
+	case Antichess:
+		return "Antichess"
+	case Horde:
+		return "Horde"
+	case RacingKings:
+		return "Racing Kings"
+	default:
+		return fmt.Sprintf("Variant(%d)", int8(v))
+	}
+}
+
+func (v Variant) StartingFEN() string {
+	switch v {
+	case ThreeCheck:
+		return "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 3+3 0 1"
+	case Antichess:
+		return "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1"
+	case Horde:
+		return "rnbqkbnr/pppppppp/8/1PP2PP1/PPPPPPPP/PPPPPPPP/PPPPPPPP/PPPPPPPP w kq - 0 1"
+	case RacingKings:
+		return "8/8/8/8/8/8/krbnNBRK/qrbnNBRQ w - - 0 1"
+	default:
+		return "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	}
+}
+
+// checkLimit is the number of checks that wins a game of Three-check.
+const checkLimit = 3
+
+func ParseVariantFEN(variant Variant, fen string) (*Position, error) {
+	p, err := ParseFEN(fen)
+	if err != nil {
+		return nil, err
+	}
+	p.variant = variant
+	return p, nil
+}
+
+func NewVariantGame(variant Variant) *Game {
+	g, err := NewVariantGameFromFEN(variant, variant.StartingFEN())
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+func NewVariantGameFromFEN(variant Variant, fen string) (*Game, error) {
+	start, err := ParseVariantFEN(variant, fen)
+	if err != nil {
+		return nil, err
+	}
+	return NewGame(start), nil
+}
+
+func (p *Position) Variant() Variant {
+	return p.variant
+}
+
+// Checks is the number of checks a color gave in Three-check.
+func (p *Position) Checks(color Color) int {
+	return p.checks[color]
+}
+
+// Goal lists the squares a king wins the game on: the center in King of the
+// Hill and the last rank in Racing Kings.
+func (p *Position) Goal() []Square {
+	var goal []Square
+	switch p.variant {
+	case KingOfTheHill:
+		for rank := p.ranks/2 - 1; rank <= p.ranks/2; rank++ {
+			for file := p.files/2 - 1; file <= p.files/2; file++ {
+				goal = append(goal, NewSquare(file, rank))
+			}
+		}
+	case RacingKings:
+		for file := 0; file < p.files; file++ {
+			goal = append(goal, NewSquare(file, p.ranks-1))
+		}
+	}
+	return goal
+}
+
+// Explosion lists the squares an Atomic capture clears: the capturing piece
+// and everything but pawns around the captured one.
+func (p *Position) Explosion(move Move) []Square {
+	if p.variant != Atomic || !p.IsCapture(move) {
+		return nil
+	}
+
+	squares := []Square{move.To}
+	if captured := p.capturedSquare(move); captured != move.To {
+		squares = append(squares, captured)
+	}
+	for _, o := range allAround {
+		square := p.step(move.To, o)
+		if square != NoSquare && p.board[square] != NoPiece && p.board[square].Type() != Pawn {
+			squares = append(squares, square)
+		}
+	}
+	return squares
+}
+
+// ForcedCaptures lists the moves of an Antichess position when taking is
+// compulsory.
+func (p *Position) ForcedCaptures() []Move {
+	if p.variant != Antichess {
+		return nil
+	}
+
+	var captures []Move
+	for _, move := range p.LegalMoves() {
+		if p.IsCapture(move) {
+			captures = append(captures, move)
+		}
+	}
+	return captures
+}
+
+func (p *Position) kingsTouch() bool {
+	white, black := p.KingSquare(White), p.KingSquare(Black)
+	if white == NoSquare || black == NoSquare {
+		return false
+	}
+	return max(abs(white.File()-black.File()), abs(white.Rank()-black.Rank())) == 1
+}
+
+// isVariantLegal tells whether the position after a move leaves the mover
+// within the rules of the variant.
+func (p *Position) isVariantLegal(move Move, next *Position) bool {
+	king := next.KingSquare(p.turn)
+	switch p.variant {
+	case Antichess:
+		return true
+	case Atomic:
+		if p.Piece(move.From).Type() == King && p.IsCapture(move) {
+			return false
+		}
+		if king == NoSquare {
+			return p.KingSquare(p.turn) == NoSquare
+		}
+		if next.KingSquare(p.turn.Other()) == NoSquare || next.kingsTouch() {
+			return true
+		}
+	case RacingKings:
+		if next.InCheck() {
+			return false
+		}
+	}
+	return king == NoSquare || !next.IsAttacked(king, p.turn.Other())
+}
+
+// variantOutcome finds the ends that come on top of checkmate and stalemate.
+func (p *Position) variantOutcome() (Outcome, Method) {
+	mover := p.turn.Other()
+	switch p.variant {
+	case KingOfTheHill:
+		king := p.KingSquare(mover)
+		for _, square := range p.Goal() {
+			if square == king {
+				return WinFor(mover), KingInCenter
+			}
+		}
+	case ThreeCheck:
+		if p.checks[mover] >= checkLimit {
+			return WinFor(mover), ThreeChecks
+		}
+	case Atomic:
+		if p.KingSquare(p.turn) == NoSquare {
+			return WinFor(mover), Explosion
+		}
+	case Horde:
+		if !p.hasPieces(White) {
+			return BlackWon, AllPiecesCaptured
+		}
+	case RacingKings:
+		return p.raceOutcome()
+	}
+	return NoOutcome, NoMethod
+}
+
+// raceOutcome gives black one more move to draw after the white king gets to
+// the last rank, making up for white moving first.
+func (p *Position) raceOutcome() (Outcome, Method) {
+	goal := p.ranks - 1
+	white := p.KingSquare(White).Rank() == goal
+	black := p.KingSquare(Black).Rank() == goal
+
+	switch {
+	case white && black:
+		return Draw, RaceFinished
+	case black:
+		return BlackWon, RaceFinished
+	case white && p.turn == White:
+		return WhiteWon, RaceFinished
+	case white:
+		for _, move := range p.LegalMovesFrom(p.KingSquare(Black)) {
+			if move.To.Rank() == goal {
+				return NoOutcome, NoMethod
+			}
+		}
+		return WhiteWon, RaceFinished
+	}
+	return NoOutcome, NoMethod
+}
+
+func (p *Position) hasPieces(color Color) bool {
+	for _, square := range p.Squares() {
+		if piece := p.board[square]; piece != NoPiece && piece.Color() == color {
+			return true
+		}
+	}
+	return false
+}
+
+func abs(val int) int {
+	if val < 0 {
+		return -val
+	}
+	return val
+}
@@ -0,0 +1,122 @@
+package rules
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestVariantPerft(t *testing.T) {
+	tests := []struct {
+		variant Variant
+		nodes   []int
+	}{
+		{KingOfTheHill, []int{20, 400, 8902}},
+		{ThreeCheck, []int{20, 400, 8902}},
+		{Atomic, []int{20, 400, 8902}},
+		{Antichess, []int{20, 400, 8067}},
+		{Horde, []int{8, 128, 1274}},
+		{RacingKings, []int{21, 421, 11264}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.variant.String(), func(t *testing.T) {
+			position, err := ParseVariantFEN(test.variant, test.variant.StartingFEN())
+			if err != nil {
+				t.Fatalf("ParseVariantFEN: %v", err)
+			}
+			for depth, want := range test.nodes {
+				if got := perft(position, depth+1); got != want {
+					t.Errorf("perft(%d) = %d, want %d", depth+1, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestVariantLegality(t *testing.T) {
+	tests := []struct {
+		name    string
+		variant Variant
+		fen     string
+		move    string
+		legal   bool
+	}{
+		{"standard kings can't touch", Standard, "8/8/8/8/8/3k4/8/3K4 w - - 0 1", "d1d2", false},
+		{"atomic kings can touch", Atomic, "8/8/8/8/8/3k4/8/3K4 w - - 0 1", "d1d2", true},
+		{"atomic kings don't capture", Atomic, "4k3/8/8/8/8/8/4p3/4K3 w - - 0 1", "e1e2", false},
+		{"atomic capture can't blow up the own king", Atomic, "4k3/8/8/8/8/8/3p4/3QK3 w - - 0 1", "d1d2", false},
+		{"atomic capture of the king beats a pin", Atomic, "3rk3/3p4/8/8/8/8/8/3QK3 w - - 0 1", "d1d7", true},
+		{"antichess captures are forced", Antichess, "rnbqkbnr/p1pppppp/8/1p6/8/4P3/PPPP1PPP/RNBQKBNR w - - 0 2", "e3e4", false},
+		{"antichess kings may walk into attacks", Antichess, "4k3/8/8/8/8/8/7r/3K4 w - - 0 1", "d1d2", true},
+		{"horde pawns double step from the first rank", Horde, "4k3/8/8/8/8/8/8/3P4 w - - 0 1", "d1d3", true},
+		{"racing kings don't give check", RacingKings, "8/8/8/8/8/k7/8/6RK w - - 0 1", "g1g3", false},
+		{"racing kings move on", RacingKings, "8/8/8/8/8/k7/8/6RK w - - 0 1", "g1g2", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			position, err := ParseVariantFEN(test.variant, test.fen)
+			if err != nil {
+				t.Fatalf("ParseVariantFEN: %v", err)
+			}
+			move, err := ParseMove(test.move)
+			if err != nil {
+				t.Fatalf("ParseMove: %v", err)
+			}
+			if got := position.IsLegal(move); got != test.legal {
+				t.Errorf("IsLegal(%s) = %t, want %t", move, got, test.legal)
+			}
+		})
+	}
+}
+
+func TestVariantOutcome(t *testing.T) {
+	tests := []struct {
+		name    string
+		variant Variant
+		fen     string
+		moves   []string
+		outcome Outcome
+		method  Method
+	}{
+		{"king of the hill", KingOfTheHill, "4k3/8/8/8/8/3K4/8/8 w - - 0 1", []string{"d3d4"}, WhiteWon, KingInCenter},
+		{"king near the hill", KingOfTheHill, "4k3/8/8/8/8/3K4/8/8 w - - 0 1", []string{"d3c4"}, NoOutcome, NoMethod},
+		{"third check", ThreeCheck, "4k3/8/8/8/8/8/8/4K2Q w - - 1+3 0 1", []string{"h1h5"}, WhiteWon, ThreeChecks},
+		{"second check", ThreeCheck, "4k3/8/8/8/8/8/8/4K2Q w - - 2+3 0 1", []string{"h1h5"}, NoOutcome, NoMethod},
+		{"atomic explosion", Atomic, "4k3/3p4/8/8/8/8/8/3QK3 w - - 0 1", []string{"d1d7"}, WhiteWon, Explosion},
+		{"antichess all pieces gone", Antichess, "8/8/8/8/8/8/p7/1R6 b - - 0 1", []string{"a2b1q"}, WhiteWon, AllPiecesCaptured},
+		{"antichess stalemate", Antichess, "8/8/8/8/8/p7/P7/8 b - - 0 1", nil, BlackWon, Stalemate},
+		{"horde wiped out", Horde, "4k3/8/8/8/8/8/8/r2P4 b - - 0 1", []string{"a1d1"}, BlackWon, AllPiecesCaptured},
+		{"race won", RacingKings, "8/6K1/8/8/8/8/k7/8 w - - 0 1", []string{"g7g8"}, WhiteWon, RaceFinished},
+		{"race black can catch up", RacingKings, "8/k5K1/8/8/8/8/8/8 w - - 0 1", []string{"g7g8"}, NoOutcome, NoMethod},
+		{"race drawn", RacingKings, "8/k5K1/8/8/8/8/8/8 w - - 0 1", []string{"g7g8", "a7a8"}, Draw, RaceFinished},
+		{"race black wins", RacingKings, "8/k7/8/8/8/8/6K1/8 b - - 0 1", []string{"a7a8"}, BlackWon, RaceFinished},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			game, err := NewVariantGameFromFEN(test.variant, test.fen)
+			if err != nil {
+				t.Fatalf("NewVariantGameFromFEN: %v", err)
+			}
+			for _, s := range test.moves {
+				move, err := ParseMove(s)
+				if err != nil {
+					t.Fatalf("ParseMove: %v", err)
+				}
+				if err := game.Move(move); err != nil {
+					t.Fatalf("Move(%s): %v", move, err)
+				}
+			}
+
+			if outcome, method := game.Outcome(); outcome != test.outcome || method != test.method {
+				t.Errorf("outcome = %s by %s, want %s by %s", outcome, method, test.outcome, test.method)
+			}
+			if test.outcome != NoOutcome {
+				if err := game.Move(Move{From: NewSquare(0, 0), To: NewSquare(0, 1)}); !errors.Is(err, ErrGameOver) {
+					t.Errorf("move after the end = %v, want %v", err, ErrGameOver)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package chessboard
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"image"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/failosof/chessboard/union"
+	"github.com/failosof/chessboard/util"
+)
+
+//go:embed assets
+var assets embed.FS
+
+// Theme is the look of a board and its pieces, which a widget can switch to
+// at runtime.
+type Theme struct {
+	Board      Board
+	BoardImage image.Image
+	Piece      Piece
+}
+
+func (t Theme) apply(c *Config) {
+	c.Board = t.Board
+	c.BoardImage = t.BoardImage
+	c.BoardImageSize = union.Size{}
+	if t.BoardImage != nil {
+		c.BoardImageSize = union.SizeFromMinPt(t.BoardImage.Bounds().Max)
+	}
+	c.Piece = t.Piece
+}
+
+type boardSource struct {
+	board Board
+	fsys  fs.FS
+	name  string // image in fsys, if any
+}
+
+// Themes lists the boards and piece sets to choose from. Boards are the
+// built-in presets and the images in the board folder of an asset tree,
+// piece sets are the folders under its pieces folder, like in the embedded
+// assets. Sets are loaded when picked.
+type Themes struct {
+	mu     sync.Mutex
+	boards map[string]boardSource
+	pieces map[string]fs.FS
+}
+
+func NewThemes() *Themes {
+	t := &Themes{
+		boards: map[string]boardSource{
+			"lichess-brown":  {board: LichessBrown},
+			"lichess-blue":   {board: LichessBlue},
+			"lichess-green":  {board: LichessGreen},
+			"chesscom-green": {board: ChessComGreen},
+			"chesscom-brown": {board: ChessComBrown},
+		},
+		pieces: make(map[string]fs.FS),
+	}
+
+	embedded, err := fs.Sub(assets, "assets")
+	if err != nil {
+		panic(err)
+	}
+	if err := t.AddFS(embedded); err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// AddDir adds the themes of a user folder laid out like the embedded assets,
+// with board images in board/ and piece sets in pieces/<name>/.
+func (t *Themes) AddDir(dir string) error {
+	if _, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("can't add themes from %q: %w", dir, err)
+	}
+	if err := t.AddFS(os.DirFS(dir)); err != nil {
+		return fmt.Errorf("can't add themes from %q: %w", dir, err)
+	}
+	return nil
+}
+
+func (t *Themes) AddFS(fsys fs.FS) error {
+	boards, err := readDir(fsys, "board")
+	if err != nil {
+		return err
+	}
+	pieces, err := readDir(fsys, "pieces")
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, entry := range boards {
+		ext := path.Ext(entry.Name())
+		if entry.IsDir() || !slices.Contains([]string{".png", ".jpg", ".jpeg"}, strings.ToLower(ext)) {
+			continue
+		}
+		t.boards[strings.TrimSuffix(entry.Name(), ext)] = boardSource{
+			board: LichessBrown,
+			fsys:  fsys,
+			name:  path.Join("board", entry.Name()),
+		}
+	}
+
+	for _, entry := range pieces {
+		if !entry.IsDir() {
+			continue
+		}
+		set, err := fs.Sub(fsys, path.Join("pieces", entry.Name()))
+		if err != nil {
+			return err
+		}
+		t.pieces[entry.Name()] = set
+	}
+
+	return nil
+}
+
+// readDir reads a folder that may be missing.
+func readDir(fsys fs.FS, name string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(fsys, name)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (t *Themes) Boards() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return sortedKeys(t.boards)
+}
+
+func (t *Themes) Pieces() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return sortedKeys(t.pieces)
+}
+
+func (t *Themes) Theme(board, pieces string) (theme Theme, err error) {
+	t.mu.Lock()
+	source, boardFound := t.boards[board]
+	set, piecesFound := t.pieces[pieces]
+	t.mu.Unlock()
+
+	if !boardFound {
+		return theme, fmt.Errorf("unknown board %q", board)
+	}
+	if !piecesFound {
+		return theme, fmt.Errorf("unknown piece set %q", pieces)
+	}
+
+	theme.Board = source.board
+	if source.fsys != nil {
+		theme.BoardImage, err = util.ReadImage(source.fsys, source.name)
+		if err != nil {
+			return theme, fmt.Errorf("can't load board %q: %w", board, err)
+		}
+	}
+
+	theme.Piece, err = LoadPiecesFS(set, nil)
+	if err != nil {
+		return theme, fmt.Errorf("can't load piece set %q: %w", pieces, err)
+	}
+	return theme, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -2,49 +2,43 @@ package util
 
 import (
 	"gioui.org/f32"
-	"github.com/notnil/chess"
+	"github.com/failosof/chessboard/rules"
 )
 
-func PointToSquare(point f32.Point, size float32, flipped bool) chess.Square {
+func PointToSquare(point f32.Point, size float32, files, ranks int, flipped bool) rules.Square {
 	scaled := point.Div(size)
 
 	var file, rank int
 	if flipped {
-		file = 7 - Floor(scaled.X)
+		file = files - 1 - Floor(scaled.X)
 		rank = Floor(scaled.Y)
 	} else {
 		file = Floor(scaled.X)
-		rank = 7 - Floor(scaled.Y)
+		rank = ranks - 1 - Floor(scaled.Y)
 	}
 
-	if (0 <= rank && rank < 8) && (0 <= file && file < 8) {
-		return chess.NewSquare(chess.File(file), chess.Rank(rank))
+	if (0 <= rank && rank < ranks) && (0 <= file && file < files) {
+		return rules.NewSquare(file, rank)
 	} else {
-		return chess.NoSquare
+		return rules.NoSquare
 	}
 }
 
-func SquareToPoint(square chess.Square, size float32, flipped bool) f32.Point {
+func SquareToPoint(square rules.Square, size float32, files, ranks int, flipped bool) f32.Point {
 	var file, rank float32
 	if flipped {
-		file = float32(7 - square%8)
-		rank = float32(square / 8)
+		file = float32(files - 1 - square.File())
+		rank = float32(square.Rank())
 	} else {
-		file = float32(square % 8)
-		rank = float32(7 - square/8)
+		file = float32(square.File())
+		rank = float32(ranks - 1 - square.Rank())
 	}
 	return f32.Pt(file*size, rank*size)
 }
 
-func IsPromotionMove(square chess.Square, piece chess.Piece) bool {
-	whitePromotes := piece.Color() == chess.White && square.Rank() == chess.Rank8
-	blackPromotes := piece.Color() == chess.Black && square.Rank() == chess.Rank1
-	return piece.Type() == chess.Pawn && (whitePromotes || blackPromotes)
-}
-
-func SquareColor(square chess.Square) chess.Color {
-	if ((square / 8) % 2) == (square % 2) {
-		return chess.Black
+func SquareColor(square rules.Square) rules.Color {
+	if square.Rank()%2 == square.File()%2 {
+		return rules.Black
 	}
-	return chess.White
+	return rules.White
 }
@@ -21,6 +21,14 @@ func DrawImage(ops *op.Ops, img image.Image, at image.Point, factor f32.Point) {
 	offset.Pop()
 }
 
+// DrawImageOp paints an image op as is, reusing the op keeps its texture
+// from being uploaded again.
+func DrawImageOp(ops *op.Ops, imageOp paint.ImageOp, at image.Point) {
+	defer op.Offset(at).Push(ops).Pop()
+	imageOp.Add(ops)
+	paint.PaintOp{}.Add(ops)
+}
+
 func DrawPane(ops *op.Ops, rect image.Rectangle, color color.NRGBA) {
 	defer clip.Rect(rect).Push(ops).Pop()
 	paint.Fill(ops, color)
@@ -2,10 +2,26 @@ package util
 
 import (
 	"image"
+	"image/color"
 	_ "image/png"
+	"io/fs"
+	"math"
 	"os"
+
+	"golang.org/x/image/draw"
 )
 
+// ReadImage opens an image of a file system, like an embedded one.
+func ReadImage(fsys fs.FS, name string) (image.Image, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	return img, err
+}
+
 func OpenImage(filename string) (image.Image, error) {
 	f, err := os.Open(filename)
 	if err != nil {
@@ -16,9 +32,58 @@ func OpenImage(filename string) (image.Image, error) {
 	return img, err
 }
 
+// Resize scales an image to size with a filter that keeps it smooth when
+// shrinking a lot, unlike the linear filtering of the GPU.
+func Resize(img image.Image, size image.Point) *image.RGBA {
+	dst := image.NewRGBA(image.Rectangle{Max: size})
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return dst
+}
+
+// ResizeToFit scales an image into a size x size square, keeping its aspect
+// ratio and centering it.
+func ResizeToFit(img image.Image, size int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(dst, Fit(img.Bounds().Size(), size), img, img.Bounds(), draw.Src, nil)
+	return dst
+}
+
+// Fit centers a rectangle of the aspect ratio of src in a size x size square.
+func Fit(src image.Point, size int) image.Rectangle {
+	if src.X <= 0 || src.Y <= 0 {
+		return image.Rect(0, 0, size, size)
+	}
+	w, h := size, size
+	if src.X > src.Y {
+		h = Round(float32(size*src.Y) / float32(src.X))
+	} else {
+		w = Round(float32(size*src.X) / float32(src.Y))
+	}
+	origin := image.Pt((size-w)/2, (size-h)/2)
+	return Rect(origin, image.Pt(w, h))
+}
+
 func Rect(origin, size image.Point) image.Rectangle {
 	return image.Rectangle{
 		Min: origin,
 		Max: origin.Add(size),
 	}
 }
+
+func RadialGradient(size int, c color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	radius := float64(size) / 2
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			distance := math.Hypot(float64(x)+0.5-radius, float64(y)+0.5-radius) / radius
+			intensity := 1 - distance/0.9
+			if intensity <= 0 {
+				continue
+			}
+			pixel := c
+			pixel.A = uint8(float64(c.A) * math.Min(intensity*1.2, 1))
+			img.SetNRGBA(x, y, pixel)
+		}
+	}
+	return img
+}
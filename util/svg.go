@@ -0,0 +1,86 @@
+package util
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+)
+
+// SVG is a parsed vector image that can be rasterized at any size. It may be
+// a cell of a grid drawn in one image, like a sheet of chess pieces.
+type SVG struct {
+	icon *oksvg.SvgIcon
+	mu   *sync.Mutex // the icon keeps its target size while drawing
+
+	column, row, columns, rows int
+}
+
+func ParseSVG(r io.Reader) (*SVG, error) {
+	icon, err := oksvg.ReadIconStream(r, oksvg.WarnErrorMode)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse SVG: %w", err)
+	}
+	return &SVG{icon: icon, mu: new(sync.Mutex), columns: 1, rows: 1}, nil
+}
+
+func OpenSVG(filename string) (*SVG, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseSVG(f)
+}
+
+func ReadSVG(fsys fs.FS, name string) (*SVG, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseSVG(f)
+}
+
+// Cell splits the image into a grid and returns one of its cells, sharing the
+// parsed image with the others.
+func (s *SVG) Cell(column, row, columns, rows int) *SVG {
+	cell := *s
+	cell.column, cell.row = column, row
+	cell.columns, cell.rows = columns, rows
+	return &cell
+}
+
+// Size is the size of the view box, rounded up.
+func (s *SVG) Size() image.Point {
+	w := s.icon.ViewBox.W / float64(s.columns)
+	h := s.icon.ViewBox.H / float64(s.rows)
+	return image.Pt(int(w+0.5), int(h+0.5))
+}
+
+// Rasterize draws the image into a size x size square, scaled to fit and
+// centered when it isn't square.
+func (s *SVG) Rasterize(size int) *image.RGBA {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	cellW := s.icon.ViewBox.W / float64(s.columns)
+	cellH := s.icon.ViewBox.H / float64(s.rows)
+	scale := float64(size) / max(cellW, cellH)
+	w, h := cellW*scale, cellH*scale
+	x := (float64(size)-w)/2 - float64(s.column)*w
+	y := (float64(size)-h)/2 - float64(s.row)*h
+	s.icon.SetTarget(x, y, float64(s.columns)*w, float64(s.rows)*h)
+
+	scanner := rasterx.NewScannerGV(size, size, img, img.Bounds())
+	// the neighbours of a cell would show in the margins
+	scanner.SetClip(Fit(image.Pt(int(cellW+0.5), int(cellH+0.5)), size))
+	s.icon.Draw(rasterx.NewDasher(size, size, scanner), 1)
+	return img
+}
@@ -28,6 +28,13 @@ func Min[T constraints.Ordered](a, b T) T {
 	}
 }
 
+func Abs[T constraints.Signed | constraints.Float](val T) T {
+	if val < 0 {
+		return -val
+	}
+	return val
+}
+
 func ToF32(pt image.Point) f32.Point {
 	return f32.Point{X: float32(pt.X), Y: float32(pt.Y)}
 }
@@ -0,0 +1,81 @@
+package chessboard
+
+import (
+	"slices"
+	"strconv"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"github.com/failosof/chessboard/rules"
+	"github.com/failosof/chessboard/util"
+)
+
+// layoutGoal marks the squares a king wins the game on.
+func (w *Widget) layoutGoal(gtx layout.Context) {
+	if goaler, ok := w.game.(rules.Goaler); ok {
+		for _, square := range goaler.Goal() {
+			w.markSquare(gtx, square, w.config.Color.Goal)
+		}
+	}
+}
+
+// layoutForcedCaptures marks the pieces that have to take.
+func (w *Widget) layoutForcedCaptures(gtx layout.Context) {
+	forcer, ok := w.game.(rules.CaptureForcer)
+	if !ok || !w.config.ShowHints {
+		return
+	}
+
+	var squares []rules.Square
+	for _, move := range forcer.ForcedCaptures() {
+		squares = append(squares, move.From)
+	}
+	slices.Sort(squares)
+	for _, square := range slices.Compact(squares) {
+		w.markSquare(gtx, square, w.config.Color.Warning)
+	}
+}
+
+// layoutExplosion previews what a capture on the hovered square blows up.
+func (w *Widget) layoutExplosion(gtx layout.Context) {
+	exploder, ok := w.game.(rules.Exploder)
+	if !ok || w.selectedSquare == rules.NoSquare || w.hoveredSquare == rules.NoSquare {
+		return
+	}
+
+	if move, ok := w.findMove(w.selectedSquare, w.hoveredSquare); ok {
+		for _, square := range exploder.Explosion(move) {
+			w.markSquare(gtx, square, w.config.Color.Danger)
+		}
+	}
+}
+
+// layoutCheckCounters shows how many checks each king took.
+func (w *Widget) layoutCheckCounters(gtx layout.Context) {
+	counter, ok := w.game.(rules.CheckCounter)
+	if !ok || counter.CheckLimit() == 0 {
+		return
+	}
+
+	for _, color := range []rules.Color{rules.White, rules.Black} {
+		king := rules.NewPiece(rules.King, color)
+		for _, square := range w.squares {
+			if w.curBoard[square] == king {
+				slot := util.Rect(w.squareOrigins[square].Pt, w.squareSize.Pt)
+				checks := counter.Checks(color.Other())
+				w.drawBadge(gtx, slot, strconv.Itoa(checks), w.config.Color.Check)
+			}
+		}
+	}
+}
+
+func (w *Widget) hover(gtx layout.Context, pos f32.Point) {
+	square := util.PointToSquare(pos, w.squareSize.Float, w.files, w.ranks, w.flipped)
+	if square != w.hoveredSquare {
+		w.hoveredSquare = square
+		if _, ok := w.game.(rules.Exploder); ok {
+			gtx.Execute(op.InvalidateCmd{})
+		}
+	}
+}
@@ -1,11 +1,12 @@
 package chessboard
 
 import (
+	"errors"
+	"fmt"
 	"image"
 	"image/color"
 	"log/slog"
 	"slices"
-	"strings"
 	"sync"
 	"time"
 
@@ -17,6 +18,7 @@ import (
 	"gioui.org/op"
 	"gioui.org/op/clip"
 	"gioui.org/widget/material"
+	"github.com/failosof/chessboard/rules"
 	"github.com/failosof/chessboard/union"
 	"github.com/failosof/chessboard/util"
 	"github.com/notnil/chess"
@@ -25,17 +27,25 @@ import (
 // todo: add inside coordinates
 // todo: add each square coordinates
 // todo: add pawn promotion
-// todo: animations
+
+var (
+	ErrNothingToTakeback = rules.ErrNothingToTakeback
+	ErrNothingToRedo     = rules.ErrNothingToRedo
+)
 
 type Widget struct {
 	th *material.Theme
 
 	config Config
 
-	redraw bool
+	// redraw rebuilds everything, when only the position changed just the
+	// changed squares are recorded again
+	redraw          bool
+	positionChanged bool
+	changedSquares  []rules.Square
 
-	curBoardSize  union.Size
-	prevBoardSize union.Size
+	curBoardSize  image.Point
+	prevBoardSize image.Point
 	squareSize    union.Size
 	hintSize      union.Size
 	pointerSize   union.Size
@@ -47,125 +57,248 @@ type Widget struct {
 	drawingAnno Annotation
 	annotations []*Annotation
 
+	files         int
+	ranks         int
+	squares       []rules.Square
 	squareOrigins []union.Point
 
-	pieceEventTargets []event.Filter
+	pieceEventTargets  []event.Filter
+	pocketEventTargets []event.Filter
 
 	coordsDrawingOp  op.CallOp
 	boardDrawingOp   op.CallOp
+	squaresImage     *image.RGBA
 	hintDrawingOp    op.CallOp
 	squareDrawingOps []*op.CallOp
 
 	dragID         pointer.ID
 	draggingPos    union.Point
-	selectedSquare chess.Square
-	selectedPiece  chess.Piece
-
-	flipped      bool
-	game         *chess.Game
-	curPosition  *chess.Position
-	prevPosition *chess.Position
-	promoteOn    chess.Square
+	selectedSquare rules.Square
+	hoveredSquare  rules.Square
+	selectedPiece  rules.Piece
+	droppedSquare  rules.Square
+	dropping       bool
+	pocketInset    int
+
+	flipped     bool
+	player      rules.Color
+	flagged     rules.Color
+	aborted     bool
+	dismissed   bool
+	checkSquare rules.Square
+	checkGlow   image.Image
+	game        rules.Rules
+	curBoard    []rules.Piece
+	prevBoard   []rules.Piece
+	curHash     uint64
+	prevHash    uint64
+	animation   animation
+	promoteOn   rules.Square
+	events      []Event
+	overlays    []*Overlay
 
 	mu sync.Mutex
 }
 
+// NewWidget starts with a standard game played by notnil/chess, SetRules
+// swaps in any other rules, like the rules package's own.
 func NewWidget(th *material.Theme, config Config) *Widget {
 	w := Widget{
 		th:                th,
 		config:            config,
 		pointerSize:       union.SizeFromInt(16), // assume for now
-		squareOrigins:     make([]union.Point, 64),
-		pieceEventTargets: make([]event.Filter, 64),
-		squareDrawingOps:  make([]*op.CallOp, 64),
-		selectedSquare:    chess.NoSquare,
-		selectedPiece:     chess.NoPiece,
+		squareOrigins:     make([]union.Point, 256),
+		pieceEventTargets: make([]event.Filter, 0, 64),
+		squareDrawingOps:  make([]*op.CallOp, 256),
+		curBoard:          make([]rules.Piece, 256),
+		prevBoard:         make([]rules.Piece, 256),
+		selectedSquare:    rules.NoSquare,
+		hoveredSquare:     rules.NoSquare,
+		selectedPiece:     rules.NoPiece,
+		droppedSquare:     rules.NoSquare,
 		annoType:          CircleAnno,
-		game:              chess.NewGame(chess.UseNotation(chess.UCINotation{})),
-		promoteOn:         chess.NoSquare,
+		game:              rules.FromChess(chess.NewGame()),
+		promoteOn:         rules.NoSquare,
+		checkSquare:       rules.NoSquare,
 	}
 
+	w.resize(8, 8)
+
 	return &w
 }
 
 func (w *Widget) Layout(gtx layout.Context) layout.Dimensions {
-	return CoordinatesStyle{
+	coordinates := CoordinatesStyle{
 		Type:     w.config.Coordinates,
 		Theme:    w.th,
 		FontSize: 16,
 		Flipped:  w.flipped,
-		Board:    w.layout,
+		Board:    w.layoutFrame,
+	}
+
+	w.mu.Lock()
+	w.updateGeometry()
+	coordinates.Files, coordinates.Ranks = w.files, w.ranks
+	coordinates.Border = gtx.Dp(w.config.Board.Border)
+	_, pockets := w.dropper()
+	w.pocketInset = coordinates.Inset(gtx) + coordinates.Border
+	w.mu.Unlock()
+
+	if !pockets {
+		return coordinates.Layout(gtx)
+	}
+	return PocketsStyle{
+		Inset: w.pocketInset,
+		Files: coordinates.Files,
+		Ranks: coordinates.Ranks,
+		Board: coordinates.Layout,
 	}.Layout(gtx)
 }
 
+// updateGeometry follows the board size of the config, or of the rules when
+// the config leaves it out.
+func (w *Widget) updateGeometry() {
+	files, ranks := w.config.Files, w.config.Ranks
+	if geometry, ok := w.game.(rules.Geometry); ok {
+		if files == 0 {
+			files = geometry.Files()
+		}
+		if ranks == 0 {
+			ranks = geometry.Ranks()
+		}
+	}
+	if files == 0 {
+		files = 8
+	}
+	if ranks == 0 {
+		ranks = 8
+	}
+
+	if files != w.files || ranks != w.ranks {
+		w.resize(files, ranks)
+	}
+}
+
+func (w *Widget) resize(files, ranks int) {
+	w.files, w.ranks = files, ranks
+	w.squares = w.squares[:0]
+	for rank := 0; rank < ranks; rank++ {
+		for file := 0; file < files; file++ {
+			w.squares = append(w.squares, rules.NewSquare(file, rank))
+		}
+	}
+	clear(w.curBoard)
+	w.prevHash = 0
+	w.squaresImage = nil
+	w.redraw = true
+}
+
 func (w *Widget) layout(gtx layout.Context) layout.Dimensions {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
-	w.curBoardSize = union.SizeFromMinPt(gtx.Constraints.Max)
-	w.curPosition = w.game.Position()
-	w.redraw = w.redraw || !w.curBoardSize.Eq(w.prevBoardSize) || w.positionChanged()
+	squareSize := min(float32(gtx.Constraints.Max.X)/float32(w.files), float32(gtx.Constraints.Max.Y)/float32(w.ranks))
+	w.curBoardSize = image.Pt(util.Round(squareSize*float32(w.files)), util.Round(squareSize*float32(w.ranks)))
+	w.curHash = w.game.Hash()
+	w.positionChanged = w.curHash != w.prevHash
+	w.redraw = w.redraw || w.curBoardSize != w.prevBoardSize
 	defer func() {
 		w.redraw = false
+		w.positionChanged = false
+		w.changedSquares = w.changedSquares[:0]
 		w.prevBoardSize = w.curBoardSize
-		w.prevPosition = w.curPosition
+		w.prevHash = w.curHash
 	}()
 
 	if w.redraw {
-		w.squareSize = union.SizeFromFloat(w.curBoardSize.Float / 8)
+		w.squareSize = union.SizeFromFloat(squareSize)
 		w.hintSize = union.SizeFromMinF32(w.squareSize.F32.Div(3))
 		w.draggingPos = union.PointFromF32(w.draggingPos.F32)
 
-		for square := chess.A1; square <= chess.H8; square++ {
-			w.squareOrigins[square] = union.PointFromF32(util.SquareToPoint(square, w.squareSize.Float, w.flipped))
+		for _, square := range w.squares {
+			w.squareOrigins[square] = union.PointFromF32(util.SquareToPoint(square, w.squareSize.Float, w.files, w.ranks, w.flipped))
 		}
+	}
 
+	if w.positionChanged {
+		w.updateBoard(gtx)
+	}
+
+	if w.redraw {
 		cache := new(op.Ops)
 		boardMacro := op.Record(cache)
-		factor := w.curBoardSize.F32.Div(w.config.BoardImageSize.Float)
-		util.DrawImage(cache, w.config.BoardImage, image.Point{}, factor)
+		w.squaresImage = drawBackground(cache, w.config, w.files, w.ranks, w.curBoardSize, w.squareRect, w.squaresImage)
 		w.boardDrawingOp = boardMacro.Stop()
 	}
 
+	boardClip := w.boardClip(gtx)
+	stack := boardClip.Push(gtx.Ops)
 	w.boardDrawingOp.Add(gtx.Ops)
+	stack.Pop()
+	w.layoutPockets(gtx)
 
-	defer clip.Rect(image.Rectangle{Max: w.curBoardSize.Pt}).Push(gtx.Ops).Pop()
+	defer boardClip.Push(gtx.Ops).Pop()
 	event.Op(gtx.Ops, w)
 
+	w.layoutGoal(gtx)
+	w.layoutOverlays(gtx, AboveBoardLayer)
+
 	if w.config.ShowLastMove {
-		lastMove := w.getLastMove()
-		if lastMove != nil {
-			w.markSquare(gtx, lastMove.S1(), w.config.Color.LastMove)
-			w.markSquare(gtx, lastMove.S2(), w.config.Color.LastMove)
+		if lastMove, ok := w.game.LastMove(); ok {
+			w.markSquare(gtx, lastMove.From, w.config.Color.LastMove)
+			w.markSquare(gtx, lastMove.To, w.config.Color.LastMove)
 		}
 	}
 
-	if w.selectedSquare != chess.NoSquare && w.selectedPiece.Color() == w.curPosition.Turn() {
+	if w.config.ShowCheck && w.checkSquare != rules.NoSquare {
+		if w.checkGlow == nil {
+			w.checkGlow = util.RadialGradient(128, w.config.Color.Check)
+		}
+		factor := w.squareSize.F32.Div(float32(w.checkGlow.Bounds().Dx()))
+		util.DrawImage(gtx.Ops, w.checkGlow, w.squareOrigins[w.checkSquare].Pt, factor)
+	}
+
+	if w.selectedSquare != rules.NoSquare && w.canMove(w.selectedPiece.Color()) {
 		w.markSquare(gtx, w.selectedSquare, util.GrayColor)
 		if w.config.ShowHints {
-			for _, move := range w.curPosition.ValidMoves() {
-				if move.S1() == w.selectedSquare {
-					position := w.squareOrigins[move.S2()]
-					if w.curPosition.Board().Piece(move.S2()) == chess.NoPiece {
-						origin := position.F32.Add(w.squareSize.Half.F32).Sub(w.hintSize.Half.F32).Round()
-						util.DrawEllipse(gtx.Ops, util.Rect(origin, w.hintSize.Pt), w.config.Color.Hint)
-					} else {
-						rect := util.Rect(position.Pt, w.squareSize.Pt)
-						util.DrawRectangle(gtx.Ops, rect, w.squareSize.Float/5, w.config.Color.Hint)
-					}
+			for _, target := range w.moveTargets(w.selectedSquare) {
+				position := w.squareOrigins[target]
+				if w.curBoard[target] == rules.NoPiece || target == w.selectedSquare {
+					origin := position.F32.Add(w.squareSize.Half.F32).Sub(w.hintSize.Half.F32).Round()
+					util.DrawEllipse(gtx.Ops, util.Rect(origin, w.hintSize.Pt), w.config.Color.Hint)
+				} else {
+					rect := util.Rect(position.Pt, w.squareSize.Pt)
+					util.DrawRectangle(gtx.Ops, rect, w.squareSize.Float/5, w.config.Color.Hint)
 				}
 			}
 		}
 	}
 
+	if dropper, ok := w.dropper(); ok && w.dropping && w.config.ShowHints {
+		for _, move := range dropper.LegalDrops(w.selectedPiece) {
+			origin := w.squareOrigins[move.To].F32.Add(w.squareSize.Half.F32).Sub(w.hintSize.Half.F32).Round()
+			util.DrawEllipse(gtx.Ops, util.Rect(origin, w.hintSize.Pt), w.config.Color.Hint)
+		}
+	}
+
+	w.layoutForcedCaptures(gtx)
+	w.layoutExplosion(gtx)
+
+	w.layoutOverlays(gtx, BelowPiecesLayer)
 	w.drawPieces(gtx)
+	w.layoutCheckCounters(gtx)
+	w.layoutOverlays(gtx, AbovePiecesLayer)
 
 	for _, anno := range w.annotations {
 		anno.Width = union.SizeFromFloat(w.squareSize.Float / 7)
+		if anno.th == nil {
+			anno.th = w.th // badges from another board or built by hand
+		}
 		anno.Draw(gtx, w.squareOrigins, w.squareSize, w.redraw)
 	}
 	w.drawingAnno.Width = union.SizeFromFloat(w.squareSize.Float / 9)
 	w.drawingAnno.Draw(gtx, w.squareOrigins, w.squareSize, w.drawingAnno.Type != NoAnno)
+	w.layoutOverlays(gtx, AboveAnnotationsLayer)
 
 	for {
 		ev, ok := gtx.Event(pointer.Filter{
@@ -180,6 +313,7 @@ func (w *Widget) layout(gtx layout.Context) layout.Dimensions {
 			switch e.Kind {
 			case pointer.Move:
 				pointer.CursorPointer.Add(gtx.Ops)
+				w.hover(gtx, e.Position)
 			case pointer.Drag:
 				if w.buttonPressed == pointer.ButtonSecondary {
 					w.processSecondaryButtonDragging(gtx, e)
@@ -187,7 +321,7 @@ func (w *Widget) layout(gtx layout.Context) layout.Dimensions {
 			case pointer.Press:
 				w.buttonPressed = e.Buttons
 				w.modifiersUsed = e.Modifiers
-				w.promoteOn = chess.NoSquare
+				w.promoteOn = rules.NoSquare
 				fallthrough
 			default:
 				if w.buttonPressed == pointer.ButtonPrimary {
@@ -209,7 +343,9 @@ func (w *Widget) layout(gtx layout.Context) layout.Dimensions {
 			switch e.Kind {
 			case pointer.Move:
 				pointer.CursorGrab.Add(gtx.Ops)
+				w.hover(gtx, e.Position)
 			case pointer.Drag:
+				w.hover(gtx, e.Position)
 				if w.buttonPressed == pointer.ButtonPrimary {
 					w.processPrimaryButtonDragging(gtx, e)
 				}
@@ -220,7 +356,7 @@ func (w *Widget) layout(gtx layout.Context) layout.Dimensions {
 	}
 
 	w.markSquare(gtx, w.promoteOn, util.GrayColor)
-	if w.promoteOn != chess.NoSquare {
+	if w.promoteOn != rules.NoSquare {
 		Promotion{
 			Position:   w.squareOrigins[w.promoteOn],
 			SquareSize: w.squareSize,
@@ -232,13 +368,290 @@ func (w *Widget) layout(gtx layout.Context) layout.Dimensions {
 		slog.Debug("draw promotion selection", "on", w.promoteOn)
 	}
 
-	return layout.Dimensions{Size: w.curBoardSize.Pt}
+	if w.config.ShowGameOver && w.gameOverShown() {
+		w.layoutGameOver(gtx)
+	}
+
+	return layout.Dimensions{Size: w.curBoardSize}
+}
+
+// SetRules lets any move generator drive the board. Check highlighting,
+// game over, takebacks and FEN only work when the rules implement the
+// matching optional interfaces of the rules package.
+func (w *Widget) SetRules(r rules.Rules) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.game = r
+	w.flagged = rules.NoColor
+	w.aborted = false
+	w.dismissed = false
+	w.redraw = true
+}
+
+// Play makes a move that didn't come from the board, like an opponent's or a
+// broadcast's. It's animated like moves made on the board, but doesn't emit
+// an event. Finished games take no more moves.
+func (w *Widget) Play(move rules.Move) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.over() {
+		return fmt.Errorf("can't play %s: %w", move, rules.ErrGameOver)
+	}
+	if err := w.game.Move(move); err != nil {
+		return fmt.Errorf("can't play %s: %w", move, err)
+	}
+
+	w.promoteOn = rules.NoSquare
+	w.selectedSquare = rules.NoSquare
+	w.selectedPiece = rules.NoPiece
+	w.dropping = false
+	w.dragID = 0
+	return nil
+}
+
+// SetConfig swaps the whole config, the board is redrawn with it on the next
+// frame.
+func (w *Widget) SetConfig(config Config) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	rasters.replace(w.config.Piece, config.Piece)
+	w.config = config
+	w.invalidate()
+}
+
+func (w *Widget) SetTheme(theme Theme) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	old := w.config.Piece
+	theme.apply(&w.config)
+	rasters.replace(old, w.config.Piece)
+	w.invalidate()
+}
+
+func (w *Widget) Config() Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.config
+}
+
+// invalidate drops everything drawn with the old config.
+func (w *Widget) invalidate() {
+	w.boardDrawingOp = op.CallOp{}
+	clear(w.squareDrawingOps)
+	w.squaresImage = nil
+	w.checkGlow = nil
+	w.redraw = true
+}
+
+func (w *Widget) Rules() rules.Rules {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.game
 }
 
 func (w *Widget) SetGame(game *chess.Game) {
+	w.SetRules(rules.FromChess(game))
+}
+
+// SetChess960 starts a Chess960 game from the start position with the given
+// Scharnagl number, 518 being the standard setup.
+func (w *Widget) SetChess960(index int) error {
+	game, err := rules.NewChess960Game(index)
+	if err != nil {
+		return fmt.Errorf("can't set up chess960 position: %w", err)
+	}
+	w.SetRules(game)
+	return nil
+}
+
+// SetFEN starts a game from a FEN, X-FEN or Shredder-FEN position, which also
+// covers Chess960 positions with castling rights.
+func (w *Widget) SetFEN(fen string) error {
+	game, err := rules.NewGameFromFEN(fen)
+	if err != nil {
+		return fmt.Errorf("can't set up position: %w", err)
+	}
+	w.SetRules(game)
+	return nil
+}
+
+func (w *Widget) FEN() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.fen()
+}
+
+func (w *Widget) AddAnnotation(anno Annotation) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	a := anno.Copy()
+	w.annotations = append(w.annotations, &a)
+}
+
+func (w *Widget) ClearAnnotations() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	clear(w.annotations)
+	w.annotations = nil
+}
+
+// SetAnnotations replaces the annotations, like ones drawn on another board.
+func (w *Widget) SetAnnotations(annos []Annotation) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	clear(w.annotations)
+	w.annotations = w.annotations[:0]
+	for _, anno := range annos {
+		a := anno.Copy()
+		w.annotations = append(w.annotations, &a)
+	}
+}
+
+func (w *Widget) Annotations() []Annotation {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	annos := make([]Annotation, 0, len(w.annotations))
+	for _, anno := range w.annotations {
+		annos = append(annos, anno.Copy())
+	}
+	return annos
+}
+
+// AddOverlay draws the overlay above the ones added before it, the returned
+// func removes it again. Overlays aren't compared, so they can be of any type.
+func (w *Widget) AddOverlay(overlay Overlay) (remove func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	added := &overlay
+	w.overlays = append(w.overlays, added)
+	w.redraw = true
+
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.overlays = slices.DeleteFunc(w.overlays, func(o *Overlay) bool {
+			return o == added
+		})
+		w.redraw = true
+	}
+}
+
+func (w *Widget) Update(gtx layout.Context) (Event, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.events) == 0 {
+		return Event{}, false
+	}
+
+	e := w.events[0]
+	w.events = w.events[1:]
+	return e, true
+}
+
+func (w *Widget) Takeback(gtx layout.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	undoer, ok := w.game.(rules.Undoer)
+	if !ok {
+		return fmt.Errorf("can't take back: %w", errors.ErrUnsupported)
+	}
+
+	move, err := undoer.Takeback()
+	if err != nil {
+		return err
+	}
+
+	w.unselectPiece(gtx)
+	w.flagged = rules.NoColor
+	w.aborted = false
+	w.dismissed = false
+	w.emit(TakebackEvent, move)
+	gtx.Execute(op.InvalidateCmd{})
+	return nil
+}
+
+func (w *Widget) Redo(gtx layout.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	undoer, ok := w.game.(rules.Undoer)
+	if !ok {
+		return fmt.Errorf("can't redo: %w", errors.ErrUnsupported)
+	}
+
+	move, err := undoer.Redo()
+	if err != nil {
+		return err
+	}
+
+	w.unselectPiece(gtx)
+	w.emit(RedoEvent, move)
+	gtx.Execute(op.InvalidateCmd{})
+	return nil
+}
+
+func (w *Widget) CanRedo() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	undoer, ok := w.game.(rules.Undoer)
+	return ok && undoer.CanRedo()
+}
+
+func (w *Widget) Timeout(color rules.Color) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if outcome, _ := w.gameOutcome(); outcome == rules.NoOutcome {
+		w.flagged = color
+		w.dismissed = false
+	}
+}
+
+// Abort stops the game without a result, the board takes no more moves.
+func (w *Widget) Abort() {
 	w.mu.Lock()
 	defer w.mu.Unlock()
-	w.game = game
+
+	if outcome, _ := w.outcome(); outcome == rules.NoOutcome {
+		w.aborted = true
+		w.dismissed = false
+	}
+}
+
+func (w *Widget) Resign(color rules.Color) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ender, ok := w.game.(rules.Ender)
+	if !ok {
+		return fmt.Errorf("can't resign: %w", errors.ErrUnsupported)
+	}
+	ender.Resign(color)
+	w.dismissed = false
+	return nil
+}
+
+func (w *Widget) AgreeDraw() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ender, ok := w.game.(rules.Ender)
+	if !ok {
+		return fmt.Errorf("can't agree to a draw: %w", errors.ErrUnsupported)
+	}
+	ender.AgreeDraw()
+	w.dismissed = false
+	return nil
+}
+
+func (w *Widget) Outcome() (rules.Outcome, rules.Method) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.outcome()
 }
 
 func (w *Widget) Flip(gtx layout.Context) {
@@ -252,8 +665,163 @@ func (w *Widget) Flip(gtx layout.Context) {
 	gtx.Execute(op.InvalidateCmd{})
 }
 
-func (w *Widget) positionChanged() bool {
-	return w.prevPosition != nil && w.prevPosition.Hash() != w.curPosition.Hash()
+func (w *Widget) SetFlipped(flipped bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flipped = flipped
+	w.redraw = true
+}
+
+// SetPlayer lets the board make moves for one color only, as when playing
+// against an engine or over a network. NoColor lets it move both.
+func (w *Widget) SetPlayer(color rules.Color) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.player = color
+}
+
+func (w *Widget) Player() rules.Color {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.player
+}
+
+// canMove tells whether the board may make a move for a color now.
+func (w *Widget) canMove(color rules.Color) bool {
+	return !w.over() && color == w.game.Turn() && (w.player == rules.NoColor || w.player == color)
+}
+
+func (w *Widget) Flipped() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.flipped
+}
+
+func (w *Widget) layoutOverlays(gtx layout.Context, layer Layer) {
+	if len(w.overlays) == 0 {
+		return
+	}
+
+	state := BoardState{
+		SquareOrigins: slices.Clone(w.squareOrigins),
+		SquareSize:    w.squareSize,
+		BoardSize:     w.curBoardSize,
+		Flipped:       w.flipped,
+		Pieces:        slices.Clone(w.curBoard),
+		Rules:         w.game,
+		Redraw:        w.redraw || w.positionChanged,
+	}
+	for _, overlay := range w.overlays {
+		(*overlay).Layout(gtx, layer, state)
+	}
+}
+
+func (w *Widget) emit(t EventType, move rules.Move) {
+	w.events = append(w.events, Event{
+		Type: t,
+		Move: move,
+		FEN:  w.fen(),
+	})
+}
+
+func (w *Widget) outcome() (rules.Outcome, rules.Method) {
+	if w.flagged != rules.NoColor {
+		return rules.WinFor(w.flagged.Other()), rules.Timeout
+	}
+	if w.aborted {
+		return rules.NoOutcome, rules.Aborted
+	}
+	return w.gameOutcome()
+}
+
+// over tells whether the game ended by its rules, the clock or an abort.
+func (w *Widget) over() bool {
+	outcome, method := w.outcome()
+	return outcome != rules.NoOutcome || method == rules.Aborted
+}
+
+func (w *Widget) gameOutcome() (rules.Outcome, rules.Method) {
+	if outcomer, ok := w.game.(rules.Outcomer); ok {
+		return outcomer.Outcome()
+	}
+	return rules.NoOutcome, rules.NoMethod
+}
+
+func (w *Widget) fen() string {
+	if fener, ok := w.game.(rules.FENer); ok {
+		return fener.FEN()
+	}
+	return ""
+}
+
+func (w *Widget) gameOverShown() bool {
+	return w.over() && !w.dismissed
+}
+
+func (w *Widget) layoutGameOver(gtx layout.Context) {
+	defer clip.Rect(image.Rectangle{Max: w.curBoardSize}).Push(gtx.Ops).Pop()
+	event.Op(gtx.Ops, &w.dismissed)
+
+	outcome, method := w.outcome()
+	GameOver{
+		Theme:      w.th,
+		Outcome:    outcome,
+		Method:     method,
+		BoardSize:  w.curBoardSize,
+		Background: w.config.Color.GameOver,
+		Foreground: util.WhiteColor,
+	}.Layout(gtx)
+
+	for {
+		ev, ok := gtx.Event(pointer.Filter{
+			Target: &w.dismissed,
+			Kinds:  pointer.Press,
+		})
+		if !ok {
+			break
+		}
+
+		if e, ok := ev.(pointer.Event); ok && e.Kind == pointer.Press {
+			w.dismissed = true
+			gtx.Execute(op.InvalidateCmd{})
+		}
+	}
+}
+
+// updateBoard takes a snapshot of the pieces and starts animating whatever
+// moved since the last one, except the piece that was dropped by hand.
+func (w *Widget) updateBoard(gtx layout.Context) {
+	w.prevBoard, w.curBoard = w.curBoard, w.prevBoard
+	for _, square := range w.squares {
+		w.curBoard[square] = w.game.Piece(square)
+		if w.curBoard[square] != w.prevBoard[square] {
+			w.changedSquares = append(w.changedSquares, square)
+		}
+	}
+
+	w.animation = animation{}
+	if w.config.AnimationSpeed > 0 && w.prevHash != 0 {
+		moves := diffBoards(w.prevBoard, w.curBoard, w.squares)
+		moves = slices.DeleteFunc(moves, func(move pieceMove) bool {
+			return move.from == w.droppedSquare
+		})
+		w.animation = animation{
+			moves:    moves,
+			start:    gtx.Now,
+			duration: w.config.AnimationSpeed,
+		}
+	}
+	w.droppedSquare = rules.NoSquare
+
+	w.checkSquare = rules.NoSquare
+	if checker, ok := w.game.(rules.Checker); ok && checker.InCheck() {
+		king := rules.NewPiece(rules.King, w.game.Turn())
+		for _, square := range w.squares {
+			if w.curBoard[square] == king {
+				w.checkSquare = square
+			}
+		}
+	}
 }
 
 func (w *Widget) drawPieces(gtx layout.Context) {
@@ -261,29 +829,28 @@ func (w *Widget) drawPieces(gtx layout.Context) {
 		return
 	}
 
+	// the piece images are scaled once and shared, so recording them is
+	// cheap
+	squares := w.changedSquares
 	if w.redraw {
 		clear(w.squareDrawingOps)
-		var wg sync.WaitGroup
-		for square := chess.A1; square <= chess.H8; square++ {
-			origin := w.squareOrigins[square]
-			if piece := w.curPosition.Board().Piece(square); piece != chess.NoPiece {
-				wg.Add(1)
-				go func(square chess.Square, piece chess.Piece) {
-					defer wg.Done()
-					factor := w.squareSize.F32.Div(w.config.Piece.Sizes[piece].Float)
-					cache := new(op.Ops)
-					squareMacro := op.Record(cache)
-					util.DrawImage(cache, w.config.Piece.Images[piece], origin.Pt, factor)
-					ops := squareMacro.Stop()
-					w.squareDrawingOps[square] = &ops
-				}(square, piece)
-			}
+		squares = w.squares
+	}
+	for _, square := range squares {
+		w.squareDrawingOps[square] = nil
+		if piece := w.curBoard[square]; piece != rules.NoPiece {
+			cache := new(op.Ops)
+			squareMacro := op.Record(cache)
+			w.config.Piece.Draw(cache, piece, w.squareOrigins[square].Pt, w.squareSize)
+			ops := squareMacro.Stop()
+			w.squareDrawingOps[square] = &ops
 		}
-		wg.Wait()
 	}
 
-	clear(w.pieceEventTargets)
-	for square := chess.A1; square <= chess.H8; square++ {
+	animating := w.animation.running(gtx.Now)
+
+	w.pieceEventTargets = w.pieceEventTargets[:0]
+	for _, square := range w.squares {
 		squareDrawingOp := w.squareDrawingOps[square]
 		if squareDrawingOp != nil {
 			origin := w.squareOrigins[square]
@@ -295,34 +862,99 @@ func (w *Widget) drawPieces(gtx layout.Context) {
 				Kinds:  pointer.Move | pointer.Drag | pointer.Release,
 			})
 
-			if square != w.selectedSquare {
+			if square != w.selectedSquare && !(animating && w.animation.moving(square)) {
 				squareDrawingOp.Add(gtx.Ops)
 			}
 		}
 	}
 
-	if w.selectedSquare != chess.NoSquare && w.promoteOn == chess.NoSquare {
-		img := w.config.Piece.Images[w.selectedPiece]
-		factor := w.squareSize.F32.Div(w.config.Piece.Sizes[w.selectedPiece].Float)
-		util.DrawImage(gtx.Ops, img, w.draggingPos.Pt, factor)
+	if animating {
+		progress := w.animation.progress(gtx.Now)
+		for _, move := range w.animation.moves {
+			from := w.squareOrigins[move.from].F32
+			to := w.squareOrigins[move.to].F32
+			w.config.Piece.Draw(gtx.Ops, move.piece, lerp(from, to, progress).Round(), w.squareSize)
+		}
+		gtx.Execute(op.InvalidateCmd{})
+	}
+
+	if (w.selectedSquare != rules.NoSquare || w.dropping) && w.promoteOn == rules.NoSquare {
+		w.config.Piece.Draw(gtx.Ops, w.selectedPiece, w.draggingPos.Pt, w.squareSize)
 	}
 }
 
+// findMove picks the legal move for a piece dropped on a square. Kings can
+// castle by being dropped onto their own rook, or onto the square they end up
+// on as long as that isn't an ordinary king move as well.
+func (w *Widget) findMove(from, to rules.Square) (rules.Move, bool) {
+	if from == rules.NoSquare || to == rules.NoSquare {
+		return rules.NoMove, false
+	}
+
+	moves := w.game.LegalMoves(from)
+	for _, move := range moves {
+		if move.To == to {
+			return move, true
+		}
+	}
+
+	for _, move := range moves {
+		if w.isCastling(move) {
+			if kingTo, _ := rules.CastlingTargets(move.From, move.To, w.files); kingTo == to {
+				return move, true
+			}
+		}
+	}
+
+	return rules.NoMove, false
+}
+
+// moveTargets lists the squares a piece can be dropped on, including the king
+// destinations of castling moves.
+func (w *Widget) moveTargets(from rules.Square) []rules.Square {
+	var targets []rules.Square
+	for _, move := range w.game.LegalMoves(from) {
+		targets = append(targets, move.To)
+		if w.isCastling(move) {
+			kingTo, _ := rules.CastlingTargets(move.From, move.To, w.files)
+			targets = append(targets, kingTo)
+		}
+	}
+	slices.Sort(targets)
+	return slices.Compact(targets)
+}
+
+func (w *Widget) isCastling(move rules.Move) bool {
+	king, rook := w.curBoard[move.From], w.curBoard[move.To]
+	return king.Type() == rules.King && rook.Type() == rules.Rook && king.Color() == rook.Color()
+}
+
 func (w *Widget) processPrimaryButtonClick(gtx layout.Context, e pointer.Event) {
-	hoveredSquare := util.PointToSquare(e.Position, w.squareSize.Float, w.flipped)
-	if hoveredSquare == chess.NoSquare {
+	hoveredSquare := util.PointToSquare(e.Position, w.squareSize.Float, w.files, w.ranks, w.flipped)
+	if hoveredSquare == rules.NoSquare {
+		return
+	}
+	hoveredPiece := w.curBoard[hoveredSquare]
+
+	if w.config.ShowGameOver && w.gameOverShown() {
+		w.unselectPiece(gtx)
 		return
 	}
-	hoveredPiece := w.game.Position().Board().Piece(hoveredSquare)
 
 	switch e.Kind {
 	case pointer.Press:
-		clear(w.annotations)
-		w.annotations = nil
+		// badges come from the app, only the drawn annotations go away
+		drawn := len(w.annotations)
+		w.annotations = slices.DeleteFunc(w.annotations, func(anno *Annotation) bool {
+			return anno.Type != BadgeAnno
+		})
+		if len(w.annotations) < drawn {
+			w.emit(AnnotateEvent, rules.NoMove)
+		}
 		w.drawingAnno.Type = NoAnno
 
-		if w.selectedPiece == chess.NoPiece || w.selectedPiece.Color() == hoveredPiece.Color() {
-			if hoveredPiece != chess.NoPiece {
+		if w.selectedPiece == rules.NoPiece || w.selectedPiece.Color() == hoveredPiece.Color() {
+			if _, castling := w.findMove(w.selectedSquare, hoveredSquare); hoveredPiece != rules.NoPiece && !castling {
 				w.selectPiece(gtx, e, hoveredPiece, hoveredSquare)
 				return
 			}
@@ -335,26 +967,29 @@ func (w *Widget) processPrimaryButtonClick(gtx layout.Context, e pointer.Event)
 			return
 		}
 
-		if w.selectedSquare != chess.NoSquare && w.selectedPiece != chess.NoPiece {
-			move := w.selectedSquare.String() + hoveredSquare.String()
-			for _, validMove := range w.game.ValidMoves() {
-				if strings.HasPrefix(validMove.String(), move) {
-					if util.IsPromotionMove(hoveredSquare, w.selectedPiece) {
-						w.promoteOn = hoveredSquare
-						gtx.Execute(op.InvalidateCmd{})
-						return
-					}
-
-					if err := w.game.MoveStr(move); err != nil {
-						slog.Error("can't make move", "err", err)
-						w.putSelectedPieceBack(gtx)
-					}
+		if w.dropping {
+			w.drop(gtx, hoveredSquare)
+			return
+		}
 
-					break
+		if w.selectedSquare != rules.NoSquare && w.canMove(w.selectedPiece.Color()) {
+			if move, ok := w.findMove(w.selectedSquare, hoveredSquare); ok {
+				if move.Promo != rules.NoPieceType {
+					w.promoteOn = hoveredSquare
+					gtx.Execute(op.InvalidateCmd{})
+					return
 				}
-			}
 
-			if hoveredPiece != chess.NoPiece {
+				if err := w.game.Move(move); err != nil {
+					slog.Error("can't make move", "err", err)
+					w.putSelectedPieceBack(gtx)
+				} else {
+					if e.Kind == pointer.Release {
+						w.droppedSquare = w.selectedSquare
+					}
+					w.emit(MoveEvent, move)
+				}
+			} else if hoveredPiece != rules.NoPiece {
 				w.selectPiece(gtx, e, hoveredPiece, hoveredSquare)
 				return
 			}
@@ -369,12 +1004,12 @@ func (w *Widget) processPrimaryButtonClick(gtx layout.Context, e pointer.Event)
 }
 
 func (w *Widget) processSecondaryButtonClick(gtx layout.Context, e pointer.Event) {
-	hoveredSquare := util.PointToSquare(e.Position, w.squareSize.Float, w.flipped)
+	hoveredSquare := util.PointToSquare(e.Position, w.squareSize.Float, w.files, w.ranks, w.flipped)
 	defer gtx.Execute(op.InvalidateCmd{At: gtx.Now.Add(time.Second / 30)})
 
 	switch e.Kind {
 	case pointer.Press:
-		if hoveredSquare != chess.NoSquare {
+		if hoveredSquare != rules.NoSquare {
 			w.drawingAnno = Annotation{
 				Type:  w.annoType,
 				Start: hoveredSquare,
@@ -386,7 +1021,7 @@ func (w *Widget) processSecondaryButtonClick(gtx layout.Context, e pointer.Event
 	case pointer.Release:
 		w.drawingAnno.Width = union.SizeFromFloat(w.squareSize.Float / 7)
 		w.drawingAnno.Color = w.selectAnnotationColor()
-		if hoveredSquare != chess.NoSquare {
+		if hoveredSquare != rules.NoSquare {
 			w.drawingAnno.End = hoveredSquare
 		}
 
@@ -411,6 +1046,7 @@ func (w *Widget) processSecondaryButtonClick(gtx layout.Context, e pointer.Event
 		} else {
 			w.annotations = append(w.annotations, &anno)
 		}
+		w.emit(AnnotateEvent, rules.NoMove)
 
 		w.drawingAnno = Annotation{}
 		w.dragID = 0
@@ -420,7 +1056,7 @@ func (w *Widget) processSecondaryButtonClick(gtx layout.Context, e pointer.Event
 }
 
 func (w *Widget) processPrimaryButtonDragging(gtx layout.Context, e pointer.Event) {
-	if w.dragID == e.PointerID && w.selectedSquare != chess.NoSquare {
+	if w.dragID == e.PointerID && w.selectedSquare != rules.NoSquare {
 		pointer.CursorGrabbing.Add(gtx.Ops)
 		w.dragTo(gtx, e.Position)
 	}
@@ -428,8 +1064,8 @@ func (w *Widget) processPrimaryButtonDragging(gtx layout.Context, e pointer.Even
 
 func (w *Widget) processSecondaryButtonDragging(gtx layout.Context, e pointer.Event) {
 	if w.drawingAnno.Type != NoAnno {
-		hoveredSquare := util.PointToSquare(e.Position, w.squareSize.Float, w.flipped)
-		if hoveredSquare != chess.NoSquare {
+		hoveredSquare := util.PointToSquare(e.Position, w.squareSize.Float, w.files, w.ranks, w.flipped)
+		if hoveredSquare != rules.NoSquare {
 			if w.dragID == e.PointerID {
 				w.drawingAnno.End = hoveredSquare
 				if w.drawingAnno.Start == w.drawingAnno.End {
@@ -443,10 +1079,11 @@ func (w *Widget) processSecondaryButtonDragging(gtx layout.Context, e pointer.Ev
 	}
 }
 
-func (w *Widget) selectPiece(gtx layout.Context, e pointer.Event, piece chess.Piece, square chess.Square) {
-	if piece != chess.NoPiece && square != chess.NoSquare {
+func (w *Widget) selectPiece(gtx layout.Context, e pointer.Event, piece rules.Piece, square rules.Square) {
+	if piece != rules.NoPiece && square != rules.NoSquare {
 		pointer.CursorGrabbing.Add(gtx.Ops)
 		w.dragID = e.PointerID
+		w.dropping = false
 		w.selectedPiece = piece
 		w.selectedSquare = square
 		w.dragTo(gtx, e.Position)
@@ -454,15 +1091,19 @@ func (w *Widget) selectPiece(gtx layout.Context, e pointer.Event, piece chess.Pi
 }
 
 func (w *Widget) dragTo(gtx layout.Context, pos f32.Point) {
-	w.draggingPos = union.PointFromF32(pos.Add(w.pointerSize.Half.F32).Sub(w.squareSize.Half.F32))
+	w.draggingPos = w.dragOrigin(pos)
 	gtx.Execute(pointer.GrabCmd{
 		Tag: w.selectedSquare,
 		ID:  w.dragID,
 	})
 }
 
+func (w *Widget) dragOrigin(pos f32.Point) union.Point {
+	return union.PointFromF32(pos.Add(w.pointerSize.Half.F32).Sub(w.squareSize.Half.F32))
+}
+
 func (w *Widget) putSelectedPieceBack(gtx layout.Context) {
-	if w.selectedSquare != chess.NoSquare {
+	if w.selectedSquare != rules.NoSquare {
 		w.draggingPos = w.squareOrigins[w.selectedSquare]
 	}
 
@@ -471,29 +1112,22 @@ func (w *Widget) putSelectedPieceBack(gtx layout.Context) {
 }
 
 func (w *Widget) unselectPiece(gtx layout.Context) {
-	if w.selectedSquare != chess.NoSquare {
+	if w.selectedSquare != rules.NoSquare {
 		w.draggingPos = w.squareOrigins[w.selectedSquare]
 	}
 
-	w.promoteOn = chess.NoSquare
-	w.selectedSquare = chess.NoSquare
-	w.selectedPiece = chess.NoPiece
+	w.promoteOn = rules.NoSquare
+	w.selectedSquare = rules.NoSquare
+	w.selectedPiece = rules.NoPiece
+	w.dropping = false
 	w.dragID = 0
 
 	pointer.CursorPointer.Add(gtx.Ops)
 	gtx.Execute(op.InvalidateCmd{At: gtx.Now.Add(time.Second / 25)})
 }
 
-func (w *Widget) getLastMove() (m *chess.Move) {
-	moves := w.game.Moves()
-	if len(moves) > 0 {
-		m = moves[len(moves)-1]
-	}
-	return
-}
-
-func (w *Widget) markSquare(gtx layout.Context, square chess.Square, color color.NRGBA) {
-	if square != chess.NoSquare {
+func (w *Widget) markSquare(gtx layout.Context, square rules.Square, color color.NRGBA) {
+	if square != rules.NoSquare {
 		origin := w.squareOrigins[square]
 		util.DrawPane(gtx.Ops, util.Rect(origin.Pt, w.squareSize.Pt), color)
 	}
@@ -0,0 +1,218 @@
+package chessboard
+
+import (
+	"errors"
+	"image"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/widget/material"
+	"github.com/failosof/chessboard/rules"
+)
+
+func TestGameOverStopsPlay(t *testing.T) {
+	tests := []struct {
+		name   string
+		end    func(w *Widget) error
+		method rules.Method
+	}{
+		{"timeout", func(w *Widget) error { w.Timeout(rules.White); return nil }, rules.Timeout},
+		{"abort", func(w *Widget) error { w.Abort(); return nil }, rules.Aborted},
+		{"resignation", func(w *Widget) error { return w.Resign(rules.White) }, rules.Resignation},
+		{"draw", func(w *Widget) error { return w.AgreeDraw() }, rules.Agreement},
+	}
+
+	e2e4, _ := rules.ParseMove("e2e4")
+	for _, test := range tests {
+		// the overlay is off, only the end itself stops the moves
+		w := NewWidget(material.NewTheme(), Config{})
+		if err := test.end(w); err != nil {
+			t.Fatalf("%s: can't end the game: %v", test.name, err)
+		}
+
+		if _, method := w.Outcome(); method != test.method {
+			t.Errorf("%s: ended by %s", test.name, method)
+		}
+		if w.canMove(rules.White) {
+			t.Errorf("%s: the board still moves for white", test.name)
+		}
+		if err := w.Play(e2e4); !errors.Is(err, rules.ErrGameOver) {
+			t.Errorf("%s: Play = %v, want %v", test.name, err, rules.ErrGameOver)
+		}
+		if fen := w.FEN(); fen != rules.Standard.StartingFEN() {
+			t.Errorf("%s: the board moved to %s", test.name, fen)
+		}
+	}
+}
+
+func TestPlay(t *testing.T) {
+	w := NewWidget(material.NewTheme(), Config{})
+	w.SetPlayer(rules.White)
+	if !w.canMove(rules.White) || w.canMove(rules.Black) {
+		t.Error("the board doesn't move for white only")
+	}
+
+	e2e4, _ := rules.ParseMove("e2e4")
+	if err := w.Play(e2e4); err != nil {
+		t.Fatalf("Play: %v", err)
+	}
+	if err := w.Play(e2e4); !errors.Is(err, rules.ErrIllegalMove) {
+		t.Errorf("Play of an illegal move = %v, want %v", err, rules.ErrIllegalMove)
+	}
+	if w.canMove(rules.White) {
+		t.Error("the board moves for white on black's turn")
+	}
+}
+
+// recorder logs the layers it's drawn at, its slice makes it incomparable.
+type recorder struct {
+	name string
+	log  *[]string
+	seen []rules.Piece
+}
+
+func (r recorder) Layout(gtx layout.Context, layer Layer, state BoardState) {
+	*r.log = append(*r.log, r.name+" "+layer.String())
+	if len(state.Pieces) > 0 {
+		state.Pieces[0] = rules.NoPiece // overlays get copies
+	}
+}
+
+func TestOverlays(t *testing.T) {
+	w, gtx := newTestWidget(t)
+	var log []string
+	removeA := w.AddOverlay(recorder{name: "a", log: &log})
+	w.AddOverlay(recorder{name: "b", log: &log})
+
+	w.layout(gtx)
+	want := []string{
+		"a above board", "b above board",
+		"a below pieces", "b below pieces",
+		"a above pieces", "b above pieces",
+		"a above annotations", "b above annotations",
+	}
+	if !slices.Equal(log, want) {
+		t.Errorf("drawn %q, want %q", log, want)
+	}
+	if w.curBoard[0] == rules.NoPiece {
+		t.Error("an overlay changed the widget's pieces")
+	}
+
+	log = nil
+	removeA()
+	removeA()
+	w.layout(gtx)
+	if want := []string{"b above board", "b below pieces", "b above pieces", "b above annotations"}; !slices.Equal(log, want) {
+		t.Errorf("drawn %q after removing a, want %q", log, want)
+	}
+}
+
+func newTestWidget(tb testing.TB) (*Widget, layout.Context) {
+	tb.Helper()
+
+	var config Config
+	var err error
+	config.Board = LichessBrown
+	config.Piece, err = LoadPiecesFS(os.DirFS("assets/pieces/aquarium"), ShortNaming)
+	if err != nil {
+		tb.Fatalf("can't load pieces: %v", err)
+	}
+
+	w := NewWidget(material.NewTheme(), config)
+	move, _ := rules.ParseMove("e2e4")
+	if err := w.Play(move); err != nil {
+		tb.Fatalf("can't play e2e4: %v", err)
+	}
+
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(800, 800)),
+		Now:         time.Now(),
+	}
+	w.layout(gtx)
+	return w, gtx
+}
+
+// toggle takes the last move back or plays it again, so every frame has a
+// new position with two changed squares.
+func toggle(b *testing.B, w *Widget) {
+	undoer := w.game.(rules.Undoer)
+	var err error
+	if undoer.CanRedo() {
+		_, err = undoer.Redo()
+	} else {
+		_, err = undoer.Takeback()
+	}
+	if err != nil {
+		b.Fatalf("can't toggle the last move: %v", err)
+	}
+}
+
+// BenchmarkLayoutStill lays out the same position every frame.
+func BenchmarkLayoutStill(b *testing.B) {
+	w, gtx := newTestWidget(b)
+	b.ResetTimer()
+	for range b.N {
+		gtx.Ops.Reset()
+		w.layout(gtx)
+	}
+}
+
+// BenchmarkLayoutMove re-records only the squares a move changed.
+func BenchmarkLayoutMove(b *testing.B) {
+	w, gtx := newTestWidget(b)
+	b.ResetTimer()
+	for range b.N {
+		toggle(b, w)
+		gtx.Ops.Reset()
+		w.layout(gtx)
+	}
+}
+
+// BenchmarkLayoutRedraw records every square on each move, like the widget
+// did before it kept the ops of unchanged squares.
+func BenchmarkLayoutRedraw(b *testing.B) {
+	w, gtx := newTestWidget(b)
+	b.ResetTimer()
+	for range b.N {
+		toggle(b, w)
+		w.redraw = true
+		gtx.Ops.Reset()
+		w.layout(gtx)
+	}
+}
+
+func BenchmarkUpdateBoard(b *testing.B) {
+	w, gtx := newTestWidget(b)
+	b.ResetTimer()
+	for range b.N {
+		toggle(b, w)
+		w.changedSquares = w.changedSquares[:0]
+		w.updateBoard(gtx)
+	}
+}
+
+// BenchmarkDrawPieces replays the recorded pieces.
+func BenchmarkDrawPieces(b *testing.B) {
+	w, gtx := newTestWidget(b)
+	b.ResetTimer()
+	for range b.N {
+		gtx.Ops.Reset()
+		w.drawPieces(gtx)
+	}
+}
+
+// BenchmarkDrawPiecesRedraw records every piece again.
+func BenchmarkDrawPiecesRedraw(b *testing.B) {
+	w, gtx := newTestWidget(b)
+	w.redraw = true
+	b.ResetTimer()
+	for range b.N {
+		gtx.Ops.Reset()
+		w.drawPieces(gtx)
+	}
+}